@@ -0,0 +1,152 @@
+package req
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+)
+
+// ErrQuotaExceeded 流量超出配额
+var ErrQuotaExceeded = errors.New("bandwidth quota exceeded")
+
+// BandwidthUsage 流量统计，单位字节，只统计请求体与响应体
+type BandwidthUsage struct {
+    Downloaded int64
+    Uploaded   int64
+}
+
+// Total 上传与下载总流量
+func (u BandwidthUsage) Total() int64 {
+    return u.Downloaded + u.Uploaded
+}
+
+// bandwidthCounter 流量计数
+type bandwidthCounter struct {
+    downloaded int64
+    uploaded   int64
+}
+
+// usage 当前统计
+func (c *bandwidthCounter) usage() BandwidthUsage {
+    return BandwidthUsage{Downloaded: atomic.LoadInt64(&c.downloaded), Uploaded: atomic.LoadInt64(&c.uploaded)}
+}
+
+var bandwidth = struct {
+    sync.Mutex
+    total bandwidthCounter
+    hosts map[string]*bandwidthCounter
+}{hosts: map[string]*bandwidthCounter{}}
+
+// hostCounter 主机流量计数
+func hostCounter(host string) *bandwidthCounter {
+    bandwidth.Lock()
+    defer bandwidth.Unlock()
+    counter, ok := bandwidth.hosts[host]
+    if !ok {
+        counter = &bandwidthCounter{}
+        bandwidth.hosts[host] = counter
+    }
+    return counter
+}
+
+// Bandwidth 当前客户端的总流量与各主机流量
+func Bandwidth() (total BandwidthUsage, hosts map[string]BandwidthUsage) {
+    bandwidth.Lock()
+    defer bandwidth.Unlock()
+    hosts = make(map[string]BandwidthUsage, len(bandwidth.hosts))
+    for host, counter := range bandwidth.hosts {
+        hosts[host] = counter.usage()
+    }
+    return bandwidth.total.usage(), hosts
+}
+
+// ResetBandwidth 清空流量统计，如按月重置配额
+func ResetBandwidth() {
+    bandwidth.Lock()
+    defer bandwidth.Unlock()
+    atomic.StoreInt64(&bandwidth.total.downloaded, 0)
+    atomic.StoreInt64(&bandwidth.total.uploaded, 0)
+    bandwidth.hosts = map[string]*bandwidthCounter{}
+}
+
+// SetBandwidthQuota 设置上传与下载总流量配额，超出后停止读写并返回 ErrQuotaExceeded，为 0 时不限制
+func SetBandwidthQuota(bytes int64) {
+    updateConfig(func(c *config) { c.bandwidthQuota = bytes })
+}
+
+// SetHostBandwidthQuota 设置指定主机的流量配额，为 0 时不限制
+func SetHostBandwidthQuota(host string, bytes int64) {
+    updateConfig(func(c *config) {
+        quotas := make(map[string]int64, len(c.hostQuotas)+1)
+        for k, v := range c.hostQuotas {
+            quotas[k] = v
+        }
+        quotas[strings.ToLower(host)] = bytes
+        c.hostQuotas = quotas
+    })
+}
+
+// exceeded 是否已超出配额
+func exceeded(c *config, host string, counter *bandwidthCounter) bool {
+    if c.bandwidthQuota > 0 && bandwidth.total.usage().Total() >= c.bandwidthQuota {
+        return true
+    }
+    quota := c.hostQuotas[host]
+    return quota > 0 && counter.usage().Total() >= quota
+}
+
+// bandwidthTransport 统计请求体与响应体流量，超出配额时返回 ErrQuotaExceeded
+type bandwidthTransport struct {
+    base http.RoundTripper
+}
+
+// RoundTrip 发送请求
+func (t *bandwidthTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+    host := strings.ToLower(r.URL.Hostname())
+    counter := hostCounter(host)
+    if exceeded(conf(), host, counter) {
+        if r.Body != nil {
+            r.Body.Close()
+        }
+        return nil, errors.WithStack(ErrQuotaExceeded)
+    }
+
+    if r.Body != nil && r.Body != http.NoBody {
+        r = r.Clone(r.Context())
+        r.Body = &countingBody{ReadCloser: r.Body, host: host, counter: counter, upload: true}
+    }
+    res, err := t.base.RoundTrip(r)
+    if err != nil {
+        return nil, err
+    }
+    res.Body = &countingBody{ReadCloser: res.Body, host: host, counter: counter}
+    return res, nil
+}
+
+// countingBody 统计读取字节数
+type countingBody struct {
+    io.ReadCloser
+    host    string
+    counter *bandwidthCounter
+    upload  bool
+}
+
+// Read 读取并计数，超出配额时停止读取
+func (b *countingBody) Read(p []byte) (int, error) {
+    if exceeded(conf(), b.host, b.counter) {
+        return 0, errors.WithStack(ErrQuotaExceeded)
+    }
+    n, err := b.ReadCloser.Read(p)
+    if b.upload {
+        atomic.AddInt64(&b.counter.uploaded, int64(n))
+        atomic.AddInt64(&bandwidth.total.uploaded, int64(n))
+    } else {
+        atomic.AddInt64(&b.counter.downloaded, int64(n))
+        atomic.AddInt64(&bandwidth.total.downloaded, int64(n))
+    }
+    return n, err
+}
@@ -0,0 +1,330 @@
+package req
+
+import (
+	"container/heap"
+	"context"
+	"net"
+	neturl "net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// SetAdaptiveLimit 开启 AIMD 自适应并发，从 SetLimit 的并发数量开始，
+// 请求健康时逐步增加并发，遇到 429/5xx/超时减半，min 为 0 时关闭
+func SetAdaptiveLimit(min, max int) {
+    if max < min {
+        max = min
+    }
+    updateConfig(func(c *config) { c.adaptiveMin, c.adaptiveMax = min, max })
+}
+
+// SetHostLimit 设置批量请求中单主机并发数量，为 0 时不限制
+func SetHostLimit(limit int) {
+    updateConfig(func(c *config) { c.hostLimit = limit })
+}
+
+// SetHostLimitFor 设置指定主机的并发数量，覆盖 SetHostLimit
+func SetHostLimitFor(host string, limit int) {
+    updateConfig(func(c *config) {
+        limits := make(map[string]int, len(c.hostLimits)+1)
+        for k, v := range c.hostLimits {
+            limits[k] = v
+        }
+        limits[strings.ToLower(host)] = limit
+        c.hostLimits = limits
+    })
+}
+
+// SetAgingInterval 设置优先级老化间隔，等待时长每增加该值优先级提升 1
+func SetAgingInterval(interval time.Duration) {
+    updateConfig(func(c *config) { c.agingInterval = interval })
+}
+
+// WithPriority 设置批量请求优先级，数值越大越先执行，默认 0
+func WithPriority(priority int) Option {
+    return func(o *options) {
+        o.priority = priority
+    }
+}
+
+// BatchStats 批量请求统计
+type BatchStats struct {
+    // Total 输入链接数量
+    Total int
+    // Unique 去重后的链接数量
+    Unique int
+    // Saved 去重节省的请求次数
+    Saved int
+}
+
+// WithBatchStats 批量请求完成后写入统计信息
+func WithBatchStats(stats *BatchStats) Option {
+    return func(o *options) {
+        o.stats = stats
+    }
+}
+
+// uniqueURLs 链接去重，返回每个链接对应的输入下标，按首次出现顺序排列
+func uniqueURLs(urls []string) [][]int {
+    var (
+        seen   = make(map[string]int, len(urls))
+        result = make([][]int, 0, len(urls))
+    )
+    for i, url := range urls {
+        if n, ok := seen[url]; ok {
+            result[n] = append(result[n], i)
+            continue
+        }
+        seen[url] = len(result)
+        result = append(result, []int{i})
+    }
+    return result
+}
+
+// hostLimit 主机并发数量
+func hostLimit(c *config, host string) int {
+    if limit, ok := c.hostLimits[host]; ok {
+        return limit
+    }
+    return c.hostLimit
+}
+
+// urlHost 链接主机名
+func urlHost(rawurl string) string {
+    u, err := neturl.Parse(rawurl)
+    if err != nil {
+        return ""
+    }
+    return strings.ToLower(u.Hostname())
+}
+
+// batchItem 批量请求条目
+type batchItem struct {
+    index int
+    url   string
+    host  string
+    // score 调度得分，优先级减去按老化间隔折算的入队时间，得分高者先执行
+    score float64
+    run   func() error
+}
+
+// newBatchItem 创建批量请求条目
+func newBatchItem(index int, url string, priority int, run func() error) *batchItem {
+    score := float64(priority)
+    if c := conf(); c.agingInterval > 0 {
+        score -= float64(c.clock.Now().UnixNano()) / float64(c.agingInterval)
+    }
+    return &batchItem{index: index, url: url, host: urlHost(url), score: score, run: run}
+}
+
+// itemQueue 按得分排序的条目堆
+type itemQueue []*batchItem
+
+func (q itemQueue) Len() int            { return len(q) }
+func (q itemQueue) Less(i, j int) bool  { return q[i].score > q[j].score }
+func (q itemQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *itemQueue) Push(x interface{}) { *q = append(*q, x.(*batchItem)) }
+func (q *itemQueue) Pop() interface{} {
+    old := *q
+    item := old[len(old)-1]
+    *q = old[:len(old)-1]
+    return item
+}
+
+// scheduler 批量请求调度，所有批量请求共享总并发与单主机并发
+// 主机并发已满时跳过该主机，调度其他主机中得分最高的条目
+type scheduler struct {
+    mutex    sync.Mutex
+    cond     *sync.Cond
+    queues   map[string]*itemQueue
+    inflight int
+    hosts    map[string]int
+    // limit 自适应并发上限，按 AIMD 调整
+    limit float64
+    // baseline 延迟基线，取观察到的最小延迟并缓慢上浮
+    baseline time.Duration
+    // throttles 自动限流中的主机
+    throttles map[string]*hostThrottle
+    // wake 关闭时取消上一次限流到期唤醒
+    wake chan struct{}
+}
+
+var (
+    schedulerOnce    sync.Once
+    defaultScheduler *scheduler
+)
+
+// getScheduler 共享调度，首次使用时启动
+func getScheduler() *scheduler {
+    schedulerOnce.Do(func() {
+        defaultScheduler = &scheduler{
+            queues:    make(map[string]*itemQueue),
+            hosts:     make(map[string]int),
+            throttles: make(map[string]*hostThrottle),
+        }
+        defaultScheduler.cond = sync.NewCond(&defaultScheduler.mutex)
+        go defaultScheduler.dispatch()
+    })
+    return defaultScheduler
+}
+
+// submit 加入调度队列
+func (s *scheduler) submit(items ...*batchItem) {
+    s.mutex.Lock()
+    defer s.mutex.Unlock()
+
+    for _, item := range items {
+        queue, ok := s.queues[item.host]
+        if !ok {
+            queue = &itemQueue{}
+            s.queues[item.host] = queue
+        }
+        heap.Push(queue, item)
+    }
+    s.cond.Broadcast()
+}
+
+// dispatch 持续调度条目执行
+func (s *scheduler) dispatch() {
+    for {
+        item := s.next()
+        go func() {
+            clock := conf().clock
+            start := clock.Now()
+            err := item.run()
+            s.done(item, clock.Now().Sub(start), err)
+        }()
+    }
+}
+
+// currentLimit 当前总并发上限
+func (s *scheduler) currentLimit(c *config) int {
+    if c.adaptiveMin <= 0 {
+        s.limit = 0
+        return int(clamp(float64(c.limit), 1, 0))
+    }
+    if s.limit == 0 {
+        s.limit = float64(c.limit)
+    }
+    s.limit = clamp(s.limit, float64(c.adaptiveMin), float64(c.adaptiveMax))
+    return int(clamp(s.limit, 1, 0))
+}
+
+// next 等待下一个可执行条目
+func (s *scheduler) next() *batchItem {
+    s.mutex.Lock()
+    defer s.mutex.Unlock()
+
+    for {
+        c := conf()
+        var wake time.Time
+        if s.inflight < s.currentLimit(c) {
+            var (
+                best *batchItem
+                now  = c.clock.Now()
+            )
+            for host, queue := range s.queues {
+                if limit := hostLimit(c, host); limit > 0 && s.hosts[host] >= limit {
+                    continue
+                }
+                if c.autoThrottle > 0 {
+                    if throttled, at := s.throttled(host, now); throttled {
+                        if !at.IsZero() && (wake.IsZero() || at.Before(wake)) {
+                            wake = at
+                        }
+                        continue
+                    }
+                }
+                if head := (*queue)[0]; best == nil || head.score > best.score {
+                    best = head
+                }
+            }
+
+            if best != nil {
+                queue := s.queues[best.host]
+                heap.Pop(queue)
+                if queue.Len() == 0 {
+                    delete(s.queues, best.host)
+                }
+                s.inflight++
+                s.hosts[best.host]++
+                if t := s.throttles[best.host]; t != nil && c.autoThrottle > 0 {
+                    t.next = now.Add(t.interval)
+                }
+                return best
+            }
+        }
+        if !wake.IsZero() {
+            s.wakeAt(c.clock, wake)
+        }
+        s.cond.Wait()
+    }
+}
+
+// done 条目执行完成，自适应模式下根据结果调整并发
+func (s *scheduler) done(item *batchItem, latency time.Duration, err error) {
+    s.mutex.Lock()
+    defer s.mutex.Unlock()
+
+    s.inflight--
+    if s.hosts[item.host]--; s.hosts[item.host] <= 0 {
+        delete(s.hosts, item.host)
+    }
+    if c := conf(); c.autoThrottle > 0 {
+        s.throttle(c, item.host, err)
+    }
+
+    if s.limit > 0 {
+        c := conf()
+        min, max := float64(c.adaptiveMin), float64(c.adaptiveMax)
+        switch {
+        case congested(err):
+            s.limit = clamp(s.limit/2, min, max)
+        case err == nil && s.baseline > 0 && latency > s.baseline*2:
+            s.limit = clamp(s.limit*0.9, min, max)
+        case err == nil:
+            s.limit = clamp(s.limit+1/s.limit, min, max)
+        }
+
+        if err == nil {
+            if s.baseline == 0 || latency < s.baseline {
+                s.baseline = latency
+            } else {
+                s.baseline += (latency - s.baseline) / 100
+            }
+        }
+    }
+    s.cond.Broadcast()
+}
+
+// congested 是否为服务端过载或超时错误
+func congested(err error) bool {
+    if err == nil {
+        return false
+    }
+
+    var status *StatusError
+    if errors.As(err, &status) {
+        return status.StatusCode == 429 || status.StatusCode >= 500
+    }
+
+    var netErr net.Error
+    if errors.As(err, &netErr) && netErr.Timeout() {
+        return true
+    }
+    return errors.Is(err, context.DeadlineExceeded)
+}
+
+// clamp 限制取值范围
+func clamp(value, min, max float64) float64 {
+    if value < min {
+        return min
+    }
+    if max > 0 && value > max {
+        return max
+    }
+    return value
+}
@@ -0,0 +1,106 @@
+package req
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+// withHostLimit 临时设置单主机并发数量，测试结束后恢复
+func withHostLimit(t *testing.T, limit int) {
+    old := conf().hostLimit
+    SetHostLimit(limit)
+    t.Cleanup(func() { SetHostLimit(old) })
+}
+
+func TestSchedulerHostLimit(t *testing.T) {
+    withHostLimit(t, 2)
+    server := NewMockServer()
+    defer server.Close()
+    var (
+        mutex  sync.Mutex
+        active = map[string]int{}
+        peak   = map[string]int{}
+        total  int
+        most   int
+    )
+    server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        mutex.Lock()
+        active[r.Host]++
+        total++
+        if active[r.Host] > peak[r.Host] {
+            peak[r.Host] = active[r.Host]
+        }
+        if total > most {
+            most = total
+        }
+        mutex.Unlock()
+
+        time.Sleep(time.Millisecond * 50)
+
+        mutex.Lock()
+        active[r.Host]--
+        total--
+        mutex.Unlock()
+    })
+    defer server.Install()()
+
+    var urls []string
+    for _, host := range []string{"a.example.com", "b.example.com"} {
+        for _, path := range []string{"/1", "/2", "/3", "/4", "/5", "/6"} {
+            urls = append(urls, "http://"+host+path)
+        }
+    }
+    if err := Batch(context.Background(), urls).Err(); err != nil {
+        t.Fatal(err)
+    }
+
+    mutex.Lock()
+    defer mutex.Unlock()
+    for _, host := range []string{"a.example.com", "b.example.com"} {
+        if peak[host] > 2 {
+            t.Fatalf("%s concurrency = %d, want at most 2", host, peak[host])
+        }
+    }
+    // 主机并发已满时调度其他主机
+    if most <= 2 {
+        t.Fatalf("total concurrency = %d, want other host scheduled", most)
+    }
+}
+
+func TestSchedulerAdaptiveLimit(t *testing.T) {
+    c := conf()
+    SetAdaptiveLimit(2, 16)
+    t.Cleanup(func() { SetAdaptiveLimit(c.adaptiveMin, c.adaptiveMax) })
+
+    s := &scheduler{queues: map[string]*itemQueue{}, hosts: map[string]int{}, throttles: map[string]*hostThrottle{}, limit: 8}
+    s.cond = sync.NewCond(&s.mutex)
+    run := func(err error) {
+        s.inflight++
+        s.hosts["api.example.com"]++
+        s.done(&batchItem{host: "api.example.com"}, time.Millisecond*10, err)
+    }
+
+    // 429/5xx 减半，不低于下限
+    run(&StatusError{StatusCode: http.StatusServiceUnavailable})
+    if s.limit != 4 {
+        t.Fatalf("limit after 503 = %v, want 4", s.limit)
+    }
+    run(&StatusError{StatusCode: http.StatusTooManyRequests})
+    run(context.DeadlineExceeded)
+    if s.limit != 2 {
+        t.Fatalf("limit after congestion = %v, want min 2", s.limit)
+    }
+    // 成功时每次增加 1/limit
+    run(nil)
+    if s.limit != 2.5 {
+        t.Fatalf("limit after success = %v, want 2.5", s.limit)
+    }
+    // 非过载错误不调整
+    run(&StatusError{StatusCode: http.StatusNotFound})
+    if s.limit != 2.5 {
+        t.Fatalf("limit after 404 = %v, want 2.5", s.limit)
+    }
+}
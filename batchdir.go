@@ -0,0 +1,128 @@
+package req
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+)
+
+// BatchFile 批量写入目录的条目索引
+type BatchFile struct {
+    URL string `json:"url"`
+    // File 相对目录的文件名，请求失败时为空
+    File string `json:"file,omitempty"`
+    // StatusCode 响应状态码，请求失败且无响应时为 0
+    StatusCode int    `json:"status"`
+    Hash       string `json:"sha256,omitempty"`
+    Size       int64  `json:"size"`
+    Error      string `json:"error,omitempty"`
+}
+
+// BatchGetToDir 批量请求内容并在返回时逐条写入目录，重复链接只请求一次，内容不保留在内存中
+// 文件名为链接的 MD5，每完成一条向 dir/index.jsonl 追加一行 BatchFile 索引
+// 单条请求失败记录在索引中，返回的错误仅表示目录或索引无法写入
+func BatchGetToDir(urls []string, dir string, v ...interface{}) error {
+    unique := uniqueURLs(urls)
+    list := make([]string, 0, len(unique))
+    for _, indexes := range unique {
+        list = append(list, urls[indexes[0]])
+    }
+    return BatchGetToDirFrom(URLsFromSlice(list), dir, v...)
+}
+
+// BatchGetToDirFrom 从迭代器逐条读取链接并写入目录，不去重，重复链接写入同一文件
+func BatchGetToDirFrom(next URLIterator, dir string, v ...interface{}) error {
+    if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+        return errors.WithStack(err)
+    }
+    index, err := os.OpenFile(filepath.Join(dir, "index.jsonl"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+    if err != nil {
+        return errors.WithStack(err)
+    }
+    defer index.Close()
+
+    var (
+        mutex    sync.Mutex
+        indexErr error
+        ctx      = argContext(v)
+        opts, _  = splitOptions(v)
+    )
+    err = streamBatch(ctx, next, opts, func(ctx context.Context, url string) error {
+        file, err := saveURL(ctx, url, dir, v)
+        line, _ := jsoniter.Marshal(file)
+
+        mutex.Lock()
+        if _, werr := index.Write(append(line, '\n')); werr != nil && indexErr == nil {
+            indexErr = errors.WithStack(werr)
+        }
+        mutex.Unlock()
+        return err
+    })
+    if err != nil {
+        return err
+    }
+    return indexErr
+}
+
+// saveURL 流式请求内容写入目录，返回索引条目
+func saveURL(ctx context.Context, url, dir string, v []interface{}) (BatchFile, error) {
+    file := BatchFile{URL: url}
+    res, err := GetStream(ctx, url, v...)
+    if err != nil {
+        file.StatusCode = statusCode(err)
+        file.Error = err.Error()
+        return file, err
+    }
+    defer res.Close()
+
+    file.StatusCode = res.StatusCode
+    name := md5sum([]byte(url))
+    if err = checkDiskSpace(filepath.Join(dir, name), res.ContentLength); err != nil {
+        file.Error = err.Error()
+        return file, err
+    }
+    out, err := os.Create(filepath.Join(dir, name))
+    if err == nil {
+        hash := sha256.New()
+        file.Size, err = io.Copy(io.MultiWriter(out, hash), res.Body)
+        if cerr := out.Close(); err == nil {
+            err = cerr
+        }
+        file.Hash = fmt.Sprintf("%x", hash.Sum(nil))
+    }
+    if err != nil {
+        _ = os.Remove(filepath.Join(dir, name))
+        file.Hash, file.Error = "", err.Error()
+        return file, errors.WithStack(err)
+    }
+
+    file.File = name
+    return file, nil
+}
+
+// statusCode 错误中的响应状态码，无响应时为 0
+func statusCode(err error) int {
+    var status *StatusError
+    if errors.As(err, &status) {
+        return status.StatusCode
+    }
+    return 0
+}
+
+// argContext 参数中的上下文，未设置时为 context.Background()
+func argContext(v []interface{}) context.Context {
+    ctx := context.Background()
+    for _, arg := range v {
+        if c, ok := arg.(context.Context); ok {
+            ctx = c
+        }
+    }
+    return ctx
+}
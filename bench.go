@@ -0,0 +1,100 @@
+package req
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// BenchResult 压测结果
+type BenchResult struct {
+    // Requests 完成的请求数量
+    Requests int
+    // Errors 请求失败的数量，不包括非 2xx 状态码
+    Errors int
+    // Duration 实际耗时
+    Duration time.Duration
+    // Throughput 每秒请求数
+    Throughput float64
+    // Status 状态码分布
+    Status map[int]int
+
+    Min  time.Duration
+    Mean time.Duration
+    P50  time.Duration
+    P90  time.Duration
+    P95  time.Duration
+    P99  time.Duration
+    Max  time.Duration
+}
+
+// Bench 以 concurrency 个并发持续请求 duration，统计延迟分位数、吞吐量与状态码分布
+// 请求使用当前客户端配置，不经过缓存且不重试，ctx 结束时提前返回已完成部分的统计
+func Bench(ctx context.Context, request Request, concurrency int, duration time.Duration, v ...interface{}) (*BenchResult, error) {
+    if concurrency <= 0 {
+        return nil, errors.Errorf("bench: invalid concurrency %d", concurrency)
+    }
+
+    ctx, cancel := context.WithTimeout(ctx, duration)
+    defer cancel()
+
+    var (
+        wg        sync.WaitGroup
+        mutex     sync.Mutex
+        latencies []time.Duration
+        result    = &BenchResult{Status: map[int]int{}}
+        start     = time.Now()
+    )
+    for i := 0; i < concurrency; i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            for ctx.Err() == nil {
+                begin := time.Now()
+                res, err := request.Do(ctx, v...)
+                if err == nil {
+                    _, err = res.Bytes()
+                }
+                latency := time.Since(begin)
+                if ctx.Err() != nil {
+                    // 压测结束时被中断的请求不计入统计
+                    return
+                }
+
+                mutex.Lock()
+                result.Requests++
+                latencies = append(latencies, latency)
+                if err != nil {
+                    result.Errors++
+                } else {
+                    result.Status[res.StatusCode]++
+                }
+                mutex.Unlock()
+            }
+        }()
+    }
+    wg.Wait()
+
+    result.Duration = time.Since(start)
+    if seconds := result.Duration.Seconds(); seconds > 0 {
+        result.Throughput = float64(result.Requests) / seconds
+    }
+
+    if len(latencies) > 0 {
+        sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+        var total time.Duration
+        for _, latency := range latencies {
+            total += latency
+        }
+        percentile := func(p float64) time.Duration {
+            return latencies[int(p*float64(len(latencies)-1))]
+        }
+        result.Min, result.Max = latencies[0], latencies[len(latencies)-1]
+        result.Mean = total / time.Duration(len(latencies))
+        result.P50, result.P90, result.P95, result.P99 = percentile(0.5), percentile(0.9), percentile(0.95), percentile(0.99)
+    }
+    return result, nil
+}
@@ -0,0 +1,55 @@
+package req
+
+import (
+	"github.com/pkg/errors"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+)
+
+// 二进制内容类型
+const (
+    contentTypeMsgpack  = "application/msgpack"
+    contentTypeProtobuf = "application/x-protobuf"
+)
+
+// WithMsgpackBody 使用 MessagePack 编码请求体，并设置 Content-Type
+func WithMsgpackBody(v interface{}) Option {
+    return func(o *options) {
+        data, err := msgpack.Marshal(v)
+        if err != nil {
+            o.err = errors.WithStack(err)
+            return
+        }
+        o.body, o.contentType = data, contentTypeMsgpack
+    }
+}
+
+// WithProtobufBody 使用 Protocol Buffers 编码请求体，并设置 Content-Type
+func WithProtobufBody(msg proto.Message) Option {
+    return func(o *options) {
+        data, err := proto.Marshal(msg)
+        if err != nil {
+            o.err = errors.WithStack(err)
+            return
+        }
+        o.body, o.contentType = data, contentTypeProtobuf
+    }
+}
+
+// Msgpack 读取并解析 MessagePack 响应内容
+func (r *Response) Msgpack(v interface{}) error {
+    data, err := r.Bytes()
+    if err != nil {
+        return err
+    }
+    return errors.WithStack(msgpack.Unmarshal(data, v))
+}
+
+// Protobuf 读取并解析 Protocol Buffers 响应内容
+func (r *Response) Protobuf(msg proto.Message) error {
+    data, err := r.Bytes()
+    if err != nil {
+        return err
+    }
+    return errors.WithStack(proto.Unmarshal(data, msg))
+}
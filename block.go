@@ -0,0 +1,168 @@
+package req
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// blockPeekSize 拦截页识别读取的响应体长度
+const blockPeekSize = 16 << 10
+
+// BlockDetector 拦截页识别，只识别状态码为 200/403/429/503 的 HTML 响应，body 为响应体前 16KB，识别为拦截页时返回原因
+type BlockDetector interface {
+    Detect(res *http.Response, body []byte) (reason string, blocked bool)
+}
+
+// BlockDetectorFunc 函数形式的拦截页识别
+type BlockDetectorFunc func(res *http.Response, body []byte) (string, bool)
+
+// Detect 识别拦截页
+func (f BlockDetectorFunc) Detect(res *http.Response, body []byte) (string, bool) {
+    return f(res, body)
+}
+
+// DefaultBlockDetector 按 HTML 特征识别 Cloudflare 质询、hCaptcha 与 reCAPTCHA 页面
+var DefaultBlockDetector BlockDetector = BlockDetectorFunc(detectBlockPage)
+
+// blockPatterns 拦截页特征
+var blockPatterns = []struct {
+    reason  string
+    pattern string
+}{
+    {"cloudflare challenge", "cf-chl-"},
+    {"cloudflare challenge", "<title>just a moment...</title>"},
+    {"cloudflare block", "attention required! | cloudflare"},
+    {"hcaptcha", "hcaptcha.com/1/api.js"},
+    {"recaptcha", "www.google.com/recaptcha/"},
+    {"recaptcha", "class=\"g-recaptcha\""},
+}
+
+// detectBlockPage 识别常见质询与验证码页面
+func detectBlockPage(res *http.Response, body []byte) (string, bool) {
+    lower := bytes.ToLower(body)
+    for _, p := range blockPatterns {
+        if bytes.Contains(lower, []byte(p.pattern)) {
+            return p.reason, true
+        }
+    }
+    return "", false
+}
+
+// BlockPolicy 拦截页处理策略
+type BlockPolicy struct {
+    // Detector 拦截页识别，为 nil 时使用 DefaultBlockDetector
+    Detector BlockDetector
+    // OnBlocked 识别到拦截页时回调，userAgent 为本次请求使用的 User-Agent
+    OnBlocked func(info RequestInfo, reason, userAgent string)
+    // Retry 识别到拦截页后更换 User-Agent 重试的次数
+    Retry int
+    // BurnDuration 拦截后该 User-Agent 从轮换池中停用的时长，为 0 时不停用
+    BurnDuration time.Duration
+}
+
+// BlockPageError 响应为拦截页
+type BlockPageError struct {
+    StatusCode int
+    Reason     string
+}
+
+// Error 错误信息
+func (e *BlockPageError) Error() string {
+    return fmt.Sprintf("blocked page (status %d): %s", e.StatusCode, e.Reason)
+}
+
+// SetBlockPolicy 设置拦截页处理策略，非流式请求的 HTML 响应会经过识别，识别为拦截页时返回 BlockPageError，为 nil 时不识别
+// 更换身份只轮换 SetUserAgentPool 设置的 User-Agent，代理保持不变
+func SetBlockPolicy(policy *BlockPolicy) {
+    var p *BlockPolicy
+    if policy != nil {
+        copied := *policy
+        if copied.Detector == nil {
+            copied.Detector = DefaultBlockDetector
+        }
+        p = &copied
+    }
+    updateConfig(func(c *config) { c.blockPolicy = p })
+}
+
+// blockCandidate 响应是否可能为拦截页，不读取响应体
+func blockCandidate(res *http.Response) bool {
+    switch res.StatusCode {
+    case http.StatusOK, http.StatusForbidden, http.StatusTooManyRequests, http.StatusServiceUnavailable:
+    default:
+        return false
+    }
+    if res.ContentLength == 0 || res.Request != nil && res.Request.Method == http.MethodHead {
+        return false
+    }
+    return strings.Contains(strings.ToLower(res.Header.Get("Content-Type")), "text/html")
+}
+
+// detectBlock 读取响应体前部识别拦截页，读取的内容会放回响应体，非 HTML 响应不读取
+func detectBlock(policy *BlockPolicy, res *http.Response) (string, bool) {
+    if !blockCandidate(res) {
+        return "", false
+    }
+    head, err := io.ReadAll(io.LimitReader(res.Body, blockPeekSize))
+    res.Body = struct {
+        io.Reader
+        io.Closer
+    }{io.MultiReader(bytes.NewReader(head), res.Body), res.Body}
+    if err != nil {
+        return "", false
+    }
+    return policy.Detector.Detect(res, head)
+}
+
+// burnedAgents 停用的 User-Agent 及恢复时间
+var burnedAgents = struct {
+    sync.Mutex
+    until map[string]time.Time
+}{until: map[string]time.Time{}}
+
+// burnUserAgent 停用 User-Agent
+func burnUserAgent(ua string, d time.Duration) {
+    if ua == "" || d <= 0 {
+        return
+    }
+    burnedAgents.Lock()
+    burnedAgents.until[ua] = conf().clock.Now().Add(d)
+    burnedAgents.Unlock()
+}
+
+// activeAgents 未停用的 User-Agent，全部停用时返回原列表
+func activeAgents(pool []UserAgent) []UserAgent {
+    burnedAgents.Lock()
+    defer burnedAgents.Unlock()
+    if len(burnedAgents.until) == 0 {
+        return pool
+    }
+
+    now := conf().clock.Now()
+    active := make([]UserAgent, 0, len(pool))
+    for _, ua := range pool {
+        if until, ok := burnedAgents.until[ua.Value]; ok {
+            if now.Before(until) {
+                continue
+            }
+            delete(burnedAgents.until, ua.Value)
+        }
+        active = append(active, ua)
+    }
+    if len(active) == 0 {
+        return pool
+    }
+    return active
+}
+
+// blockError 拦截页错误
+func blockError(code int, reason string) error {
+    return errors.WithStack(&BlockPageError{StatusCode: code, Reason: reason})
+}
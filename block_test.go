@@ -0,0 +1,64 @@
+package req
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// withBlockPolicy 临时设置拦截页处理策略，测试结束后关闭
+func withBlockPolicy(t *testing.T) {
+    SetBlockPolicy(&BlockPolicy{})
+    t.Cleanup(func() { SetBlockPolicy(nil) })
+}
+
+func TestBlockPolicyDetectsChallenge(t *testing.T) {
+    withBlockPolicy(t)
+    server := NewMockServer()
+    defer server.Close()
+    server.Route(http.MethodGet, "/challenge").Status(403).
+        Header("Content-Type", "text/html; charset=utf-8").
+        Body("<html><head><title>Just a moment...</title></head></html>")
+    server.Route(http.MethodGet, "/data").JSON(map[string]string{"title": "just a moment..."})
+    defer server.Install()()
+
+    _, err := Get("http://api.example.com/challenge")
+    var blocked *BlockPageError
+    if !errors.As(err, &blocked) || blocked.StatusCode != http.StatusForbidden {
+        t.Fatalf("err = %v, want block page", err)
+    }
+    if _, err = Get("http://api.example.com/data"); err != nil {
+        t.Fatalf("json response detected as block page: %v", err)
+    }
+}
+
+func TestBlockPolicySkipsStream(t *testing.T) {
+    withBlockPolicy(t)
+    server := NewMockServer()
+    defer server.Close()
+    release := make(chan struct{})
+    defer close(release)
+    server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.Header().Set("Content-Type", "text/html")
+        w.Write([]byte("<html>"))
+        w.(http.Flusher).Flush()
+        <-release
+    })
+    defer server.Install()()
+
+    // 流式响应不等待读取拦截页识别的内容
+    ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+    defer cancel()
+    start := time.Now()
+    res, err := GetStream(ctx, "http://api.example.com/stream")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer res.Close()
+    if elapsed := time.Since(start); elapsed > time.Second*2 {
+        t.Fatalf("GetStream waited %v for the body", elapsed)
+    }
+}
@@ -0,0 +1,316 @@
+package req
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pkg/errors"
+)
+
+// requestEditKey 上下文中的请求修改函数
+type requestEditKey struct{}
+
+// WithChunked 强制使用 Transfer-Encoding: chunked 发送请求体
+func WithChunked() Option {
+    return withEdit(func(r *http.Request) error {
+        if r.Body != nil && r.Body != http.NoBody {
+            r.ContentLength = -1
+            r.TransferEncoding = []string{"chunked"}
+        }
+        return nil
+    })
+}
+
+// WithContentLength 为流式请求体指定 Content-Length，不使用 chunked 编码
+// 实际发送的长度与 n 不一致时请求失败
+func WithContentLength(n int64) Option {
+    return withEdit(func(r *http.Request) error {
+        r.ContentLength = n
+        r.TransferEncoding = nil
+        if n == 0 {
+            r.Body = http.NoBody
+        }
+        return nil
+    })
+}
+
+// WithExpectContinue 发送 Expect: 100-continue，服务端返回 100 Continue 后才发送请求体，
+// 认证失败等情况下可避免上传大文件，等待时长由 SetExpectContinueTimeout 设置
+func WithExpectContinue() Option {
+    return withEdit(func(r *http.Request) error {
+        if r.Body != nil && r.Body != http.NoBody {
+            r.Header = r.Header.Clone()
+            r.Header.Set("Expect", "100-continue")
+        }
+        return nil
+    })
+}
+
+// WithGzipBody 使用 gzip 压缩请求体并设置 Content-Encoding，服务端需支持压缩上传
+func WithGzipBody() Option {
+    return withEdit(func(r *http.Request) error {
+        return compressBody(r, "gzip", func(w io.Writer) (io.WriteCloser, error) {
+            return gzip.NewWriter(w), nil
+        })
+    })
+}
+
+// WithZstdBody 使用 zstd 压缩请求体并设置 Content-Encoding，服务端需支持压缩上传
+func WithZstdBody() Option {
+    return withEdit(func(r *http.Request) error {
+        return compressBody(r, "zstd", func(w io.Writer) (io.WriteCloser, error) {
+            return zstd.NewWriter(w)
+        })
+    })
+}
+
+// compressBody 压缩请求体，已设置 Content-Encoding 时不处理
+func compressBody(r *http.Request, encoding string, writer func(w io.Writer) (io.WriteCloser, error)) error {
+    if r.Body == nil || r.Body == http.NoBody || r.Header.Get("Content-Encoding") != "" {
+        return nil
+    }
+
+    var buf bytes.Buffer
+    w, err := writer(&buf)
+    if err != nil {
+        return errors.WithStack(err)
+    }
+    _, err = io.Copy(w, r.Body)
+    r.Body.Close()
+    if err == nil {
+        err = w.Close()
+    }
+    if err != nil {
+        return errors.WithStack(err)
+    }
+
+    data := buf.Bytes()
+    r.Header = r.Header.Clone()
+    r.Header.Set("Content-Encoding", encoding)
+    r.ContentLength = int64(len(data))
+    r.TransferEncoding = nil
+    r.Body = io.NopCloser(bytes.NewReader(data))
+    r.GetBody = func() (io.ReadCloser, error) {
+        return io.NopCloser(bytes.NewReader(data)), nil
+    }
+    return nil
+}
+
+// withEdit 发送前修改请求的选项
+func withEdit(edit func(r *http.Request) error) Option {
+    return func(o *options) {
+        o.edits = append(o.edits, edit)
+    }
+}
+
+// withRequestEdits 将请求修改函数放入参数中的上下文
+func withRequestEdits(args []interface{}, edits []func(r *http.Request) error) []interface{} {
+    if len(edits) == 0 {
+        return args
+    }
+
+    ctx, index := context.Background(), -1
+    for i, arg := range args {
+        if c, ok := arg.(context.Context); ok {
+            ctx, index = c, i
+        }
+    }
+
+    prev, _ := ctx.Value(requestEditKey{}).([]func(r *http.Request) error)
+    ctx = context.WithValue(ctx, requestEditKey{}, append(prev[:len(prev):len(prev)], edits...))
+    args = append([]interface{}{}, args...)
+    if index >= 0 {
+        args[index] = ctx
+        return args
+    }
+    return append(args, ctx)
+}
+
+// withContextValue 将值写入参数中的上下文，未传入上下文时追加
+func withContextValue(args []interface{}, key, value interface{}) []interface{} {
+    ctx, index := context.Background(), -1
+    for i, arg := range args {
+        if c, ok := arg.(context.Context); ok {
+            ctx, index = c, i
+        }
+    }
+
+    ctx = context.WithValue(ctx, key, value)
+    args = append([]interface{}{}, args...)
+    if index >= 0 {
+        args[index] = ctx
+        return args
+    }
+    return append(args, ctx)
+}
+
+// replayable 缓存没有 GetBody 的请求体，使认证质询后可以重新发送
+func replayable(r *http.Request) (*http.Request, error) {
+    if r.Body == nil || r.Body == http.NoBody || r.GetBody != nil {
+        return r, nil
+    }
+
+    data, err := io.ReadAll(r.Body)
+    r.Body.Close()
+    if err != nil {
+        return nil, errors.WithStack(err)
+    }
+    r = r.Clone(r.Context())
+    r.Body = io.NopCloser(bytes.NewReader(data))
+    r.GetBody = func() (io.ReadCloser, error) {
+        return io.NopCloser(bytes.NewReader(data)), nil
+    }
+    return r, nil
+}
+
+// resend 复制请求并设置请求头，请求体从 GetBody 重新读取
+func resend(r *http.Request, key, value string) (*http.Request, error) {
+    next := r.Clone(r.Context())
+    next.Header.Set(key, value)
+    if r.GetBody != nil {
+        body, err := r.GetBody()
+        if err != nil {
+            return nil, errors.WithStack(err)
+        }
+        next.Body = body
+    }
+    return next, nil
+}
+
+// editTransport 发送前按上下文修改请求的传输层
+type editTransport struct {
+    base http.RoundTripper
+}
+
+// RoundTrip 修改并发送请求
+func (t *editTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+    if edits, ok := r.Context().Value(requestEditKey{}).([]func(r *http.Request) error); ok {
+        r = r.Clone(r.Context())
+        for _, edit := range edits {
+            if err := edit(r); err != nil {
+                if r.Body != nil {
+                    r.Body.Close()
+                }
+                return nil, err
+            }
+        }
+    }
+    return t.base.RoundTrip(r)
+}
+
+// RelatedPart multipart/related 内容部分
+type RelatedPart struct {
+    // ContentType 内容类型
+    ContentType string
+    // ContentID 可选的 Content-ID
+    ContentID string
+    // Body 内容
+    Body []byte
+    // Reader 流式内容，设置时忽略 Body
+    Reader io.Reader
+}
+
+// WithMultipartRelated 使用 multipart/related 发送请求体，如 Google Drive 的元数据与文件内容上传
+// 第一部分为根部分，其类型写入 Content-Type 的 type 参数，包含 Reader 时以流式发送
+// Reader 均实现 io.Seeker 时每次发送前回到初始位置，可以重试，否则请求只发送一次，不重试
+func WithMultipartRelated(parts ...RelatedPart) Option {
+    if len(parts) == 0 {
+        return func(o *options) {
+            o.err = errors.New("multipart/related: no parts")
+        }
+    }
+
+    var b [16]byte
+    _, _ = rand.Read(b[:])
+    boundary := fmt.Sprintf("%x", b[:])
+    contentType := mime.FormatMediaType("multipart/related", map[string]string{
+        "boundary": boundary,
+        "type":     parts[0].ContentType,
+    })
+
+    // chunks 与 readers 交替组成请求体，chunks 比 readers 多一个
+    var (
+        chunks  [][]byte
+        readers []io.Reader
+        offsets []int64
+        seek    = true
+        buf     bytes.Buffer
+    )
+    for i, part := range parts {
+        if i > 0 {
+            buf.WriteString("\r\n")
+        }
+        fmt.Fprintf(&buf, "--%s\r\nContent-Type: %s\r\n", boundary, part.ContentType)
+        if part.ContentID != "" {
+            fmt.Fprintf(&buf, "Content-ID: <%s>\r\n", strings.Trim(part.ContentID, "<>"))
+        }
+        buf.WriteString("\r\n")
+
+        if part.Reader != nil {
+            chunks = append(chunks, append([]byte{}, buf.Bytes()...))
+            readers = append(readers, part.Reader)
+            buf.Reset()
+
+            var offset int64
+            seeker, ok := part.Reader.(io.Seeker)
+            if ok {
+                offset, ok = seekOffset(seeker)
+            }
+            seek = seek && ok
+            offsets = append(offsets, offset)
+            continue
+        }
+        buf.Write(part.Body)
+    }
+    fmt.Fprintf(&buf, "\r\n--%s--\r\n", boundary)
+    chunks = append(chunks, buf.Bytes())
+
+    if len(readers) == 0 {
+        return func(o *options) {
+            o.body, o.contentType = chunks[0], contentType
+        }
+    }
+
+    // 不可回退时只能读取一次，多次应用选项共用同一个请求体
+    var once io.Reader
+    if !seek {
+        once = relatedReader(chunks, readers)
+    }
+    return func(o *options) {
+        o.contentType = contentType
+        if !seek {
+            o.body, o.oneShotBody = once, true
+            return
+        }
+        for i, r := range readers {
+            if _, err := r.(io.Seeker).Seek(offsets[i], io.SeekStart); err != nil {
+                o.err = errors.WithStack(err)
+                return
+            }
+        }
+        o.body = relatedReader(chunks, readers)
+    }
+}
+
+// seekOffset Reader 的当前位置
+func seekOffset(seeker io.Seeker) (int64, bool) {
+    offset, err := seeker.Seek(0, io.SeekCurrent)
+    return offset, err == nil
+}
+
+// relatedReader 按顺序拼接分隔内容与各部分 Reader
+func relatedReader(chunks [][]byte, readers []io.Reader) io.Reader {
+    all := make([]io.Reader, 0, len(chunks)+len(readers))
+    for i, r := range readers {
+        all = append(all, bytes.NewReader(chunks[i]), r)
+    }
+    return io.MultiReader(append(all, bytes.NewReader(chunks[len(chunks)-1]))...)
+}
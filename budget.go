@@ -0,0 +1,124 @@
+package req
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrBudgetExceeded 批量请求超出时间预算，条目未执行或已取消
+var ErrBudgetExceeded = errors.New("batch time budget exceeded")
+
+// ErrErrorRateExceeded 批量请求失败率超出阈值，条目未执行
+var ErrErrorRateExceeded = errors.New("batch error rate exceeded")
+
+// WithBatchBudget 设置批量请求时间预算，超出后不再调度新条目，未执行的条目返回 ErrBudgetExceeded
+// cancel 为 true 时同时取消执行中的条目，否则等待其完成
+func WithBatchBudget(budget time.Duration, cancel bool) Option {
+    return func(o *options) {
+        o.batchBudget, o.budgetCancel = budget, cancel
+    }
+}
+
+// WithErrorBudget 设置批量请求失败率阈值，已完成 min 个条目后失败率超过 rate 时中止，
+// 不再调度新条目，未执行的条目返回 ErrErrorRateExceeded，执行中的条目继续完成
+func WithErrorBudget(rate float64, min int) Option {
+    return func(o *options) {
+        o.errorRate, o.errorMin = rate, min
+    }
+}
+
+// batchBudget 批量请求时间预算与失败率阈值
+type batchBudget struct {
+    ctx      context.Context
+    cancel   context.CancelFunc
+    done     chan struct{}
+    exceeded int32
+    opts     *options
+
+    mutex sync.Mutex
+    // reason 中止原因，ErrBudgetExceeded 或 ErrErrorRateExceeded
+    reason    error
+    completed int
+    failed    int
+}
+
+// newBatchBudget 按选项开始计时，未设置预算时不限制
+func newBatchBudget(ctx context.Context, opts *options) *batchBudget {
+    ctx, cancel := context.WithCancel(ctx)
+    b := &batchBudget{ctx: ctx, cancel: cancel, done: make(chan struct{}), opts: opts}
+    if opts.batchBudget <= 0 {
+        return b
+    }
+
+    after := conf().clock.After(opts.batchBudget)
+    go func() {
+        select {
+        case <-after:
+            b.exceed(ErrBudgetExceeded, opts.budgetCancel)
+        case <-b.done:
+        }
+    }()
+    return b
+}
+
+// exceed 记录中止原因，只记录第一次
+func (b *batchBudget) exceed(reason error, cancel bool) {
+    b.mutex.Lock()
+    defer b.mutex.Unlock()
+    if b.reason != nil {
+        return
+    }
+    b.reason = reason
+    atomic.StoreInt32(&b.exceeded, 1)
+    if cancel {
+        b.cancel()
+    }
+}
+
+// record 记录条目结果，失败率超出阈值时中止
+func (b *batchBudget) record(err error) {
+    if b.opts.errorRate <= 0 || (err != nil && b.expired() && b.ctx.Err() != nil) {
+        return
+    }
+
+    b.mutex.Lock()
+    b.completed++
+    if err != nil {
+        b.failed++
+    }
+    exceeded := b.completed >= b.opts.errorMin && float64(b.failed)/float64(b.completed) > b.opts.errorRate
+    b.mutex.Unlock()
+    if exceeded {
+        b.exceed(ErrErrorRateExceeded, false)
+    }
+}
+
+// expired 是否已中止
+func (b *batchBudget) expired() bool {
+    return atomic.LoadInt32(&b.exceeded) == 1
+}
+
+// err 中止原因
+func (b *batchBudget) err() error {
+    b.mutex.Lock()
+    defer b.mutex.Unlock()
+    return errors.WithStack(b.reason)
+}
+
+// wrap 超出预算后因取消产生的错误转换为中止原因
+func (b *batchBudget) wrap(err error) error {
+    if err != nil && b.expired() && b.ctx.Err() != nil {
+        return b.err()
+    }
+    return err
+}
+
+// stop 结束计时
+func (b *batchBudget) stop() {
+    close(b.done)
+    b.cancel()
+}
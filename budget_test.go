@@ -0,0 +1,75 @@
+package req
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestBatchBudgetExceeded(t *testing.T) {
+    clock := withFakeClock(t)
+    withRetry(t, 0, 0)
+    withHostLimit(t, 1)
+    server := NewMockServer()
+    defer server.Close()
+    started := make(chan struct{}, 3)
+    server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        started <- struct{}{}
+        <-r.Context().Done()
+    })
+    defer server.Install()()
+
+    urls := []string{"http://budget.example.com/1", "http://budget.example.com/2", "http://budget.example.com/3"}
+    result := make(chan *BatchResult, 1)
+    go func() {
+        result <- Batch(context.Background(), urls, WithBatchBudget(time.Minute, true))
+    }()
+
+    // 第一个条目执行中超出预算，取消执行中的条目且不再调度其他条目
+    <-started
+    clock.BlockUntil(1)
+    clock.Advance(time.Minute)
+
+    select {
+    case r := <-result:
+        if summary := r.Summary(); summary.Skipped != 3 || !r.Incomplete() {
+            t.Fatalf("summary = %+v, want 3 skipped", summary)
+        }
+        for _, entry := range r.Entries {
+            if !errors.Is(entry.Err, ErrBudgetExceeded) {
+                t.Fatalf("entry %d err = %v, want ErrBudgetExceeded", entry.Index, entry.Err)
+            }
+        }
+    case <-time.After(time.Second * 5):
+        t.Fatal("batch did not stop after the budget was exceeded")
+    }
+    if n := len(started); n != 0 {
+        t.Fatalf("%d entries started after the budget was exceeded", n)
+    }
+}
+
+func TestBatchErrorBudget(t *testing.T) {
+    withRetry(t, 0, 0)
+    withHostLimit(t, 1)
+    server := NewMockServer()
+    defer server.Close()
+    route := server.Route(http.MethodGet, "/item/{id}").Status(http.StatusInternalServerError)
+    defer server.Install()()
+
+    var urls []string
+    for _, id := range []string{"1", "2", "3", "4", "5"} {
+        urls = append(urls, "http://errors.example.com/item/"+id)
+    }
+    r := Batch(context.Background(), urls, WithErrorBudget(0.5, 2))
+
+    // 完成 2 个条目后失败率超过阈值，其余条目不执行
+    if calls := route.Calls(); calls != 2 {
+        t.Fatalf("calls = %d, want 2", calls)
+    }
+    if summary := r.Summary(); summary.Failed != 2 || summary.Skipped != 3 {
+        t.Fatalf("summary = %+v, want 2 failed and 3 skipped", summary)
+    }
+}
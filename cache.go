@@ -0,0 +1,117 @@
+package req
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"unsafe"
+
+	"github.com/imroc/req"
+)
+
+// defaultCacheHeaders POST 缓存默认参与计算的请求头
+var defaultCacheHeaders = []string{"Accept", "Authorization", "Content-Type"}
+
+// WithCache 开启 POST 等非 GET 请求的缓存，默认不缓存
+// 缓存名称由链接、规范化后的请求体与 headers 指定的请求头计算，未指定时使用 Accept/Authorization/Content-Type
+// 请求体为 io.Reader 时无法计算，不缓存
+func WithCache(headers ...string) Option {
+    return func(o *options) {
+        o.cache = true
+        o.cacheHeaders = headers
+    }
+}
+
+// bodyCacheName 按请求体计算的缓存名称，无法计算时返回空
+func bodyCacheName(method, url string, opts *options, v []interface{}) string {
+    path := conf().cachePath
+    if path == "" || cacheBypassed(url) {
+        return ""
+    }
+    headers := opts.cacheHeaders
+    if len(headers) == 0 {
+        headers = defaultCacheHeaders
+    }
+
+    var key bytes.Buffer
+    key.WriteString(method + " " + url + "\n")
+    for _, name := range headers {
+        if value := headerValue(v, name); value != "" {
+            fmt.Fprintf(&key, "%s: %s\n", http.CanonicalHeaderKey(name), value)
+        }
+    }
+    // 选项编码的请求体不经过请求头参数，内容类型单独参与计算
+    if opts.contentType != "" {
+        fmt.Fprintf(&key, "body-type: %s\n", opts.contentType)
+    }
+
+    for _, arg := range cacheArgs(v) {
+        switch a := arg.(type) {
+        case req.Header, http.Header, Option:
+            continue
+        case []byte:
+            key.Write(canonicalBody(a))
+        case string:
+            key.Write(canonicalBody([]byte(a)))
+        case io.Reader:
+            return ""
+        case req.Param, req.QueryParam, map[string]interface{}:
+            data, _ := json.Marshal(a)
+            key.Write(data)
+        default:
+            // 按编码后的内容计算，指针与 BodyJSON 等包装类型不影响结果
+            data, err := json.Marshal(unwrapBody(a))
+            if err != nil {
+                return ""
+            }
+            fmt.Fprintf(&key, "%T", a)
+            key.Write(canonicalBody(data))
+        }
+        key.WriteString("\n")
+    }
+
+    return fmt.Sprintf("%s/.%s.%s.cache", path, md5sum(key.Bytes()), method)
+}
+
+// unwrapBody 取出 imroc/req BodyJSON/BodyXML 包装的内容，字段不可导出，只能通过 unsafe 读取
+func unwrapBody(a interface{}) interface{} {
+    if !wrappedBody(a) {
+        return a
+    }
+    field := reflect.ValueOf(a).Elem().Field(0)
+    return reflect.NewAt(field.Type(), unsafe.Pointer(field.UnsafeAddr())).Elem().Interface()
+}
+
+// wrappedBody 是否为 imroc/req BodyJSON/BodyXML 包装的请求体
+func wrappedBody(a interface{}) bool {
+    v := reflect.ValueOf(a)
+    if v.Kind() != reflect.Ptr || v.IsNil() {
+        return false
+    }
+    elem := v.Elem()
+    return elem.Kind() == reflect.Struct && elem.Type().PkgPath() == reflect.TypeOf(req.Header{}).PkgPath() && elem.NumField() == 1
+}
+
+// canonicalBody 规范化请求体，JSON 按键排序并去除空白，表单按键排序，其他内容原样返回
+func canonicalBody(body []byte) []byte {
+    trimmed := bytes.TrimSpace(body)
+    if len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+        var value interface{}
+        if err := json.Unmarshal(trimmed, &value); err == nil {
+            data, _ := json.Marshal(value)
+            return data
+        }
+    }
+
+    if s := string(trimmed); strings.Contains(s, "=") && !strings.ContainsAny(s, " \n") {
+        pairs := strings.Split(s, "&")
+        sort.Strings(pairs)
+        return []byte(strings.Join(pairs, "&"))
+    }
+    return body
+}
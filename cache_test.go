@@ -0,0 +1,68 @@
+package req
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/imroc/req"
+)
+
+// withCachePath 临时使用测试目录作为缓存目录
+func withCachePath(t *testing.T) {
+    path := conf().cachePath
+    SetCachePath(t.TempDir())
+    t.Cleanup(func() { updateConfig(func(c *config) { c.cachePath = path }) })
+}
+
+func TestBodyCacheName(t *testing.T) {
+    withCachePath(t)
+    type order struct {
+        ID    int      `json:"id"`
+        Items []string `json:"items"`
+    }
+    name := func(body interface{}, opts ...Option) string {
+        o, _ := splitOptions(optionArgs(opts))
+        args := []interface{}{req.Header{"Accept": "application/json"}}
+        if body != nil {
+            args = append(args, body)
+        }
+        if o.body != nil {
+            args = append(args, o.body)
+        }
+        return bodyCacheName(http.MethodPost, "http://api.example.com/orders", o, args)
+    }
+
+    first := name(req.BodyJSON(&order{ID: 1, Items: []string{"a"}}))
+    if first == "" {
+        t.Fatal("no cache name for json body")
+    }
+    if again := name(req.BodyJSON(&order{ID: 1, Items: []string{"a"}})); again != first {
+        t.Errorf("identical pointer bodies: %s != %s", again, first)
+    }
+    if value := name(req.BodyJSON(order{ID: 1, Items: []string{"a"}})); value != first {
+        t.Errorf("pointer and value bodies differ: %s != %s", value, first)
+    }
+    if other := name(req.BodyJSON(&order{ID: 2})); other == first {
+        t.Error("different bodies share a cache name")
+    }
+
+    // 选项编码的请求体按内容类型区分
+    data := []byte(`{"id":1}`)
+    withType := func(contentType string) Option {
+        return func(o *options) {
+            o.body, o.contentType = data, contentType
+        }
+    }
+    if name(nil, withType("application/json")) == name(nil, withType("application/x-msgpack")) {
+        t.Error("content type from options not part of the cache name")
+    }
+}
+
+// optionArgs 选项转换为请求参数
+func optionArgs(opts []Option) []interface{} {
+    args := make([]interface{}, 0, len(opts))
+    for _, opt := range opts {
+        args = append(args, opt)
+    }
+    return args
+}
@@ -0,0 +1,349 @@
+package req
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+    bucketCacheEntries = []byte("entries")
+    bucketCacheHosts   = []byte("hosts")
+)
+
+// CacheEntry 缓存索引条目
+type CacheEntry struct {
+    // Name 缓存文件路径
+    Name      string    `json:"name"`
+    URL       string    `json:"url"`
+    Method    string    `json:"method"`
+    Host      string    `json:"host"`
+    Size      int64     `json:"size"`
+    Status    int       `json:"status"`
+    FetchedAt time.Time `json:"fetched_at"`
+    // Hash 去重缓存的内容 SHA-256，未去重时为空
+    Hash string `json:"hash,omitempty"`
+    // Meta 附加信息，如 ChromeGet 的最终链接与渲染耗时
+    Meta map[string]string `json:"meta,omitempty"`
+}
+
+// cacheIndex 缓存目录下的索引数据库，缓存目录变化时重新打开
+var cacheIndex struct {
+    sync.Mutex
+    path string
+    db   *bolt.DB
+    // err 打开失败的错误，同一目录不再重试，避免每次写入缓存都等待文件锁
+    err error
+}
+
+// openCacheIndex 打开当前缓存目录的索引，未设置缓存目录时返回 nil
+func openCacheIndex() (*bolt.DB, error) {
+    path := conf().cachePath
+    cacheIndex.Lock()
+    defer cacheIndex.Unlock()
+
+    if path == "" {
+        return nil, nil
+    }
+    if cacheIndex.path == path && (cacheIndex.db != nil || cacheIndex.err != nil) {
+        return cacheIndex.db, cacheIndex.err
+    }
+    if cacheIndex.db != nil {
+        cacheIndex.db.Close()
+        cacheIndex.db = nil
+    }
+
+    cacheIndex.path, cacheIndex.err = path, nil
+    db, err := bolt.Open(filepath.Join(path, ".index.db"), 0600, &bolt.Options{Timeout: time.Second})
+    if err != nil {
+        cacheIndex.err = errors.WithStack(err)
+        return nil, cacheIndex.err
+    }
+    err = db.Update(func(tx *bolt.Tx) error {
+        for _, name := range [][]byte{bucketCacheEntries, bucketCacheHosts} {
+            if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+                return err
+            }
+        }
+        return nil
+    })
+    if err != nil {
+        db.Close()
+        cacheIndex.err = errors.WithStack(err)
+        return nil, cacheIndex.err
+    }
+
+    cacheIndex.db = db
+    return db, nil
+}
+
+// closeCacheIndex 关闭缓存索引，之后写入缓存只写入文件
+func closeCacheIndex() error {
+    cacheIndex.Lock()
+    defer cacheIndex.Unlock()
+
+    if cacheIndex.db == nil {
+        return nil
+    }
+    err := cacheIndex.db.Close()
+    cacheIndex.db, cacheIndex.err = nil, errors.WithStack(ErrClosed)
+    return errors.WithStack(err)
+}
+
+// hostKey 主机索引键
+func hostKey(host, name string) []byte {
+    return []byte(host + "\x00" + name)
+}
+
+// storeCache 写入缓存文件并记录索引，索引不可用时只写入文件
+func storeCache(name, method, url string, data []byte) error {
+    return storeCacheMeta(name, method, url, data, nil)
+}
+
+// storeCacheMeta 写入缓存文件并在索引中记录附加信息，索引不可用时不保存附加信息
+func storeCacheMeta(name, method, url string, data []byte, meta map[string]string) error {
+    db, err := openCacheIndex()
+    if err != nil || db == nil {
+        return writeCache(name, data)
+    }
+
+    var hash string
+    if conf().cacheDedup {
+        hash = contentHash(data)
+    }
+
+    entry := CacheEntry{
+        Name:      name,
+        URL:       url,
+        Method:    method,
+        Host:      urlHost(url),
+        Size:      int64(len(data)),
+        Status:    200,
+        FetchedAt: conf().clock.Now(),
+        Hash:      hash,
+        Meta:      meta,
+    }
+    value, _ := jsoniter.Marshal(entry)
+    var orphan string
+    err = db.Batch(func(tx *bolt.Tx) error {
+        orphan = ""
+        entries := tx.Bucket(bucketCacheEntries)
+        var old CacheEntry
+        if prev := entries.Get([]byte(name)); prev != nil {
+            _ = jsoniter.Unmarshal(prev, &old)
+        }
+        if old.Hash != hash {
+            if _, err := refObject(tx, hash, 1); err != nil {
+                return err
+            }
+            zero, err := refObject(tx, old.Hash, -1)
+            if err != nil {
+                return err
+            }
+            if zero {
+                orphan = old.Hash
+            }
+        }
+
+        if err := entries.Put([]byte(name), value); err != nil {
+            return err
+        }
+        return tx.Bucket(bucketCacheHosts).Put(hostKey(entry.Host, name), nil)
+    })
+    if err == nil && orphan != "" {
+        removeObjects(db, []string{orphan})
+    }
+    if err != nil || hash == "" {
+        return writeCache(name, data)
+    }
+
+    // 引用计数增加后再写入对象，避免并发删除最后一个引用时误删
+    if err = writeObject(hash, data); err != nil {
+        return err
+    }
+    return writeCache(name, append(cacheRefPrefix[:len(cacheRefPrefix):len(cacheRefPrefix)], hash...))
+}
+
+// cachedAt 缓存抓取时间，索引不可用时使用文件修改时间，未缓存时返回零值
+func cachedAt(name string) time.Time {
+    info, err := os.Stat(name)
+    if err != nil {
+        return time.Time{}
+    }
+
+    if entry, ok := cacheEntry(name); ok && !entry.FetchedAt.IsZero() {
+        return entry.FetchedAt
+    }
+    return info.ModTime()
+}
+
+// cacheEntry 索引中的缓存条目，索引不可用或未记录时 ok 为 false
+func cacheEntry(name string) (entry CacheEntry, ok bool) {
+    db, err := openCacheIndex()
+    if err != nil || db == nil {
+        return entry, false
+    }
+    _ = db.View(func(tx *bolt.Tx) error {
+        if value := tx.Bucket(bucketCacheEntries).Get([]byte(name)); value != nil {
+            ok = jsoniter.Unmarshal(value, &entry) == nil
+        }
+        return nil
+    })
+    return entry, ok
+}
+
+// CacheEntries 查询主机的缓存条目，host 为空时返回全部
+func CacheEntries(host string) ([]CacheEntry, error) {
+    db, err := openCacheIndex()
+    if err != nil || db == nil {
+        return nil, err
+    }
+
+    var list []CacheEntry
+    err = db.View(func(tx *bolt.Tx) error {
+        entries := tx.Bucket(bucketCacheEntries)
+        add := func(value []byte) error {
+            var entry CacheEntry
+            if err := jsoniter.Unmarshal(value, &entry); err != nil {
+                return err
+            }
+            list = append(list, entry)
+            return nil
+        }
+
+        if host == "" {
+            return entries.ForEach(func(k, v []byte) error { return add(v) })
+        }
+        prefix := hostKey(strings.ToLower(host), "")
+        cursor := tx.Bucket(bucketCacheHosts).Cursor()
+        for k, _ := cursor.Seek(prefix); k != nil && strings.HasPrefix(string(k), string(prefix)); k, _ = cursor.Next() {
+            if value := entries.Get(k[len(prefix):]); value != nil {
+                if err := add(value); err != nil {
+                    return err
+                }
+            }
+        }
+        return nil
+    })
+    return list, errors.WithStack(err)
+}
+
+// removeCacheEntries 删除缓存文件与索引条目
+func removeCacheEntries(list []CacheEntry) (int, error) {
+    db, err := openCacheIndex()
+    if err != nil || db == nil {
+        return 0, err
+    }
+
+    removed := 0
+    for _, entry := range list {
+        if err = fileRemove(entry.Name); err != nil {
+            return removed, errors.WithStack(err)
+        }
+        removed++
+    }
+    var orphans []string
+    err = db.Update(func(tx *bolt.Tx) error {
+        for _, entry := range list {
+            if err := tx.Bucket(bucketCacheEntries).Delete([]byte(entry.Name)); err != nil {
+                return err
+            }
+            if err := tx.Bucket(bucketCacheHosts).Delete(hostKey(entry.Host, entry.Name)); err != nil {
+                return err
+            }
+            zero, err := refObject(tx, entry.Hash, -1)
+            if err != nil {
+                return err
+            }
+            if zero {
+                orphans = append(orphans, entry.Hash)
+            }
+        }
+        return nil
+    })
+    if err == nil {
+        removeObjects(db, orphans)
+    }
+    return removed, errors.WithStack(err)
+}
+
+// PurgeCacheHost 删除主机的全部缓存，返回删除数量
+func PurgeCacheHost(host string) (int, error) {
+    list, err := CacheEntries(host)
+    if err != nil || len(list) == 0 {
+        return 0, err
+    }
+    return removeCacheEntries(list)
+}
+
+// EvictCache 按抓取时间从旧到新删除缓存，直到总大小不超过 maxSize，返回删除数量
+func EvictCache(maxSize int64) (int, error) {
+    list, err := CacheEntries("")
+    if err != nil {
+        return 0, err
+    }
+
+    var total int64
+    for _, entry := range list {
+        total += entry.Size
+    }
+    sort.Slice(list, func(i, j int) bool { return list[i].FetchedAt.Before(list[j].FetchedAt) })
+
+    n := 0
+    for ; n < len(list) && total > maxSize; n++ {
+        total -= list[n].Size
+    }
+    if n == 0 {
+        return 0, nil
+    }
+    return removeCacheEntries(list[:n])
+}
+
+// unindexCache 删除缓存文件的索引条目
+func unindexCache(name string) {
+    db, err := openCacheIndex()
+    if err != nil || db == nil {
+        return
+    }
+    var orphan string
+    err = db.Update(func(tx *bolt.Tx) error {
+        entries := tx.Bucket(bucketCacheEntries)
+        var entry CacheEntry
+        if value := entries.Get([]byte(name)); value == nil || jsoniter.Unmarshal(value, &entry) != nil {
+            return nil
+        }
+        if err := entries.Delete([]byte(name)); err != nil {
+            return err
+        }
+        zero, err := refObject(tx, entry.Hash, -1)
+        if err != nil {
+            return err
+        }
+        if zero {
+            orphan = entry.Hash
+        }
+        return tx.Bucket(bucketCacheHosts).Delete(hostKey(entry.Host, name))
+    })
+    if err == nil && orphan != "" {
+        removeObjects(db, []string{orphan})
+    }
+}
+
+// removeCacheFile 删除缓存文件与索引
+func removeCacheFile(name string) error {
+    if name == "" {
+        return nil
+    }
+    unindexCache(name)
+    if err := os.Remove(name); err != nil && !os.IsNotExist(err) {
+        return errors.WithStack(err)
+    }
+    return nil
+}
@@ -0,0 +1,95 @@
+package req
+
+import (
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// cacheTTL 按链接匹配的缓存有效期
+type cacheTTL struct {
+    pattern *regexp.Regexp
+    ttl     time.Duration
+}
+
+// compileURLPattern 编译链接匹配规则，以 re: 开头时为正则表达式，否则为 glob，* 可匹配包括 / 在内的任意字符
+func compileURLPattern(pattern string) (*regexp.Regexp, error) {
+    if strings.HasPrefix(pattern, "re:") {
+        re, err := regexp.Compile(pattern[3:])
+        return re, errors.Wrapf(err, "invalid pattern: %s", pattern)
+    }
+
+    expr := regexp.QuoteMeta(pattern)
+    expr = strings.ReplaceAll(expr, `\*`, ".*")
+    expr = strings.ReplaceAll(expr, `\?`, ".")
+    return regexp.MustCompile("^" + expr + "$"), nil
+}
+
+// SetCacheBypass 设置不缓存的链接规则，如认证接口、实时行情，为空时清除
+// 规则以 re: 开头时为正则表达式，否则为 glob，如 https://api.example.com/auth/*
+func SetCacheBypass(patterns ...string) error {
+    list := make([]*regexp.Regexp, 0, len(patterns))
+    for _, pattern := range patterns {
+        re, err := compileURLPattern(pattern)
+        if err != nil {
+            return err
+        }
+        list = append(list, re)
+    }
+    updateConfig(func(c *config) { c.cacheBypass = list })
+    return nil
+}
+
+// SetCacheTTL 设置匹配链接的缓存有效期，过期后重新请求，规则按设置顺序匹配，先设置的优先
+// 未匹配任何规则的缓存长期有效
+func SetCacheTTL(pattern string, ttl time.Duration) error {
+    re, err := compileURLPattern(pattern)
+    if err != nil {
+        return err
+    }
+    updateConfig(func(c *config) {
+        c.cacheTTLs = append(c.cacheTTLs[:len(c.cacheTTLs):len(c.cacheTTLs)], cacheTTL{pattern: re, ttl: ttl})
+    })
+    return nil
+}
+
+// ClearCacheTTL 清除全部缓存有效期规则
+func ClearCacheTTL() {
+    updateConfig(func(c *config) { c.cacheTTLs = nil })
+}
+
+// cacheBypassed 链接是否不缓存
+func cacheBypassed(url string) bool {
+    for _, re := range conf().cacheBypass {
+        if re.MatchString(url) {
+            return true
+        }
+    }
+    return false
+}
+
+// cacheTTLFor 链接的缓存有效期，为 0 时长期有效
+func cacheTTLFor(url string) time.Duration {
+    for _, rule := range conf().cacheTTLs {
+        if rule.pattern.MatchString(url) {
+            return rule.ttl
+        }
+    }
+    return 0
+}
+
+// cacheHit 缓存文件是否存在且未过期
+func cacheHit(name, url string) bool {
+    if name == "" {
+        return false
+    }
+    info, err := os.Stat(name)
+    if err != nil {
+        return false
+    }
+    ttl := cacheTTLFor(url)
+    return ttl <= 0 || conf().clock.Now().Sub(info.ModTime()) < ttl
+}
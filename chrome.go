@@ -0,0 +1,259 @@
+package req
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+)
+
+// DefaultDismissSelectors 常见 Cookie 同意横幅、年龄确认与继续访问按钮
+var DefaultDismissSelectors = []string{
+    "#onetrust-accept-btn-handler",
+    "#CybotCookiebotDialogBodyLevelButtonLevelOptinAllowAll",
+    "#didomi-notice-agree-button",
+    "#truste-consent-button",
+    "[data-testid='uc-accept-all-button']",
+    ".fc-cta-consent",
+    ".cc-allow",
+    ".cc-dismiss",
+    "button#L2AGLb",
+    "button[aria-label='Accept all']",
+    "button[mode='primary'][data-role='accept']",
+    "#age-gate-yes",
+    ".age-gate button[type='submit']",
+    "#proceed-button",
+}
+
+// SetChromeDismiss 设置 ChromeGet 提取内容前点击的遮罩按钮选择器，为空时不点击
+// 每个选择器点击第一个可见元素，如 SetChromeDismiss(DefaultDismissSelectors...)
+// 不处理跨域 iframe 中的按钮
+func SetChromeDismiss(selectors ...string) {
+    list := append([]string{}, selectors...)
+    updateConfig(func(c *config) { c.chromeDismiss = list })
+}
+
+// SetChromeDismissWait 设置点击遮罩按钮后等待页面更新的时长，默认 500 毫秒
+func SetChromeDismissWait(wait time.Duration) {
+    updateConfig(func(c *config) { c.chromeDismissWait = wait })
+}
+
+// WithChromeWait ChromeGet 提取内容前等待选择器匹配的元素可见
+func WithChromeWait(selector string) Option {
+    return func(o *options) {
+        o.chromeWait = selector
+    }
+}
+
+// WithChromeSelector ChromeGet 提取选择器匹配元素的 HTML，默认 body
+func WithChromeSelector(selector string) Option {
+    return func(o *options) {
+        o.chromeSelector = selector
+    }
+}
+
+// WithChromeSleep ChromeGet 页面加载后等待的时长，用于异步渲染的内容
+func WithChromeSleep(d time.Duration) Option {
+    return func(o *options) {
+        o.chromeSleep = d
+    }
+}
+
+// ChromePage ChromeFetch 结果
+type ChromePage struct {
+    Body string
+    // URL 最终链接，包含重定向与脚本跳转
+    URL string
+    // StatusCode 主文档的状态码，命中缓存时为缓存时记录的状态码
+    StatusCode int
+    // Header 主文档的响应头，命中缓存时为 nil
+    Header http.Header
+    // Cached 是否命中缓存
+    Cached bool
+}
+
+// listenDocument 记录主框架最后一次文档响应的状态码与响应头，返回的函数在渲染结束后调用，将结果写入 page
+func listenDocument(ctx context.Context, page *ChromePage) func() {
+    var (
+        mutex sync.Mutex
+        frame cdp.FrameID
+        last  *network.Response
+    )
+    chromedp.ListenTarget(ctx, func(ev interface{}) {
+        e, ok := ev.(*network.EventResponseReceived)
+        if !ok || e.Type != network.ResourceTypeDocument || e.Response == nil {
+            return
+        }
+        mutex.Lock()
+        defer mutex.Unlock()
+        // 第一个文档响应属于主框架，之后忽略 iframe 的文档
+        if frame == "" {
+            frame = e.FrameID
+        }
+        if e.FrameID == frame {
+            last = e.Response
+        }
+    })
+
+    return func() {
+        mutex.Lock()
+        defer mutex.Unlock()
+        if last == nil {
+            return
+        }
+        page.StatusCode = int(last.Status)
+        page.Header = http.Header{}
+        for key, value := range last.Headers {
+            // 同名响应头的多个值以换行分隔
+            for _, v := range strings.Split(fmt.Sprint(value), "\n") {
+                page.Header.Add(key, v)
+            }
+        }
+        if page.URL == "" {
+            page.URL = last.URL
+        }
+    }
+}
+
+// cachedChromePage 缓存中的页面，索引中没有记录时状态码为 200、链接为请求链接
+func cachedChromePage(name, url string, data []byte) *ChromePage {
+    page := &ChromePage{Body: string(data), URL: url, StatusCode: http.StatusOK, Cached: true}
+    if entry, ok := cacheEntry(name); ok {
+        if finalURL := entry.Meta["final_url"]; finalURL != "" {
+            page.URL = finalURL
+        }
+        if code, err := strconv.Atoi(entry.Meta["status"]); err == nil {
+            page.StatusCode = code
+        }
+    }
+    return page
+}
+
+// chromeContext 按代理与 Chrome 配置创建浏览器上下文，cancel 关闭浏览器并释放配置
+func chromeContext(ctx context.Context, url string, opts *options) (context.Context, func(), error) {
+    proxy, err := proxyFor(url)
+    if err != nil {
+        return nil, nil, err
+    }
+    profile, unlock, err := openChromeProfile(opts)
+    if err != nil {
+        return nil, nil, err
+    }
+
+    cancels := []context.CancelFunc{unlock}
+    if proxy != nil || profile != "" {
+        allocator := append([]chromedp.ExecAllocatorOption{}, chromedp.DefaultExecAllocatorOptions[:]...)
+        if proxy != nil {
+            allocator = append(allocator, chromedp.ProxyServer(proxy.String()))
+        }
+        if profile != "" {
+            allocator = append(allocator, chromedp.UserDataDir(profile))
+        }
+        var cancel context.CancelFunc
+        ctx, cancel = chromedp.NewExecAllocator(ctx, allocator...)
+        cancels = append(cancels, cancel)
+    }
+    ctx, cancel := chromedp.NewContext(ctx)
+    cancels = append(cancels, cancel)
+
+    return ctx, func() {
+        for i := len(cancels) - 1; i >= 0; i-- {
+            cancels[i]()
+        }
+    }, nil
+}
+
+// chromeRender 影响渲染结果的选项，参与 ChromeGet 缓存名称计算
+type chromeRender struct {
+    Wait        string        `json:"wait,omitempty"`
+    Selector    string        `json:"selector,omitempty"`
+    Sleep       time.Duration `json:"sleep,omitempty"`
+    Dismiss     []string      `json:"dismiss,omitempty"`
+    DismissWait time.Duration `json:"dismiss_wait,omitempty"`
+    // Profile 登录状态等影响页面内容
+    Profile string `json:"profile,omitempty"`
+}
+
+// chromeCacheName ChromeGet 缓存名称，未设置渲染选项时与 GET 请求相同
+func chromeCacheName(url string, opts *options) string {
+    render := chromeRender{Wait: opts.chromeWait, Selector: opts.chromeSelector, Sleep: opts.chromeSleep, Profile: chromeProfileName(opts)}
+    if c := conf(); len(c.chromeDismiss) > 0 {
+        render.Dismiss, render.DismissWait = c.chromeDismiss, c.chromeDismissWait
+    }
+    if render.Wait == "" && render.Selector == "" && render.Sleep == 0 && len(render.Dismiss) == 0 && render.Profile == "" {
+        return cacheName(http.MethodGet, url)
+    }
+    return cacheName(http.MethodGet, url, render)
+}
+
+// chromeActions 按渲染选项导航并提取内容
+func chromeActions(url string, opts *options, body, location *string) []chromedp.Action {
+    actions := []chromedp.Action{chromedp.Navigate(url)}
+    if opts.chromeWait != "" {
+        actions = append(actions, chromedp.WaitVisible(opts.chromeWait))
+    }
+    if opts.chromeSleep > 0 {
+        actions = append(actions, chromedp.Sleep(opts.chromeSleep))
+    }
+
+    selector := "body"
+    if opts.chromeSelector != "" {
+        selector = opts.chromeSelector
+    }
+    return append(actions,
+        dismissOverlays(),
+        chromedp.OuterHTML(selector, body, chromedp.NodeVisible),
+        chromedp.Location(location),
+    )
+}
+
+// dismissScript 点击每个选择器匹配的第一个可见元素，返回点击数量
+const dismissScript = `(function(selectors) {
+    var clicked = 0;
+    for (var i = 0; i < selectors.length; i++) {
+        var elements;
+        try { elements = document.querySelectorAll(selectors[i]); } catch (e) { continue; }
+        for (var j = 0; j < elements.length; j++) {
+            var rect = elements[j].getBoundingClientRect();
+            if (rect.width > 0 && rect.height > 0) {
+                elements[j].click();
+                clicked++;
+                break;
+            }
+        }
+    }
+    return clicked;
+})(%s)`
+
+// dismissOverlays 点击遮罩按钮，有点击时等待页面更新
+func dismissOverlays() chromedp.Action {
+    return chromedp.ActionFunc(func(ctx context.Context) error {
+        c := conf()
+        if len(c.chromeDismiss) == 0 {
+            return nil
+        }
+
+        selectors, err := jsoniter.MarshalToString(c.chromeDismiss)
+        if err != nil {
+            return errors.WithStack(err)
+        }
+
+        var clicked int
+        if err = chromedp.Evaluate(fmt.Sprintf(dismissScript, selectors), &clicked).Do(ctx); err != nil {
+            return errors.WithStack(err)
+        }
+        if clicked > 0 && c.chromeDismissWait > 0 {
+            return chromedp.Sleep(c.chromeDismissWait).Do(ctx)
+        }
+        return nil
+    })
+}
@@ -0,0 +1,101 @@
+package req
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/chromedp/cdproto/browser"
+	"github.com/chromedp/chromedp"
+	"github.com/pkg/errors"
+)
+
+// ChromeDownload 使用 Chrome 下载文件，clickSelector 为空时直接访问链接触发下载，否则打开页面后点击选择器匹配的元素
+// 等待下载完成后按建议文件名保存到 destDir 并返回文件路径，同名文件会被覆盖；v 支持 WithChromeProfile 等选项
+func ChromeDownload(ctx context.Context, url, clickSelector, destDir string, v ...interface{}) (string, error) {
+    if err := ValidateURL(url); err != nil {
+        return "", err
+    }
+    opts, _ := splitOptions(v)
+    if opts.err != nil {
+        return "", opts.err
+    }
+    done, err := track()
+    if err != nil {
+        return "", err
+    }
+    defer done()
+
+    if destDir, err = filepath.Abs(destDir); err != nil {
+        return "", errors.WithStack(err)
+    }
+    if err = os.MkdirAll(destDir, 0755); err != nil {
+        return "", errors.WithStack(err)
+    }
+
+    ctx, cancel, err := chromeContext(ctx, url, opts)
+    if err != nil {
+        return "", err
+    }
+    defer cancel()
+
+    var (
+        mutex     sync.Mutex
+        filenames = map[string]string{}
+        finished  = make(chan *browser.EventDownloadProgress, 1)
+    )
+    chromedp.ListenTarget(ctx, func(ev interface{}) {
+        switch e := ev.(type) {
+        case *browser.EventDownloadWillBegin:
+            mutex.Lock()
+            filenames[e.GUID] = e.SuggestedFilename
+            mutex.Unlock()
+        case *browser.EventDownloadProgress:
+            if e.State == browser.DownloadProgressStateCompleted || e.State == browser.DownloadProgressStateCanceled {
+                select {
+                case finished <- e:
+                default:
+                }
+            }
+        }
+    })
+
+    // 下载文件先以 GUID 命名保存，完成后重命名
+    actions := []chromedp.Action{
+        browser.SetDownloadBehavior(browser.SetDownloadBehaviorBehaviorAllowAndName).WithDownloadPath(destDir).WithEventsEnabled(true),
+        chromedp.Navigate(url),
+    }
+    if clickSelector != "" {
+        actions = append(actions, chromedp.WaitVisible(clickSelector), chromedp.Click(clickSelector))
+    }
+    // 直接访问下载链接时导航被中止，不视为错误
+    if err = chromedp.Run(ctx, actions...); err != nil && !strings.Contains(err.Error(), "net::ERR_ABORTED") {
+        return "", errors.WithStack(err)
+    }
+
+    var progress *browser.EventDownloadProgress
+    select {
+    case <-ctx.Done():
+        return "", errors.WithStack(ctx.Err())
+    case progress = <-finished:
+    }
+    if progress.State == browser.DownloadProgressStateCanceled {
+        return "", errors.Errorf("chrome download canceled: %s", url)
+    }
+
+    mutex.Lock()
+    name := filepath.Base(filenames[progress.GUID])
+    mutex.Unlock()
+    saved := filepath.Join(destDir, progress.GUID)
+    if name == "" || name == "." || name == string(filepath.Separator) {
+        return saved, nil
+    }
+
+    path := filepath.Join(destDir, name)
+    if err = os.Rename(saved, path); err != nil {
+        return "", errors.WithStack(err)
+    }
+    return path, nil
+}
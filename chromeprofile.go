@@ -0,0 +1,136 @@
+package req
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// chromeProfileLocks 按目录串行使用同一配置，Chrome 不允许多个实例同时打开同一用户数据目录
+var chromeProfileLocks sync.Map
+
+// SetChromeProfileDir 设置 Chrome 配置的存放目录，为空时使用用户缓存目录下的 req/chrome-profiles
+func SetChromeProfileDir(dir string) {
+    updateConfig(func(c *config) { c.chromeProfileDir = dir })
+}
+
+// CreateChromeProfile 创建 Chrome 配置，已存在时不做修改
+func CreateChromeProfile(name string) error {
+    dir, err := chromeProfilePath(name)
+    if err != nil {
+        return err
+    }
+    return errors.WithStack(os.MkdirAll(dir, 0700))
+}
+
+// UseChromeProfile 设置 ChromeGet 默认使用的 Chrome 配置，Cookie、localStorage 与登录状态在多次访问间保留
+// 配置不存在时创建，为空时每次使用临时配置
+func UseChromeProfile(name string) error {
+    if name != "" {
+        if err := CreateChromeProfile(name); err != nil {
+            return err
+        }
+    }
+    updateConfig(func(c *config) { c.chromeProfile = name })
+    return nil
+}
+
+// WithChromeProfile 本次 ChromeGet 使用的 Chrome 配置，覆盖 UseChromeProfile，配置不存在时创建
+func WithChromeProfile(name string) Option {
+    return func(o *options) {
+        o.chromeProfile = name
+    }
+}
+
+// ChromeProfiles 已创建的 Chrome 配置名称，按字母排序
+func ChromeProfiles() ([]string, error) {
+    root, err := chromeProfileRoot()
+    if err != nil {
+        return nil, err
+    }
+    entries, err := os.ReadDir(root)
+    if os.IsNotExist(err) {
+        return nil, nil
+    } else if err != nil {
+        return nil, errors.WithStack(err)
+    }
+
+    var names []string
+    for _, entry := range entries {
+        if entry.IsDir() {
+            names = append(names, entry.Name())
+        }
+    }
+    sort.Strings(names)
+    return names, nil
+}
+
+// WipeChromeProfile 删除 Chrome 配置及其全部数据，正在使用时等待使用结束
+func WipeChromeProfile(name string) error {
+    dir, err := chromeProfilePath(name)
+    if err != nil {
+        return err
+    }
+    unlock := lockChromeProfile(dir)
+    defer unlock()
+    return errors.WithStack(os.RemoveAll(dir))
+}
+
+// chromeProfileRoot Chrome 配置的存放目录
+func chromeProfileRoot() (string, error) {
+    if dir := conf().chromeProfileDir; dir != "" {
+        return dir, nil
+    }
+    dir, err := os.UserCacheDir()
+    if err != nil {
+        return "", errors.WithStack(err)
+    }
+    return filepath.Join(dir, "req", "chrome-profiles"), nil
+}
+
+// chromeProfilePath 配置名称对应的用户数据目录
+func chromeProfilePath(name string) (string, error) {
+    if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+        return "", errors.Errorf("invalid chrome profile name: %q", name)
+    }
+    root, err := chromeProfileRoot()
+    if err != nil {
+        return "", err
+    }
+    return filepath.Join(root, name), nil
+}
+
+// chromeProfileName 本次访问使用的配置名称，未设置时为空
+func chromeProfileName(opts *options) string {
+    if opts.chromeProfile != "" {
+        return opts.chromeProfile
+    }
+    return conf().chromeProfile
+}
+
+// openChromeProfile 创建并锁定本次访问使用的配置，未设置配置时 dir 为空
+func openChromeProfile(opts *options) (dir string, unlock func(), err error) {
+    name := chromeProfileName(opts)
+    if name == "" {
+        return "", func() {}, nil
+    }
+    if dir, err = chromeProfilePath(name); err != nil {
+        return "", nil, err
+    }
+    if err = os.MkdirAll(dir, 0700); err != nil {
+        return "", nil, errors.WithStack(err)
+    }
+    return dir, lockChromeProfile(dir), nil
+}
+
+// lockChromeProfile 锁定用户数据目录，返回解锁函数
+func lockChromeProfile(dir string) func() {
+    value, _ := chromeProfileLocks.LoadOrStore(dir, &sync.Mutex{})
+    mutex := value.(*sync.Mutex)
+    mutex.Lock()
+    return mutex.Unlock
+}
@@ -0,0 +1,114 @@
+package req
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock 时钟，重试等待、轮询间隔、重连退避与调度老化均通过时钟计算
+type Clock interface {
+    Now() time.Time
+    Sleep(d time.Duration)
+    After(d time.Duration) <-chan time.Time
+}
+
+// SetClock 设置时钟，为 nil 时恢复系统时钟，测试中可传入 FakeClock 避免真实等待
+func SetClock(clock Clock) {
+    if clock == nil {
+        clock = realClock{}
+    }
+    updateConfig(func(c *config) { c.clock = clock })
+}
+
+// realClock 系统时钟
+type realClock struct{}
+
+// Now 当前时间
+func (realClock) Now() time.Time {
+    return time.Now()
+}
+
+// Sleep 暂停
+func (realClock) Sleep(d time.Duration) {
+    time.Sleep(d)
+}
+
+// After 等待 d 后返回当前时间
+func (realClock) After(d time.Duration) <-chan time.Time {
+    return time.After(d)
+}
+
+// FakeClock 手动推进的时钟，用于测试
+type FakeClock struct {
+    mutex   sync.Mutex
+    now     time.Time
+    waiters []fakeWaiter
+}
+
+// fakeWaiter 等待到期的调用
+type fakeWaiter struct {
+    at time.Time
+    ch chan time.Time
+}
+
+// NewFakeClock 创建从 now 开始的时钟
+func NewFakeClock(now time.Time) *FakeClock {
+    return &FakeClock{now: now}
+}
+
+// Now 当前时间
+func (c *FakeClock) Now() time.Time {
+    c.mutex.Lock()
+    defer c.mutex.Unlock()
+    return c.now
+}
+
+// Sleep 暂停，直到时钟被推进 d
+func (c *FakeClock) Sleep(d time.Duration) {
+    <-c.After(d)
+}
+
+// After 时钟被推进 d 后返回当前时间
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+    c.mutex.Lock()
+    defer c.mutex.Unlock()
+
+    ch := make(chan time.Time, 1)
+    if d <= 0 {
+        ch <- c.now
+        return ch
+    }
+    c.waiters = append(c.waiters, fakeWaiter{at: c.now.Add(d), ch: ch})
+    return ch
+}
+
+// Advance 推进时钟，唤醒到期的等待
+func (c *FakeClock) Advance(d time.Duration) {
+    c.mutex.Lock()
+    defer c.mutex.Unlock()
+
+    c.now = c.now.Add(d)
+    waiters := c.waiters[:0]
+    for _, w := range c.waiters {
+        if w.at.After(c.now) {
+            waiters = append(waiters, w)
+            continue
+        }
+        w.ch <- c.now
+    }
+    c.waiters = waiters
+}
+
+// Waiters 正在等待的调用数量
+func (c *FakeClock) Waiters() int {
+    c.mutex.Lock()
+    defer c.mutex.Unlock()
+    return len(c.waiters)
+}
+
+// BlockUntil 阻塞直到至少有 n 个等待的调用，用于在推进时钟前确认被测代码已进入等待
+func (c *FakeClock) BlockUntil(n int) {
+    for c.Waiters() < n {
+        time.Sleep(time.Millisecond)
+    }
+}
@@ -0,0 +1,69 @@
+package req
+
+import (
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// withFakeClock 临时使用手动推进的时钟，测试结束后恢复系统时钟
+func withFakeClock(t *testing.T) *FakeClock {
+    clock := NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+    SetClock(clock)
+    t.Cleanup(func() { SetClock(nil) })
+    return clock
+}
+
+func TestRetryWithFakeClock(t *testing.T) {
+    clock := withFakeClock(t)
+    withRetry(t, 2, time.Hour)
+    server := NewMockServer()
+    defer server.Close()
+    route := server.Route(http.MethodGet, "/flaky").Status(503, 503, 200).Body("ok")
+    defer server.Install()()
+
+    result := make(chan error, 1)
+    go func() {
+        _, err := Get("http://api.example.com/flaky")
+        result <- err
+    }()
+
+    // 每次失败后等待一小时，推进时钟后立即重试
+    for i := 1; i <= 2; i++ {
+        clock.BlockUntil(1)
+        if calls := route.Calls(); calls != i {
+            t.Fatalf("calls before retry %d = %d", i, calls)
+        }
+        clock.Advance(time.Hour)
+    }
+
+    select {
+    case err := <-result:
+        if err != nil {
+            t.Fatal(err)
+        }
+    case <-time.After(time.Second * 5):
+        t.Fatal("retry did not resume after advancing the clock")
+    }
+    if calls := route.Calls(); calls != 3 {
+        t.Fatalf("calls = %d, want 3", calls)
+    }
+}
+
+func TestAddressFailTTLWithFakeClock(t *testing.T) {
+    clock := withFakeClock(t)
+    host := "ttl.example.com"
+    ips := []net.IP{net.ParseIP("192.0.2.1"), net.ParseIP("192.0.2.2")}
+
+    healthyFirst(host, ips)
+    markFailed(host, "192.0.2.1:443")
+    if got := healthyFirst(host, ips); !got[0].Equal(ips[1]) {
+        t.Fatalf("failed address not moved last: %v", got)
+    }
+
+    clock.Advance(addressFailTTL)
+    if got := healthyFirst(host, ips); !got[0].Equal(ips[0]) {
+        t.Fatalf("failed address not restored after ttl: %v", got)
+    }
+}
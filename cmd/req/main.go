@@ -0,0 +1,313 @@
+// req 命令行工具，提供 get/post/download/batch/chrome/check 子命令
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	imroc "github.com/imroc/req"
+	"github.com/itnxs/req"
+	jsoniter "github.com/json-iterator/go"
+)
+
+const usage = `usage: req <command> [flags] [args]
+
+commands:
+  get <url>                 GET 请求并输出内容
+  post <url> [body]         POST 请求，body 为 - 时从标准输入读取
+  download <url> <file>     下载文件
+  batch [file]              批量 GET，每行一个链接，未指定文件时从标准输入读取
+  chrome <url>              使用 Chrome 渲染页面
+  check <url>               检查链接是否可访问
+
+运行 req <command> -h 查看命令参数
+`
+
+// options 公共参数
+type options struct {
+    config  string
+    cache   string
+    limit   int
+    proxy   string
+    timeout time.Duration
+    retry   int
+    output  string
+    headers headers
+}
+
+// headers 可重复的 -H 参数
+type headers []string
+
+// String 参数值
+func (h *headers) String() string {
+    return strings.Join(*h, ", ")
+}
+
+// Set 添加请求头
+func (h *headers) Set(value string) error {
+    if !strings.Contains(value, ":") {
+        return fmt.Errorf("invalid header %q, want \"Key: Value\"", value)
+    }
+    *h = append(*h, value)
+    return nil
+}
+
+// header 转换为请求头
+func (h headers) header() imroc.Header {
+    header := imroc.Header{}
+    for _, value := range h {
+        kv := strings.SplitN(value, ":", 2)
+        header[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+    }
+    return header
+}
+
+// newFlags 创建带公共参数的子命令参数集
+func newFlags(name string) (*flag.FlagSet, *options) {
+    opts := &options{}
+    flags := flag.NewFlagSet(name, flag.ExitOnError)
+    flags.StringVar(&opts.config, "config", "", "配置文件，支持 JSON/YAML")
+    flags.StringVar(&opts.cache, "cache", "", "缓存目录")
+    flags.IntVar(&opts.limit, "limit", 0, "并发数量")
+    flags.StringVar(&opts.proxy, "proxy", "", "代理地址")
+    flags.DurationVar(&opts.timeout, "timeout", 0, "超时时间")
+    flags.IntVar(&opts.retry, "retry", -1, "重试次数")
+    flags.StringVar(&opts.output, "o", "text", "输出格式: text/json")
+    flags.Var(&opts.headers, "H", "请求头 \"Key: Value\"，可重复")
+    return flags, opts
+}
+
+// apply 应用公共参数，优先级为命令行参数、配置文件、环境变量
+func (o *options) apply() error {
+    if err := req.ConfigFromEnv(); err != nil {
+        return err
+    }
+    if o.config != "" {
+        if err := req.LoadConfig(o.config); err != nil {
+            return err
+        }
+    }
+
+    c := req.Config{Proxy: o.proxy, Limit: o.limit, CachePath: o.cache}
+    if o.timeout > 0 {
+        c.Timeout = o.timeout.String()
+    }
+    if o.retry >= 0 {
+        c.RetryCount = &o.retry
+    }
+    if o.output != "text" && o.output != "json" {
+        return fmt.Errorf("unknown output format: %s", o.output)
+    }
+    return c.Apply()
+}
+
+func main() {
+    if len(os.Args) < 2 {
+        fmt.Fprint(os.Stderr, usage)
+        os.Exit(2)
+    }
+
+    commands := map[string]func(args []string) error{
+        "get":      get,
+        "post":     post,
+        "download": download,
+        "batch":    batch,
+        "chrome":   chrome,
+        "check":    check,
+    }
+    command, ok := commands[os.Args[1]]
+    if !ok {
+        fmt.Fprint(os.Stderr, usage)
+        os.Exit(2)
+    }
+
+    if err := command(os.Args[2:]); err != nil {
+        fmt.Fprintln(os.Stderr, "req:", err)
+        os.Exit(1)
+    }
+}
+
+// parse 解析子命令参数，校验位置参数数量
+func parse(name string, args []string, min, max int) ([]string, *options, error) {
+    flags, opts := newFlags(name)
+    if err := flags.Parse(args); err != nil {
+        return nil, nil, err
+    }
+    if n := flags.NArg(); n < min || n > max {
+        flags.Usage()
+        os.Exit(2)
+    }
+    return flags.Args(), opts, opts.apply()
+}
+
+// result 单个链接的输出结果
+type result struct {
+    URL   string `json:"url"`
+    Body  string `json:"body,omitempty"`
+    OK    *bool  `json:"ok,omitempty"`
+    File  string `json:"file,omitempty"`
+    Error string `json:"error,omitempty"`
+}
+
+// print 按输出格式输出结果
+func (o *options) print(r result) {
+    if o.output == "json" {
+        line, _ := jsoniter.MarshalToString(r)
+        fmt.Println(line)
+        return
+    }
+
+    switch {
+    case r.Error != "":
+        fmt.Fprintf(os.Stderr, "%s\t%s\n", r.URL, r.Error)
+    case r.OK != nil:
+        fmt.Printf("%s\t%t\n", r.URL, *r.OK)
+    case r.File != "":
+        fmt.Printf("%s\t%s\n", r.URL, r.File)
+    default:
+        fmt.Print(r.Body)
+    }
+}
+
+func get(args []string) error {
+    args, opts, err := parse("get", args, 1, 1)
+    if err != nil {
+        return err
+    }
+
+    body, err := req.Get(args[0], opts.headers.header())
+    if err != nil {
+        return err
+    }
+    opts.print(result{URL: args[0], Body: body})
+    return nil
+}
+
+func post(args []string) error {
+    args, opts, err := parse("post", args, 1, 2)
+    if err != nil {
+        return err
+    }
+
+    v := []interface{}{opts.headers.header()}
+    if len(args) == 2 {
+        body := []byte(args[1])
+        if args[1] == "-" {
+            if body, err = io.ReadAll(os.Stdin); err != nil {
+                return err
+            }
+        }
+        v = append(v, body)
+    }
+
+    body, err := req.Post(args[0], v...)
+    if err != nil {
+        return err
+    }
+    opts.print(result{URL: args[0], Body: body})
+    return nil
+}
+
+func download(args []string) error {
+    args, opts, err := parse("download", args, 2, 2)
+    if err != nil {
+        return err
+    }
+
+    if err = req.Download(args[0], args[1]); err != nil {
+        return err
+    }
+    opts.print(result{URL: args[0], File: args[1]})
+    return nil
+}
+
+func batch(args []string) error {
+    args, opts, err := parse("batch", args, 0, 1)
+    if err != nil {
+        return err
+    }
+
+    input := io.Reader(os.Stdin)
+    if len(args) == 1 {
+        file, err := os.Open(args[0])
+        if err != nil {
+            return err
+        }
+        defer file.Close()
+        input = file
+    }
+
+    var urls []string
+    scanner := bufio.NewScanner(input)
+    for scanner.Scan() {
+        url := strings.TrimSpace(scanner.Text())
+        if url != "" && !strings.HasPrefix(url, "#") {
+            urls = append(urls, url)
+        }
+    }
+    if err = scanner.Err(); err != nil {
+        return err
+    }
+
+    // 并发数量由 -limit、配置文件或 REQ_LIMIT 设置，按库的调度器执行
+    failed := false
+    for _, entry := range req.Batch(context.Background(), urls, opts.headers.header()).Entries {
+        if entry.Err != nil {
+            failed = true
+            opts.print(result{URL: entry.URL, Error: entry.Err.Error()})
+        } else if opts.output == "text" {
+            // 文本格式下批量结果只输出链接与长度，内容使用 -o json
+            fmt.Printf("%s\t%d\n", entry.URL, len(entry.Body))
+        } else {
+            opts.print(result{URL: entry.URL, Body: entry.Body})
+        }
+    }
+    if failed {
+        return fmt.Errorf("some requests failed")
+    }
+    return nil
+}
+
+func chrome(args []string) error {
+    args, opts, err := parse("chrome", args, 1, 1)
+    if err != nil {
+        return err
+    }
+
+    ctx := context.Background()
+    if opts.timeout > 0 {
+        var cancel context.CancelFunc
+        ctx, cancel = context.WithTimeout(ctx, opts.timeout)
+        defer cancel()
+    }
+
+    body, err := req.ChromeGet(ctx, args[0])
+    if err != nil {
+        return err
+    }
+    opts.print(result{URL: args[0], Body: body})
+    return nil
+}
+
+func check(args []string) error {
+    args, opts, err := parse("check", args, 1, 1)
+    if err != nil {
+        return err
+    }
+
+    ok, err := req.Check(args[0])
+    if err != nil {
+        return err
+    }
+    opts.print(result{URL: args[0], OK: &ok})
+    if !ok {
+        os.Exit(1)
+    }
+    return nil
+}
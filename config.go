@@ -0,0 +1,343 @@
+package req
+
+import (
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	neturl "net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/imroc/req"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// config 全局配置，修改时复制后整体替换，请求开始时读取快照，避免与并发请求产生数据竞争
+type config struct {
+    // limit 并发数量
+    limit int
+    // timeout 超时时间
+    timeout time.Duration
+    // cachePath 文件缓存路径
+    cachePath string
+    // retryCount 重试次数
+    retryCount int
+    // retrySleepTime 重试暂停时长
+    retrySleepTime time.Duration
+    // retryStatus 状态码是否需要重试
+    retryStatus func(code int) bool
+    // onRetry 重试回调
+    onRetry func(attempt int, info RequestInfo, err error, wait time.Duration)
+    // proxy 代理地址，为 nil 时按 proxyFromEnv 决定是否使用环境变量
+    proxy *neturl.URL
+    // proxyFromEnv 是否使用 HTTP_PROXY/HTTPS_PROXY/NO_PROXY 环境变量
+    proxyFromEnv bool
+    // headers 全部请求附加的请求头，修改时复制
+    headers req.Header
+
+    // adaptiveMin 自适应并发下限，为 0 时使用固定并发数量
+    adaptiveMin int
+    // adaptiveMax 自适应并发上限
+    adaptiveMax int
+    // hostLimit 单主机并发数量，为 0 时不限制
+    hostLimit int
+    // hostLimits 指定主机的并发数量，修改时复制
+    hostLimits map[string]int
+    // agingInterval 等待时长每增加该值，优先级提升 1，防止低优先级条目饿死
+    agingInterval time.Duration
+    // autoThrottle 自动限流冷却时长，为 0 时关闭
+    autoThrottle time.Duration
+    // ipPreference IP 协议族偏好
+    ipPreference IPPreference
+    // fallbackDelay 双栈连接回退延迟
+    fallbackDelay time.Duration
+    // hostIPs 指定主机连接的 IP，修改时复制
+    hostIPs map[string][]net.IP
+
+    // autoIdempotencyKey 是否为 POST/PATCH 自动生成幂等键
+    autoIdempotencyKey bool
+    // requestIDHeader 请求 ID 请求头，为空时不生成
+    requestIDHeader string
+    // jobPath 任务存储目录
+    jobPath string
+    // expectContinueTimeout 等待 100 Continue 的时长，为 0 时使用默认值
+    expectContinueTimeout time.Duration
+    // tlsFingerprint TLS 握手指纹
+    tlsFingerprint TLSFingerprint
+    // userAgent 固定 User-Agent
+    userAgent UserAgent
+    // userAgentPool 轮换 User-Agent 池，修改时复制
+    userAgentPool []UserAgent
+    // clock 时钟
+    clock Clock
+    // validatorStore 条件请求校验值存储
+    validatorStore ValidatorStore
+    // harRecorder HAR 记录器，为 nil 时不记录
+    harRecorder *harRecord
+    // dumper 原始内容输出配置，为 nil 时不输出
+    dumper *dumpConfig
+    // guard 请求目标限制，为 nil 时不限制
+    guard *hostGuard
+    // serverAuth 服务端 NTLM/Negotiate 认证
+    serverAuth Authenticator
+    // proxyAuth 代理 NTLM/Negotiate 认证
+    proxyAuth Authenticator
+    // logins 按主机记录的表单登录
+    logins map[string]*formLogin
+    // quotaPacing 是否按配额响应头控制请求节奏
+    quotaPacing bool
+    // slowLog 慢请求回调，为 nil 时关闭
+    slowLog *slowLog
+    // addressFailover 连接失败时是否切换地址
+    addressFailover bool
+    // localAddrs 轮流绑定的本地地址
+    localAddrs []net.IP
+    // allowedSchemes http/https 之外允许的协议，修改时复制
+    allowedSchemes []string
+    // maxURLLength 链接最大长度，为 0 时不限制
+    maxURLLength int
+    // chromeDismiss ChromeGet 点击的遮罩按钮选择器，修改时复制
+    chromeDismiss []string
+    // chromeDismissWait 点击遮罩按钮后的等待时长
+    chromeDismissWait time.Duration
+    // chromeProfileDir Chrome 配置的存放目录
+    chromeProfileDir string
+    // chromeProfile ChromeGet 默认使用的 Chrome 配置
+    chromeProfile string
+    // blockPolicy 拦截页处理策略，为 nil 时不识别
+    blockPolicy *BlockPolicy
+    // bandwidthQuota 总流量配额，为 0 时不限制
+    bandwidthQuota int64
+    // hostQuotas 主机流量配额，修改时复制
+    hostQuotas map[string]int64
+    // diskSpaceMargin 下载前检查剩余空间时额外保留的字节数，为负数时不检查
+    diskSpaceMargin int64
+    // cacheBypass 不缓存的链接规则，修改时复制
+    cacheBypass []*regexp.Regexp
+    // cacheTTLs 缓存有效期规则，修改时复制
+    cacheTTLs []cacheTTL
+    // cacheDedup 是否按内容去重缓存
+    cacheDedup bool
+    // mockAddr 模拟服务地址，不为空时全部连接发送到模拟服务
+    mockAddr string
+    // jar 请求客户端共用的 Cookie
+    jar http.CookieJar
+    // client 按当前配置创建的请求客户端，只读
+    client *http.Client
+    // clients 绑定本地地址等请求设置对应的客户端
+    clients *clientSet
+}
+
+var (
+    // configMutex 串行化配置修改
+    configMutex sync.Mutex
+    // currentConfig 当前配置
+    currentConfig = newConfigValue()
+)
+
+// newConfigValue 默认配置
+func newConfigValue() *atomic.Value {
+    jar, _ := cookiejar.New(nil)
+    value := &atomic.Value{}
+    value.Store(&config{
+        limit:              10,
+        timeout:            time.Second * 10,
+        retryCount:         3,
+        retrySleepTime:     time.Millisecond * 200,
+        retryStatus:        defaultRetryStatus,
+        proxyFromEnv:       true,
+        hostLimits:         map[string]int{},
+        agingInterval:      time.Second * 10,
+        autoIdempotencyKey: true,
+        requestIDHeader:    "X-Request-ID",
+        jobPath:            "jobs",
+        tlsFingerprint:     TLSFingerprintGo,
+        clock:              realClock{},
+        validatorStore:     NewMemoryValidatorStore(),
+        maxURLLength:       8192,
+        chromeDismissWait:  time.Millisecond * 500,
+        diskSpaceMargin:    64 << 20,
+        jar:                jar,
+    })
+    return value
+}
+
+// conf 当前配置快照，只读
+func conf() *config {
+    return currentConfig.Load().(*config)
+}
+
+// updateConfig 复制当前配置，修改后替换
+func updateConfig(fn func(c *config)) {
+    configMutex.Lock()
+    defer configMutex.Unlock()
+
+    c := *conf()
+    fn(&c)
+    currentConfig.Store(&c)
+}
+
+// Config 可从环境变量或配置文件加载的配置，零值字段不修改当前配置
+type Config struct {
+    // Timeout 超时时间，如 10s
+    Timeout string `json:"timeout" yaml:"timeout"`
+    // Proxy 代理地址
+    Proxy string `json:"proxy" yaml:"proxy"`
+    // Limit 并发数量
+    Limit int `json:"limit" yaml:"limit"`
+    // CachePath 缓存目录
+    CachePath string `json:"cache_path" yaml:"cache_path"`
+    // RetryCount 重试次数，为 0 时不重试
+    RetryCount *int `json:"retry_count" yaml:"retry_count"`
+    // RetrySleepTime 重试暂停时长，如 200ms
+    RetrySleepTime string `json:"retry_sleep_time" yaml:"retry_sleep_time"`
+    // UserAgent User-Agent
+    UserAgent string `json:"user_agent" yaml:"user_agent"`
+    // Headers 全部请求附加的请求头，调用方设置的请求头优先
+    Headers map[string]string `json:"headers" yaml:"headers"`
+}
+
+// 环境变量名称
+const (
+    envTimeout        = "REQ_TIMEOUT"
+    envProxy          = "REQ_PROXY"
+    envLimit          = "REQ_LIMIT"
+    envCachePath      = "REQ_CACHE_PATH"
+    envRetryCount     = "REQ_RETRY_COUNT"
+    envRetrySleepTime = "REQ_RETRY_SLEEP_TIME"
+    envUserAgent      = "REQ_USER_AGENT"
+    // envHeaderPrefix 请求头前缀，如 REQ_HEADER_X_TENANT_ID 对应 X-Tenant-Id
+    envHeaderPrefix = "REQ_HEADER_"
+)
+
+// ConfigFromEnv 从 REQ_ 前缀的环境变量读取并应用配置
+func ConfigFromEnv() error {
+    c := Config{
+        Timeout:        os.Getenv(envTimeout),
+        Proxy:          os.Getenv(envProxy),
+        CachePath:      os.Getenv(envCachePath),
+        RetrySleepTime: os.Getenv(envRetrySleepTime),
+        UserAgent:      os.Getenv(envUserAgent),
+    }
+
+    var err error
+    if value := os.Getenv(envLimit); value != "" {
+        if c.Limit, err = strconv.Atoi(value); err != nil {
+            return errors.Wrapf(err, "config: %s", envLimit)
+        }
+    }
+    if value := os.Getenv(envRetryCount); value != "" {
+        count, err := strconv.Atoi(value)
+        if err != nil {
+            return errors.Wrapf(err, "config: %s", envRetryCount)
+        }
+        c.RetryCount = &count
+    }
+
+    for _, env := range os.Environ() {
+        kv := strings.SplitN(env, "=", 2)
+        if len(kv) == 2 && strings.HasPrefix(kv[0], envHeaderPrefix) && len(kv[0]) > len(envHeaderPrefix) {
+            if c.Headers == nil {
+                c.Headers = map[string]string{}
+            }
+            name := strings.ReplaceAll(strings.TrimPrefix(kv[0], envHeaderPrefix), "_", "-")
+            c.Headers[http.CanonicalHeaderKey(name)] = kv[1]
+        }
+    }
+
+    return c.Apply()
+}
+
+// LoadConfig 读取并应用配置文件，扩展名为 .yaml/.yml 时按 YAML 解析，否则按 JSON 解析
+func LoadConfig(path string) error {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return errors.WithStack(err)
+    }
+
+    var c Config
+    switch strings.ToLower(filepath.Ext(path)) {
+    case ".yaml", ".yml":
+        err = yaml.Unmarshal(data, &c)
+    default:
+        err = jsoniter.Unmarshal(data, &c)
+    }
+    if err != nil {
+        return errors.Wrapf(err, "config: %s", path)
+    }
+
+    return c.Apply()
+}
+
+// Apply 校验并应用配置，校验失败时不修改任何配置
+func (c Config) Apply() error {
+    var (
+        timeout, sleep time.Duration
+        proxy          *neturl.URL
+        err            error
+    )
+    if c.Timeout != "" {
+        if timeout, err = time.ParseDuration(c.Timeout); err != nil {
+            return errors.Wrapf(err, "config: timeout")
+        }
+    }
+    if c.RetrySleepTime != "" {
+        if sleep, err = time.ParseDuration(c.RetrySleepTime); err != nil {
+            return errors.Wrapf(err, "config: retry_sleep_time")
+        }
+    }
+    if c.Proxy != "" {
+        if proxy, err = parseProxy(c.Proxy); err != nil {
+            return err
+        }
+    }
+    if c.CachePath != "" {
+        if err = SetCachePathE(c.CachePath); err != nil {
+            return err
+        }
+    }
+
+    updateConfig(func(conf *config) {
+        if c.Limit > 0 {
+            conf.limit = c.Limit
+        }
+        if c.RetryCount != nil {
+            conf.retryCount = *c.RetryCount
+        }
+        if sleep > 0 {
+            conf.retrySleepTime = sleep
+        }
+        if c.UserAgent != "" {
+            conf.userAgent = UserAgent{Value: c.UserAgent}
+        }
+        if len(c.Headers) > 0 {
+            headers := make(req.Header, len(conf.headers)+len(c.Headers))
+            for k, v := range conf.headers {
+                headers[k] = v
+            }
+            for k, v := range c.Headers {
+                headers[k] = v
+            }
+            conf.headers = headers
+        }
+        if proxy != nil {
+            conf.proxy = proxy
+        }
+    })
+
+    if timeout > 0 {
+        SetTimeout(timeout)
+    }
+    if proxy != nil {
+        resetClient()
+    }
+    return nil
+}
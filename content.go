@@ -0,0 +1,158 @@
+package req
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// sniffSize MIME 识别读取的响应体长度
+const sniffSize = 512
+
+// WithMaxBodySize 响应体超过 size 字节时返回 BodyTooLargeError，不再继续读取
+func WithMaxBodySize(size int64) Option {
+    return func(o *options) {
+        o.maxBodySize = size
+    }
+}
+
+// WithTextOnly 响应内容为二进制时返回 BinaryContentError，内容类型由 Content-Type 与 MIME 识别共同确定
+func WithTextOnly() Option {
+    return func(o *options) {
+        o.textOnly = true
+    }
+}
+
+// WithDownloadFallback 响应内容为二进制或超过 WithMaxBodySize 时改为流式写入文件，并返回 DownloadedError
+func WithDownloadFallback(fileName string) Option {
+    return func(o *options) {
+        o.downloadFallback = fileName
+    }
+}
+
+// BinaryContentError 响应内容为二进制
+type BinaryContentError struct {
+    ContentType string
+}
+
+// Error 错误信息
+func (e *BinaryContentError) Error() string {
+    return fmt.Sprintf("binary content: %s", e.ContentType)
+}
+
+// BodyTooLargeError 响应体超过限制
+type BodyTooLargeError struct {
+    Limit int64
+    // Size 响应体长度，未知时为 -1
+    Size int64
+}
+
+// Error 错误信息
+func (e *BodyTooLargeError) Error() string {
+    if e.Size >= 0 {
+        return fmt.Sprintf("body too large: %d > %d", e.Size, e.Limit)
+    }
+    return fmt.Sprintf("body too large: exceeds %d", e.Limit)
+}
+
+// DownloadedError 响应内容已写入文件，未返回内容
+type DownloadedError struct {
+    FileName    string
+    ContentType string
+    Size        int64
+}
+
+// Error 错误信息
+func (e *DownloadedError) Error() string {
+    return fmt.Sprintf("content (%s, %d bytes) downloaded to %s", e.ContentType, e.Size, e.FileName)
+}
+
+// GetBytes GET请求内容，返回原始字节
+func GetBytes(url string, v ...interface{}) ([]byte, error) {
+    return doRequestBytes(http.MethodGet, url, v...)
+}
+
+// sniffContentType 内容类型，Content-Type 缺失或为 application/octet-stream 时按内容识别
+func sniffContentType(header http.Header, head []byte) string {
+    contentType := header.Get("Content-Type")
+    mediaType, _, _ := mime.ParseMediaType(contentType)
+    if mediaType == "" || mediaType == "application/octet-stream" {
+        return http.DetectContentType(head)
+    }
+    return contentType
+}
+
+// binaryContent 内容类型是否为二进制
+func binaryContent(contentType string) bool {
+    switch mediaKind(contentType) {
+    case "json", "xml", "form", "text":
+        return false
+    }
+    mediaType, _, _ := mime.ParseMediaType(contentType)
+    return !strings.Contains(mediaType, "javascript") && mediaType != "application/x-ndjson"
+}
+
+// readBody 按选项读取响应体，二进制或超长内容按选项返回错误或写入文件
+func readBody(res *http.Response, opts *options) ([]byte, error) {
+    defer res.Body.Close()
+    if !opts.textOnly && opts.maxBodySize <= 0 && opts.downloadFallback == "" {
+        data, err := io.ReadAll(res.Body)
+        return data, errors.WithStack(err)
+    }
+
+    head := make([]byte, sniffSize)
+    n, err := io.ReadFull(res.Body, head)
+    if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+        return nil, errors.WithStack(err)
+    }
+    head = head[:n]
+    contentType := sniffContentType(res.Header, head)
+
+    binary := binaryContent(contentType) && (opts.textOnly || opts.downloadFallback != "")
+    tooLarge := opts.maxBodySize > 0 && res.ContentLength > opts.maxBodySize
+    if !binary && !tooLarge {
+        var buf bytes.Buffer
+        buf.Write(head)
+        body := io.Reader(res.Body)
+        if opts.maxBodySize > 0 {
+            body = io.LimitReader(body, opts.maxBodySize-int64(len(head))+1)
+        }
+        if _, err = buf.ReadFrom(body); err != nil {
+            return nil, errors.WithStack(err)
+        }
+        if opts.maxBodySize <= 0 || int64(buf.Len()) <= opts.maxBodySize {
+            return buf.Bytes(), nil
+        }
+        head, tooLarge = buf.Bytes(), true
+    }
+
+    if opts.downloadFallback != "" {
+        size, err := saveBody(opts.downloadFallback, io.MultiReader(bytes.NewReader(head), res.Body))
+        if err != nil {
+            return nil, err
+        }
+        return nil, errors.WithStack(&DownloadedError{FileName: opts.downloadFallback, ContentType: contentType, Size: size})
+    }
+    if tooLarge {
+        return nil, errors.WithStack(&BodyTooLargeError{Limit: opts.maxBodySize, Size: res.ContentLength})
+    }
+    return nil, errors.WithStack(&BinaryContentError{ContentType: contentType})
+}
+
+// saveBody 写入文件
+func saveBody(fileName string, body io.Reader) (int64, error) {
+    file, err := os.Create(fileName)
+    if err != nil {
+        return 0, errors.WithStack(err)
+    }
+    defer file.Close()
+
+    size, err := io.Copy(file, body)
+    return size, errors.WithStack(err)
+}
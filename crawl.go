@@ -0,0 +1,78 @@
+package req
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+// CrawlResult 增量抓取统计
+type CrawlResult struct {
+    // Fetched 重新请求的页面数量
+    Fetched int
+    // Skipped 缓存未过期跳过的页面数量
+    Skipped int
+    // Failed 请求失败的页面数量
+    Failed int
+}
+
+// CrawlSitemap 按站点地图增量抓取，只请求未缓存或 lastmod 晚于缓存时间的页面
+// 没有 lastmod 的页面已缓存时跳过，fn 回调重新请求的页面，会被并发调用；未设置缓存目录时请求全部页面
+func CrawlSitemap(ctx context.Context, url string, fn func(url, body string, err error), v ...interface{}) (CrawlResult, error) {
+    var result CrawlResult
+    items, err := GetSitemap(url, append([]interface{}{ctx, WithCacheRefresh()}, v...)...)
+    if err != nil {
+        return result, err
+    }
+
+    var (
+        mu      sync.Mutex
+        lastMod = make(map[string]SitemapURL, len(items))
+    )
+    for _, item := range items {
+        lastMod[item.Loc] = item
+    }
+
+    opts, args := splitOptions(v)
+    err = streamBatch(ctx, URLsFromSlice(uniqueLocs(items)), opts, func(ctx context.Context, url string) error {
+        name := cacheName(http.MethodGet, url, args...)
+        fetchedAt := cachedAt(name)
+        if modified := lastMod[url].LastModTime(); !fetchedAt.IsZero() && !modified.After(fetchedAt) {
+            mu.Lock()
+            result.Skipped++
+            mu.Unlock()
+            return nil
+        }
+
+        body, err := Get(url, append([]interface{}{ctx, WithCacheRefresh()}, v...)...)
+        if ctx.Err() != nil {
+            return err
+        }
+
+        mu.Lock()
+        if err != nil {
+            result.Failed++
+        } else {
+            result.Fetched++
+        }
+        mu.Unlock()
+        if fn != nil {
+            fn(url, body, err)
+        }
+        return err
+    })
+    return result, err
+}
+
+// uniqueLocs 去重后的链接地址
+func uniqueLocs(items []SitemapURL) []string {
+    seen := make(map[string]bool, len(items))
+    urls := make([]string, 0, len(items))
+    for _, item := range items {
+        if !seen[item.Loc] {
+            seen[item.Loc] = true
+            urls = append(urls, item.Loc)
+        }
+    }
+    return urls
+}
@@ -0,0 +1,225 @@
+package req
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"io"
+	"mime"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/html/charset"
+)
+
+// CSVOptions CSV 解析选项
+type CSVOptions struct {
+    // Context 请求上下文，默认 context.Background()
+    Context context.Context
+    // Comma 分隔符，为 0 时根据首行自动识别
+    Comma rune
+    // Comment 注释行前缀
+    Comment rune
+    // Charset 内容编码，为空时使用 Content-Type 中的 charset，默认 UTF-8
+    Charset string
+    // LazyQuotes 宽松引号
+    LazyQuotes bool
+}
+
+// GetCSV 获取 CSV 内容，返回全部行
+func GetCSV(url string, opts CSVOptions, v ...interface{}) ([][]string, error) {
+    reader, closer, err := csvReader(url, opts, v...)
+    if err != nil {
+        return nil, err
+    }
+    defer closer.Close()
+
+    records, err := reader.ReadAll()
+    return records, errors.WithStack(err)
+}
+
+// GetCSVTo 获取 CSV 内容并按首行表头解析到结构体切片指针
+// 字段通过 csv 标签对应表头，未设置标签时按字段名忽略大小写匹配，标签为 - 时忽略
+func GetCSVTo(url string, out interface{}, opts CSVOptions, v ...interface{}) error {
+    slice := reflect.ValueOf(out)
+    if slice.Kind() != reflect.Ptr || slice.Elem().Kind() != reflect.Slice || slice.Elem().Type().Elem().Kind() != reflect.Struct {
+        return errors.Errorf("csv: out must be pointer to struct slice, got %T", out)
+    }
+    slice = slice.Elem()
+    elemType := slice.Type().Elem()
+
+    reader, closer, err := csvReader(url, opts, v...)
+    if err != nil {
+        return err
+    }
+    defer closer.Close()
+
+    header, err := reader.Read()
+    if err != nil {
+        return errors.WithStack(err)
+    }
+
+    columns := csvColumns(elemType, header)
+    for line := 2; ; line++ {
+        record, err := reader.Read()
+        if err == io.EOF {
+            return nil
+        } else if err != nil {
+            return errors.WithStack(err)
+        }
+
+        elem := reflect.New(elemType).Elem()
+        for i, field := range columns {
+            if field < 0 || i >= len(record) {
+                continue
+            }
+            if err = setField(elem.Field(field), record[i]); err != nil {
+                return errors.Wrapf(err, "csv line %d column %s", line, header[i])
+            }
+        }
+        slice.Set(reflect.Append(slice, elem))
+    }
+}
+
+// csvReader 创建响应流的 CSV 读取器
+func csvReader(url string, opts CSVOptions, v ...interface{}) (*csv.Reader, io.Closer, error) {
+    ctx := opts.Context
+    if ctx == nil {
+        ctx = context.Background()
+    }
+
+    rep, err := GetStream(ctx, url, v...)
+    if err != nil {
+        return nil, nil, err
+    }
+
+    label := opts.Charset
+    if label == "" {
+        if _, params, err := mime.ParseMediaType(rep.Header.Get("Content-Type")); err == nil {
+            label = params["charset"]
+        }
+    }
+
+    var body io.Reader = rep.Body
+    if label != "" && !strings.EqualFold(label, "utf-8") && !strings.EqualFold(label, "utf8") {
+        body, err = charset.NewReaderLabel(label, body)
+        if err != nil {
+            rep.Close()
+            return nil, nil, errors.WithStack(err)
+        }
+    }
+
+    buffered := bufio.NewReader(body)
+    if bom, _ := buffered.Peek(3); bytes.Equal(bom, []byte{0xef, 0xbb, 0xbf}) {
+        _, _ = buffered.Discard(3)
+    }
+
+    comma := opts.Comma
+    if comma == 0 {
+        head, _ := buffered.Peek(4096)
+        comma = detectComma(head)
+    }
+
+    reader := csv.NewReader(buffered)
+    reader.Comma = comma
+    reader.Comment = opts.Comment
+    reader.LazyQuotes = opts.LazyQuotes
+    reader.FieldsPerRecord = -1
+
+    return reader, rep, nil
+}
+
+// detectComma 根据首行识别分隔符，忽略引号内字符
+func detectComma(head []byte) rune {
+    if i := bytes.IndexByte(head, '\n'); i >= 0 {
+        head = head[:i]
+    }
+
+    var (
+        quoted bool
+        counts = map[rune]int{}
+    )
+    for _, c := range string(head) {
+        switch {
+        case c == '"':
+            quoted = !quoted
+        case !quoted && (c == ',' || c == ';' || c == '\t' || c == '|'):
+            counts[c]++
+        }
+    }
+
+    comma, max := ',', 0
+    for _, c := range []rune{',', ';', '\t', '|'} {
+        if counts[c] > max {
+            comma, max = c, counts[c]
+        }
+    }
+    return comma
+}
+
+// csvColumns 表头对应的结构体字段下标，未匹配时为 -1
+func csvColumns(t reflect.Type, header []string) []int {
+    columns := make([]int, len(header))
+    for i, name := range header {
+        columns[i] = -1
+        name = strings.TrimSpace(name)
+        for j := 0; j < t.NumField(); j++ {
+            field := t.Field(j)
+            if field.PkgPath != "" {
+                continue
+            }
+            tag := field.Tag.Get("csv")
+            if tag == "-" {
+                continue
+            }
+            if tag == name || (tag == "" && strings.EqualFold(field.Name, name)) {
+                columns[i] = j
+                break
+            }
+        }
+    }
+    return columns
+}
+
+// setField 按字段类型设置字符串值
+func setField(field reflect.Value, value string) error {
+    value = strings.TrimSpace(value)
+    if value == "" && field.Kind() != reflect.String {
+        return nil
+    }
+
+    switch field.Kind() {
+    case reflect.String:
+        field.SetString(value)
+    case reflect.Bool:
+        b, err := strconv.ParseBool(value)
+        if err != nil {
+            return errors.WithStack(err)
+        }
+        field.SetBool(b)
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+        n, err := strconv.ParseInt(value, 10, field.Type().Bits())
+        if err != nil {
+            return errors.WithStack(err)
+        }
+        field.SetInt(n)
+    case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+        n, err := strconv.ParseUint(value, 10, field.Type().Bits())
+        if err != nil {
+            return errors.WithStack(err)
+        }
+        field.SetUint(n)
+    case reflect.Float32, reflect.Float64:
+        n, err := strconv.ParseFloat(value, field.Type().Bits())
+        if err != nil {
+            return errors.WithStack(err)
+        }
+        field.SetFloat(n)
+    default:
+        return errors.Errorf("unsupported field type: %s", field.Type())
+    }
+    return nil
+}
@@ -0,0 +1,151 @@
+package req
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"mime"
+	"net/http"
+	neturl "net/url"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+	"github.com/vmihailenco/msgpack/v5"
+	"golang.org/x/net/html/charset"
+)
+
+// XMLNode 通用 XML 节点，Decoded 解析 XML 响应时使用
+type XMLNode struct {
+    XMLName  xml.Name
+    Attrs    []xml.Attr `xml:",any,attr"`
+    Content  string     `xml:",chardata"`
+    Children []XMLNode  `xml:",any"`
+}
+
+// GetDecoded GET请求并按 Content-Type 解析响应内容，不经过缓存
+// JSON 与 MessagePack 返回 map[string]interface{} 等通用类型，XML 返回 *XMLNode，表单返回 url.Values，
+// 文本返回 string，其他类型返回 []byte
+func GetDecoded(url string, v ...interface{}) (interface{}, error) {
+    rep, attempts, err := doAttempts(http.MethodGet, url, v...)
+    if err != nil {
+        return nil, err
+    }
+
+    res := newResponse(rep)
+    res.Attempts = attempts
+    return res.Decoded()
+}
+
+// Decoded 按 Content-Type 解析响应内容为通用类型，返回值同 GetDecoded
+func (r *Response) Decoded() (interface{}, error) {
+    switch mediaKind(r.Header.Get("Content-Type")) {
+    case "json", "msgpack":
+        var value interface{}
+        err := r.Decode(&value)
+        return value, err
+    case "xml":
+        node := &XMLNode{}
+        return node, r.Decode(node)
+    case "form":
+        var values neturl.Values
+        err := r.Decode(&values)
+        return values, err
+    case "text":
+        var text string
+        err := r.Decode(&text)
+        return text, err
+    }
+    return r.Bytes()
+}
+
+// Decode 按 Content-Type 解析响应内容到 v
+// JSON、MessagePack 与 XML 解析到对应结构，表单支持 *url.Values 与 *map[string]string，
+// 文本支持 *string 与 *[]byte，非 UTF-8 文本会转换编码
+func (r *Response) Decode(v interface{}) error {
+    data, err := r.Bytes()
+    if err != nil {
+        return err
+    }
+
+    contentType := r.Header.Get("Content-Type")
+    switch mediaKind(contentType) {
+    case "json":
+        return errors.WithStack(jsoniter.Unmarshal(data, v))
+    case "msgpack":
+        return errors.WithStack(msgpack.Unmarshal(data, v))
+    case "xml":
+        decoder := xml.NewDecoder(bytes.NewReader(data))
+        decoder.CharsetReader = charset.NewReaderLabel
+        return errors.WithStack(decoder.Decode(v))
+    case "form":
+        values, err := neturl.ParseQuery(string(data))
+        if err != nil {
+            return errors.WithStack(err)
+        }
+        switch out := v.(type) {
+        case *neturl.Values:
+            *out = values
+        case *map[string]string:
+            *out = make(map[string]string, len(values))
+            for key := range values {
+                (*out)[key] = values.Get(key)
+            }
+        default:
+            return errors.Errorf("decode form: unsupported type %T", v)
+        }
+        return nil
+    case "text":
+        if data, err = textUTF8(contentType, data); err != nil {
+            return err
+        }
+    }
+
+    switch out := v.(type) {
+    case *string:
+        *out = string(data)
+    case *[]byte:
+        *out = data
+    default:
+        return errors.Errorf("decode %s: unsupported type %T", contentType, v)
+    }
+    return nil
+}
+
+// mediaKind 内容类型分类：json/msgpack/xml/form/text，无法识别时返回空
+func mediaKind(contentType string) string {
+    mediaType, _, err := mime.ParseMediaType(contentType)
+    if err != nil {
+        return ""
+    }
+
+    switch {
+    case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+        return "json"
+    case mediaType == "application/xml" || mediaType == "text/xml" || strings.HasSuffix(mediaType, "+xml"):
+        return "xml"
+    case mediaType == contentTypeMsgpack || mediaType == "application/x-msgpack":
+        return "msgpack"
+    case mediaType == "application/x-www-form-urlencoded":
+        return "form"
+    case strings.HasPrefix(mediaType, "text/"):
+        return "text"
+    }
+    return ""
+}
+
+// textUTF8 按 Content-Type 中的 charset 将文本转换为 UTF-8
+func textUTF8(contentType string, data []byte) ([]byte, error) {
+    _, params, _ := mime.ParseMediaType(contentType)
+    label := params["charset"]
+    if label == "" || strings.EqualFold(label, "utf-8") || strings.EqualFold(label, "utf8") {
+        return data, nil
+    }
+
+    reader, err := charset.NewReaderLabel(label, bytes.NewReader(data))
+    if err != nil {
+        return nil, errors.WithStack(err)
+    }
+    data, err = io.ReadAll(reader)
+    return data, errors.WithStack(err)
+}
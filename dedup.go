@@ -0,0 +1,99 @@
+package req
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+// cacheRefPrefix 去重缓存文件内容前缀，后接内容的 SHA-256
+var cacheRefPrefix = []byte("\x00req-object:")
+
+// bucketCacheObjects 内容对象引用计数
+var bucketCacheObjects = []byte("objects")
+
+// SetCacheDedup 开启缓存内容去重，相同内容只保存一份，缓存文件只记录内容的 SHA-256
+// 引用计数保存在缓存索引中，索引不可用时按普通方式写入
+func SetCacheDedup(enable bool) {
+    updateConfig(func(c *config) { c.cacheDedup = enable })
+}
+
+// objectName 内容对象文件路径
+func objectName(hash string) string {
+    return filepath.Join(conf().cachePath, ".objects", hash[:2], hash)
+}
+
+// contentHash 内容的 SHA-256
+func contentHash(data []byte) string {
+    return fmt.Sprintf("%x", sha256.Sum256(data))
+}
+
+// writeObject 写入内容对象，已存在时跳过
+func writeObject(hash string, data []byte) error {
+    name := objectName(hash)
+    if fileExist(name) {
+        return nil
+    }
+    if err := os.MkdirAll(filepath.Dir(name), os.ModePerm); err != nil {
+        return errors.WithStack(err)
+    }
+    return writeCache(name, data)
+}
+
+// readCache 读取缓存文件，去重缓存读取引用的内容对象
+func readCache(name string) ([]byte, error) {
+    data, err := os.ReadFile(name)
+    if err != nil {
+        return nil, errors.WithStack(err)
+    }
+    if !bytes.HasPrefix(data, cacheRefPrefix) {
+        return data, nil
+    }
+    data, err = os.ReadFile(objectName(string(data[len(cacheRefPrefix):])))
+    return data, errors.WithStack(err)
+}
+
+// refObject 调整内容对象引用计数，返回计数是否归零
+// 事务可能回滚或被 Batch 重新执行，归零的对象在事务提交后通过 removeObjects 删除
+func refObject(tx *bolt.Tx, hash string, delta int64) (bool, error) {
+    if hash == "" {
+        return false, nil
+    }
+    objects, err := tx.CreateBucketIfNotExists(bucketCacheObjects)
+    if err != nil {
+        return false, err
+    }
+
+    var count int64
+    if value := objects.Get([]byte(hash)); len(value) == 8 {
+        count = int64(binary.BigEndian.Uint64(value))
+    }
+    if count += delta; count > 0 {
+        value := make([]byte, 8)
+        binary.BigEndian.PutUint64(value, uint64(count))
+        return false, objects.Put([]byte(hash), value)
+    }
+    return true, objects.Delete([]byte(hash))
+}
+
+// removeObjects 删除引用计数已归零的内容对象，在写事务中确认计数未被再次增加，删除失败时忽略
+func removeObjects(db *bolt.DB, hashes []string) {
+    if len(hashes) == 0 {
+        return
+    }
+    _ = db.Update(func(tx *bolt.Tx) error {
+        objects := tx.Bucket(bucketCacheObjects)
+        for _, hash := range hashes {
+            if objects == nil || objects.Get([]byte(hash)) == nil {
+                _ = os.Remove(objectName(hash))
+            }
+        }
+        return nil
+    })
+}
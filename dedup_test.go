@@ -0,0 +1,44 @@
+package req
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestCacheDedupRefCount(t *testing.T) {
+    withCachePath(t)
+    SetCacheDedup(true)
+    t.Cleanup(func() { SetCacheDedup(false) })
+
+    data := []byte("shared content")
+    a := cacheName(http.MethodGet, "http://a.example.com/")
+    b := cacheName(http.MethodGet, "http://b.example.com/")
+    for _, name := range []string{a, b} {
+        if err := storeCache(name, http.MethodGet, "http://example.com/", data); err != nil {
+            t.Fatal(err)
+        }
+    }
+    object := objectName(contentHash(data))
+    if !fileExist(object) {
+        t.Fatal("content object not written")
+    }
+
+    // 一个引用改为其他内容后对象仍被另一个引用使用
+    if err := storeCache(a, http.MethodGet, "http://a.example.com/", []byte("other")); err != nil {
+        t.Fatal(err)
+    }
+    if got, err := readCache(b); err != nil || string(got) != string(data) {
+        t.Fatalf("read shared object = %q, %v", got, err)
+    }
+
+    // 最后一个引用删除后对象在事务提交后删除
+    if err := removeCacheFile(b); err != nil {
+        t.Fatal(err)
+    }
+    if fileExist(object) {
+        t.Fatal("unreferenced object not removed")
+    }
+    if got, err := readCache(a); err != nil || string(got) != "other" {
+        t.Fatalf("read remaining entry = %q, %v", got, err)
+    }
+}
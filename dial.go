@@ -0,0 +1,222 @@
+package req
+
+import (
+	"context"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// IPPreference 建立连接时的 IP 协议族偏好
+type IPPreference int
+
+const (
+    // IPDefault 使用系统解析顺序，双栈时按 Happy Eyeballs 并行连接
+    IPDefault IPPreference = iota
+    // IPPreferV4 优先连接 IPv4，失败或超过回退延迟后尝试 IPv6
+    IPPreferV4
+    // IPPreferV6 优先连接 IPv6，失败或超过回退延迟后尝试 IPv4
+    IPPreferV6
+    // IPOnlyV4 只连接 IPv4
+    IPOnlyV4
+    // IPOnlyV6 只连接 IPv6
+    IPOnlyV6
+)
+
+// SetIPPreference 设置 IP 协议族偏好，用于目标 IPv6 线路不稳定等场景
+func SetIPPreference(preference IPPreference) {
+    updateConfig(func(c *config) { c.ipPreference = preference })
+    resetClient()
+}
+
+// SetFallbackDelay 设置双栈连接的回退延迟，首选地址在该时长内未连接成功时并行尝试另一协议族，
+// 为 0 时使用默认值 300ms，为负数时关闭并行，按顺序逐个尝试
+func SetFallbackDelay(delay time.Duration) {
+    updateConfig(func(c *config) { c.fallbackDelay = delay })
+    resetClient()
+}
+
+// SetHostIPs 指定主机连接的 IP，跳过 DNS 解析，ips 为空时取消
+func SetHostIPs(host string, ips ...string) error {
+    parsed := make([]net.IP, 0, len(ips))
+    for _, s := range ips {
+        ip := net.ParseIP(s)
+        if ip == nil {
+            return errors.Errorf("invalid ip: %s", s)
+        }
+        parsed = append(parsed, ip)
+    }
+
+    updateConfig(func(c *config) {
+        hosts := make(map[string][]net.IP, len(c.hostIPs)+1)
+        for k, v := range c.hostIPs {
+            hosts[k] = v
+        }
+        if len(parsed) > 0 {
+            hosts[strings.ToLower(host)] = parsed
+        } else {
+            delete(hosts, strings.ToLower(host))
+        }
+        c.hostIPs = hosts
+    })
+    resetClient()
+    return nil
+}
+
+// lookupIPs 解析主机 IP，按指定 IP 与协议族偏好过滤排序
+func lookupIPs(ctx context.Context, host string) ([]net.IP, error) {
+    c := conf()
+    ips, ok := c.hostIPs[strings.ToLower(host)]
+    if !ok {
+        if ip := net.ParseIP(host); ip != nil {
+            ips = []net.IP{ip}
+        } else {
+            addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+            if err != nil {
+                return nil, errors.WithStack(err)
+            }
+            for _, a := range addrs {
+                ips = append(ips, a.IP)
+            }
+        }
+    }
+
+    var first, second []net.IP
+    for _, ip := range ips {
+        v4 := ip.To4() != nil
+        switch c.ipPreference {
+        case IPOnlyV4, IPPreferV4:
+            if v4 {
+                first = append(first, ip)
+            } else if c.ipPreference == IPPreferV4 {
+                second = append(second, ip)
+            }
+        case IPOnlyV6, IPPreferV6:
+            if !v4 {
+                first = append(first, ip)
+            } else if c.ipPreference == IPPreferV6 {
+                second = append(second, ip)
+            }
+        default:
+            first = append(first, ip)
+        }
+    }
+    if ips = append(first, second...); len(ips) == 0 {
+        return nil, errors.Errorf("no address for host: %s", host)
+    }
+    if c.addressFailover {
+        ips = healthyFirst(strings.ToLower(host), ips)
+    }
+    return ips, nil
+}
+
+// preferenceDial 按协议族偏好解析后建立连接，首选协议族超过回退延迟未成功时并行尝试另一协议族
+func preferenceDial(dialer *net.Dialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+    return func(ctx context.Context, network, addr string) (net.Conn, error) {
+        host, port, err := net.SplitHostPort(addr)
+        if err != nil {
+            return nil, errors.WithStack(err)
+        }
+        ips, ok := resolvedIPs(ctx)
+        if !ok {
+            if ips, err = lookupIPs(ctx, host); err != nil {
+                return nil, err
+            }
+        }
+
+        var primary, fallback []string
+        for _, ip := range ips {
+            if (ip.To4() != nil) == (ips[0].To4() != nil) {
+                primary = append(primary, net.JoinHostPort(ip.String(), port))
+            } else {
+                fallback = append(fallback, net.JoinHostPort(ip.String(), port))
+            }
+        }
+        if dialer.FallbackDelay < 0 || len(fallback) == 0 {
+            return dialSerial(ctx, dialer, network, append(primary, fallback...))
+        }
+        return dialFallback(ctx, dialer, network, primary, fallback)
+    }
+}
+
+// dialSerial 依次尝试地址，返回第一个成功的连接
+func dialSerial(ctx context.Context, dialer *net.Dialer, network string, addrs []string) (net.Conn, error) {
+    var err error
+    for _, addr := range addrs {
+        var conn net.Conn
+        if conn, err = dialer.DialContext(ctx, network, addr); err == nil {
+            return conn, nil
+        }
+        if ctx.Err() != nil {
+            break
+        }
+    }
+    return nil, errors.WithStack(err)
+}
+
+// dialFallback 首选地址失败或超过回退延迟后并行尝试备选地址，返回先成功的连接
+func dialFallback(ctx context.Context, dialer *net.Dialer, network string, primary, fallback []string) (net.Conn, error) {
+    type result struct {
+        conn    net.Conn
+        err     error
+        primary bool
+    }
+
+    ctx, cancel := context.WithCancel(ctx)
+    defer cancel()
+
+    results := make(chan result, 2)
+    start := func(addrs []string, primary bool) {
+        go func() {
+            conn, err := dialSerial(ctx, dialer, network, addrs)
+            results <- result{conn: conn, err: err, primary: primary}
+        }()
+    }
+
+    delay := dialer.FallbackDelay
+    if delay == 0 {
+        delay = 300 * time.Millisecond
+    }
+    timer := time.NewTimer(delay)
+    defer timer.Stop()
+
+    start(primary, true)
+    var (
+        started = false
+        pending = 1
+        first   error
+    )
+    for {
+        select {
+        case <-timer.C:
+            if !started {
+                started, pending = true, pending+1
+                start(fallback, false)
+            }
+        case res := <-results:
+            pending--
+            if res.err == nil {
+                // 另一组稍后成功的连接直接关闭
+                go func(n int) {
+                    for ; n > 0; n-- {
+                        if late := <-results; late.conn != nil {
+                            late.conn.Close()
+                        }
+                    }
+                }(pending)
+                return res.conn, nil
+            }
+            if first == nil || res.primary {
+                first = res.err
+            }
+            if !started {
+                started, pending = true, pending+1
+                start(fallback, false)
+            } else if pending == 0 {
+                return nil, first
+            }
+        }
+    }
+}
@@ -0,0 +1,227 @@
+package req
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// digestKey 请求上下文中的 Digest 认证信息
+type digestKey struct{}
+
+// digestCredentials Digest 认证信息
+type digestCredentials struct {
+    user     string
+    password string
+}
+
+// WithDigestAuth 使用 RFC 7616 Digest 认证，收到 401 质询后计算摘要重新发送请求，
+// 同一主机后续请求复用质询中的 nonce，支持 MD5/SHA-256 及其 -sess 算法与 qop=auth
+func WithDigestAuth(user, password string) Option {
+    return func(o *options) {
+        o.digest = &digestCredentials{user: user, password: password}
+    }
+}
+
+// digestChallenge 服务端质询，nc 为该 nonce 已使用的次数
+type digestChallenge struct {
+    mutex     sync.Mutex
+    realm     string
+    nonce     string
+    opaque    string
+    algorithm string
+    qop       string
+    nc        int
+}
+
+// parseDigestChallenge 解析 WWW-Authenticate 中的 Digest 质询
+func parseDigestChallenge(header string) (*digestChallenge, bool) {
+    scheme, rest, _ := strings.Cut(strings.TrimSpace(header), " ")
+    if !strings.EqualFold(scheme, "Digest") {
+        return nil, false
+    }
+
+    params := parseAuthParams(rest)
+    c := &digestChallenge{
+        realm:     params["realm"],
+        nonce:     params["nonce"],
+        opaque:    params["opaque"],
+        algorithm: params["algorithm"],
+    }
+    if c.algorithm == "" {
+        c.algorithm = "MD5"
+    }
+    for _, qop := range strings.Split(params["qop"], ",") {
+        if strings.TrimSpace(qop) == "auth" {
+            c.qop = "auth"
+        }
+    }
+    return c, c.nonce != ""
+}
+
+// parseAuthParams 解析 key=value 与 key="value" 形式的认证参数
+func parseAuthParams(s string) map[string]string {
+    params := map[string]string{}
+    for s = strings.TrimSpace(s); s != ""; s = strings.TrimLeft(strings.TrimSpace(s), ",") {
+        s = strings.TrimSpace(s)
+        eq := strings.IndexByte(s, '=')
+        if eq < 0 {
+            break
+        }
+        key := strings.ToLower(strings.TrimSpace(s[:eq]))
+        s = strings.TrimSpace(s[eq+1:])
+
+        var value string
+        if strings.HasPrefix(s, `"`) {
+            var b strings.Builder
+            i := 1
+            for ; i < len(s) && s[i] != '"'; i++ {
+                if s[i] == '\\' && i+1 < len(s) {
+                    i++
+                }
+                b.WriteByte(s[i])
+            }
+            if i < len(s) {
+                i++
+            }
+            value, s = b.String(), s[i:]
+        } else if end := strings.IndexByte(s, ','); end >= 0 {
+            value, s = strings.TrimSpace(s[:end]), s[end:]
+        } else {
+            value, s = strings.TrimSpace(s), ""
+        }
+        params[key] = value
+    }
+    return params
+}
+
+// hasher 按算法返回摘要函数
+func (c *digestChallenge) hasher() (func() hash.Hash, error) {
+    switch strings.TrimSuffix(strings.ToUpper(c.algorithm), "-SESS") {
+    case "MD5":
+        return md5.New, nil
+    case "SHA-256":
+        return sha256.New, nil
+    }
+    return nil, errors.Errorf("digest: unsupported algorithm %s", c.algorithm)
+}
+
+// authorize 计算 Authorization 请求头
+func (c *digestChallenge) authorize(cred *digestCredentials, method, uri string) (string, error) {
+    c.mutex.Lock()
+    c.nc++
+    nc := fmt.Sprintf("%08x", c.nc)
+    c.mutex.Unlock()
+
+    b := make([]byte, 16)
+    _, _ = rand.Read(b)
+    cnonce := hex.EncodeToString(b)
+
+    response, err := c.response(cred, method, uri, nc, cnonce)
+    if err != nil {
+        return "", err
+    }
+
+    var b2 strings.Builder
+    fmt.Fprintf(&b2, `Digest username=%q, realm=%q, nonce=%q, uri=%q, algorithm=%s, response=%q`,
+        cred.user, c.realm, c.nonce, uri, c.algorithm, response)
+    if c.opaque != "" {
+        fmt.Fprintf(&b2, `, opaque=%q`, c.opaque)
+    }
+    if c.qop != "" {
+        fmt.Fprintf(&b2, `, qop=%s, nc=%s, cnonce=%q`, c.qop, nc, cnonce)
+    }
+    return b2.String(), nil
+}
+
+// response 按 RFC 7616 计算摘要
+func (c *digestChallenge) response(cred *digestCredentials, method, uri, nc, cnonce string) (string, error) {
+    newHash, err := c.hasher()
+    if err != nil {
+        return "", err
+    }
+    h := func(s string) string {
+        sum := newHash()
+        io.WriteString(sum, s)
+        return hex.EncodeToString(sum.Sum(nil))
+    }
+
+    ha1 := h(cred.user + ":" + c.realm + ":" + cred.password)
+    if strings.HasSuffix(strings.ToUpper(c.algorithm), "-SESS") {
+        ha1 = h(ha1 + ":" + c.nonce + ":" + cnonce)
+    }
+    ha2 := h(method + ":" + uri)
+
+    if c.qop != "" {
+        return h(strings.Join([]string{ha1, c.nonce, nc, cnonce, c.qop, ha2}, ":")), nil
+    }
+    return h(ha1 + ":" + c.nonce + ":" + ha2), nil
+}
+
+// digestTransport 按上下文中的认证信息处理 Digest 质询
+type digestTransport struct {
+    base http.RoundTripper
+    // challenges 按主机缓存的质询
+    challenges sync.Map
+}
+
+// RoundTrip 发送请求，收到 Digest 质询时携带认证信息重新发送
+func (t *digestTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+    cred, ok := r.Context().Value(digestKey{}).(*digestCredentials)
+    if !ok {
+        return t.base.RoundTrip(r)
+    }
+
+    r, err := replayable(r)
+    if err != nil {
+        return nil, err
+    }
+
+    key := r.URL.Scheme + "://" + r.URL.Host
+    if cached, ok := t.challenges.Load(key); ok {
+        if auth, err := cached.(*digestChallenge).authorize(cred, r.Method, r.URL.RequestURI()); err == nil {
+            if first, err := resend(r, "Authorization", auth); err == nil {
+                r = first
+            }
+        }
+    }
+
+    rep, err := t.base.RoundTrip(r)
+    if err != nil || rep.StatusCode != http.StatusUnauthorized {
+        return rep, err
+    }
+
+    var challenge *digestChallenge
+    for _, header := range rep.Header.Values("WWW-Authenticate") {
+        if c, ok := parseDigestChallenge(header); ok {
+            challenge = c
+            break
+        }
+    }
+    if challenge == nil {
+        return rep, nil
+    }
+    auth, err := challenge.authorize(cred, r.Method, r.URL.RequestURI())
+    if err != nil {
+        return rep, nil
+    }
+
+    retry, err := resend(r, "Authorization", auth)
+    if err != nil {
+        return rep, nil
+    }
+    io.Copy(io.Discard, rep.Body)
+    rep.Body.Close()
+
+    t.challenges.Store(key, challenge)
+    return t.base.RoundTrip(retry)
+}
@@ -0,0 +1,90 @@
+package req
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// RFC 7616 3.9.1 示例参数
+const (
+    digestTestRealm  = "http-auth@example.org"
+    digestTestNonce  = "7ypf/xlj9XXwfDPEoM4URrv/xwf94BcCAzFZH4GiTo0v"
+    digestTestCnonce = "f2/wE4q74E6zIJEtWaHKaf5wv/H5QzzpXusqGemxURZJ"
+    digestTestURI    = "/dir/index.html"
+)
+
+// digestHex 计算十六进制摘要
+func digestHex(newHash func() hash.Hash, s string) string {
+    sum := newHash()
+    io.WriteString(sum, s)
+    return hex.EncodeToString(sum.Sum(nil))
+}
+
+// digestSessResponse 按 -sess 算法逐步计算期望的摘要
+func digestSessResponse(newHash func() hash.Hash) string {
+    ha1 := digestHex(newHash, digestHex(newHash, "Mufasa:"+digestTestRealm+":Circle of Life")+":"+digestTestNonce+":"+digestTestCnonce)
+    ha2 := digestHex(newHash, "GET:"+digestTestURI)
+    return digestHex(newHash, ha1+":"+digestTestNonce+":00000001:"+digestTestCnonce+":auth:"+ha2)
+}
+
+func TestDigestResponse(t *testing.T) {
+    cred := &digestCredentials{user: "Mufasa", password: "Circle of Life"}
+    tests := []struct {
+        algorithm string
+        want      string
+    }{
+        // RFC 7616 示例中的 MD5 结果有误，使用勘误修正后的值
+        {"MD5", "8ca523f5e9506fed4657c9700eebdbec"},
+        {"SHA-256", "753927fa0e85d155564e2e272a28d1802ca10daf4496794697cf8db5856cb6c1"},
+        {"MD5-sess", digestSessResponse(md5.New)},
+        {"SHA-256-sess", digestSessResponse(sha256.New)},
+    }
+    for _, tt := range tests {
+        t.Run(tt.algorithm, func(t *testing.T) {
+            c := &digestChallenge{realm: digestTestRealm, nonce: digestTestNonce, algorithm: tt.algorithm, qop: "auth"}
+            got, err := c.response(cred, http.MethodGet, digestTestURI, "00000001", digestTestCnonce)
+            if err != nil {
+                t.Fatal(err)
+            }
+            if got != tt.want {
+                t.Fatalf("response = %s, want %s", got, tt.want)
+            }
+        })
+    }
+}
+
+func TestDigestAuth(t *testing.T) {
+    server := NewMockServer()
+    defer server.Close()
+    var calls int
+    server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        calls++
+        _, auth, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+        params := parseAuthParams(auth)
+        c := &digestChallenge{realm: digestTestRealm, nonce: digestTestNonce, algorithm: "SHA-256", qop: "auth"}
+        want, _ := c.response(&digestCredentials{user: "Mufasa", password: "Circle of Life"},
+            r.Method, r.URL.RequestURI(), params["nc"], params["cnonce"])
+        if params["response"] != want || params["username"] != "Mufasa" {
+            w.Header().Set("WWW-Authenticate",
+                `Digest realm="`+digestTestRealm+`", qop="auth, auth-int", algorithm=SHA-256, nonce="`+digestTestNonce+`"`)
+            w.WriteHeader(http.StatusUnauthorized)
+            return
+        }
+        w.Write([]byte("ok"))
+    })
+    defer server.Install()()
+
+    body, err := Get("http://api.example.com"+digestTestURI, WithDigestAuth("Mufasa", "Circle of Life"))
+    if err != nil || string(body) != "ok" {
+        t.Fatalf("Get = %q, %v", body, err)
+    }
+    if calls != 2 {
+        t.Fatalf("calls = %d, want challenge and authorized request", calls)
+    }
+}
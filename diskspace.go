@@ -0,0 +1,45 @@
+package req
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// InsufficientSpaceError 目标文件系统剩余空间不足
+type InsufficientSpaceError struct {
+    Path      string
+    Required  int64
+    Available int64
+}
+
+// Error 错误信息
+func (e *InsufficientSpaceError) Error() string {
+    return fmt.Sprintf("insufficient disk space at %s: required %d, available %d", e.Path, e.Required, e.Available)
+}
+
+// SetDiskSpaceMargin 设置下载前检查剩余空间时额外保留的字节数，默认 64MB，为负数时不检查
+func SetDiskSpaceMargin(margin int64) {
+    updateConfig(func(c *config) { c.diskSpaceMargin = margin })
+}
+
+// checkDiskSpace 检查写入 size 字节前目标目录的剩余空间，size 未知或当前系统不支持获取剩余空间时不检查
+func checkDiskSpace(fileName string, size int64) error {
+    margin := conf().diskSpaceMargin
+    if size <= 0 || margin < 0 {
+        return nil
+    }
+
+    dir := filepath.Dir(fileName)
+    available, err := freeSpace(dir)
+    if err != nil {
+        return errors.Wrapf(err, "disk space: %s", dir)
+    } else if available < 0 {
+        return nil
+    }
+    if required := size + margin; available < required {
+        return errors.WithStack(&InsufficientSpaceError{Path: dir, Required: required, Available: available})
+    }
+    return nil
+}
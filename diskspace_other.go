@@ -0,0 +1,8 @@
+//go:build !linux && !darwin && !freebsd && !windows
+
+package req
+
+// freeSpace 当前系统不支持获取可用空间，返回 -1
+func freeSpace(dir string) (int64, error) {
+    return -1, nil
+}
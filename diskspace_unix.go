@@ -0,0 +1,18 @@
+//go:build linux || darwin || freebsd
+
+package req
+
+import (
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// freeSpace 目录所在文件系统的可用空间
+func freeSpace(dir string) (int64, error) {
+    var stat syscall.Statfs_t
+    if err := syscall.Statfs(dir, &stat); err != nil {
+        return -1, errors.WithStack(err)
+    }
+    return int64(uint64(stat.Bavail) * uint64(stat.Bsize)), nil
+}
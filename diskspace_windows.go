@@ -0,0 +1,21 @@
+//go:build windows
+
+package req
+
+import (
+	"github.com/pkg/errors"
+	"golang.org/x/sys/windows"
+)
+
+// freeSpace 目录所在文件系统的可用空间
+func freeSpace(dir string) (int64, error) {
+    path, err := windows.UTF16PtrFromString(dir)
+    if err != nil {
+        return -1, errors.WithStack(err)
+    }
+    var available, total, free uint64
+    if err = windows.GetDiskFreeSpaceEx(path, &available, &total, &free); err != nil {
+        return -1, errors.WithStack(err)
+    }
+    return int64(available), nil
+}
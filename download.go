@@ -0,0 +1,231 @@
+package req
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/imroc/req"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+)
+
+// DownloadTask 下载任务
+type DownloadTask struct {
+    URL      string
+    FileName string
+}
+
+// downloadEntry 下载状态文件条目
+type downloadEntry struct {
+    URL string `json:"url"`
+    // Size 文件总长度，未知时为 -1
+    Size int64 `json:"size"`
+    // Offset 已写入长度
+    Offset       int64  `json:"offset"`
+    Done         bool   `json:"done"`
+    ETag         string `json:"etag,omitempty"`
+    LastModified string `json:"last_modified,omitempty"`
+}
+
+// downloadState 下载状态文件，按文件名记录进度
+type downloadState struct {
+    mutex   sync.Mutex
+    path    string
+    Entries map[string]*downloadEntry `json:"entries"`
+}
+
+// loadDownloadState 读取状态文件，不存在时创建空状态
+func loadDownloadState(path string) (*downloadState, error) {
+    state := &downloadState{path: path, Entries: map[string]*downloadEntry{}}
+    data, err := os.ReadFile(path)
+    if os.IsNotExist(err) {
+        return state, nil
+    } else if err != nil {
+        return nil, errors.WithStack(err)
+    }
+    if err = jsoniter.Unmarshal(data, state); err != nil {
+        return nil, errors.Wrapf(err, "invalid download state: %s", path)
+    }
+    if state.Entries == nil {
+        state.Entries = map[string]*downloadEntry{}
+    }
+    return state, nil
+}
+
+// entry 文件的状态条目，链接变化时重新下载
+func (s *downloadState) entry(task DownloadTask) downloadEntry {
+    s.mutex.Lock()
+    defer s.mutex.Unlock()
+    if e, ok := s.Entries[task.FileName]; ok && e.URL == task.URL {
+        return *e
+    }
+    return downloadEntry{URL: task.URL, Size: -1}
+}
+
+// update 更新条目并写入状态文件，先写临时文件再替换，避免中断时损坏
+func (s *downloadState) update(fileName string, e downloadEntry) error {
+    s.mutex.Lock()
+    defer s.mutex.Unlock()
+
+    s.Entries[fileName] = &e
+    data, err := jsoniter.MarshalIndent(s, "", "  ")
+    if err != nil {
+        return errors.WithStack(err)
+    }
+    tmp := s.path + ".tmp"
+    if err = os.WriteFile(tmp, data, 0644); err != nil {
+        return errors.WithStack(err)
+    }
+    return errors.WithStack(os.Rename(tmp, s.path))
+}
+
+// BatchDownload 批量下载文件，进度记录在 stateFile 中
+// 重新执行时跳过已完成的文件，未完成的文件通过 Range 请求续传，服务端不支持续传或文件已变化时重新下载
+// 返回失败任务的下标与错误，error 仅表示状态文件无法读写
+func BatchDownload(ctx context.Context, tasks []DownloadTask, stateFile string, v ...interface{}) (map[int]error, error) {
+    state, err := loadDownloadState(stateFile)
+    if err != nil {
+        return nil, err
+    }
+
+    var (
+        wg       sync.WaitGroup
+        mutex    sync.Mutex
+        errMap   = make(map[int]error)
+        stateErr error
+        opts, _  = splitOptions(v)
+        items    = make([]*batchItem, 0, len(tasks))
+    )
+    for i, task := range tasks {
+        i, task := i, task
+        wg.Add(1)
+        items = append(items, newBatchItem(i, task.URL, opts.priority, func() error {
+            defer wg.Done()
+            err := resumeDownload(ctx, task, state, v)
+            if err != nil {
+                mutex.Lock()
+                var se *downloadStateError
+                if errors.As(err, &se) {
+                    if stateErr == nil {
+                        stateErr = se.err
+                    }
+                } else {
+                    errMap[i] = err
+                }
+                mutex.Unlock()
+            }
+            return err
+        }))
+    }
+
+    getScheduler().submit(items...)
+    wg.Wait()
+    return errMap, stateErr
+}
+
+// downloadStateError 状态文件写入错误
+type downloadStateError struct {
+    err error
+}
+
+// Error 错误信息
+func (e *downloadStateError) Error() string {
+    return e.err.Error()
+}
+
+// resumeDownload 下载单个文件，已有部分内容时续传
+func resumeDownload(ctx context.Context, task DownloadTask, state *downloadState, v []interface{}) error {
+    e := state.entry(task)
+    info, statErr := os.Stat(task.FileName)
+    if e.Done && statErr == nil && (e.Size < 0 || info.Size() == e.Size) {
+        return nil
+    }
+
+    // 以磁盘上的实际长度为准，状态文件可能落后于写入
+    offset := int64(0)
+    if statErr == nil && (e.ETag != "" || e.LastModified != "") {
+        offset = info.Size()
+    }
+
+    header := req.Header{}
+    if offset > 0 {
+        header["Range"] = fmt.Sprintf("bytes=%d-", offset)
+        if e.ETag != "" {
+            header["If-Range"] = e.ETag
+        } else {
+            header["If-Range"] = e.LastModified
+        }
+    }
+
+    res, err := GetStream(ctx, task.URL, append([]interface{}{header}, v...)...)
+    if err != nil {
+        if offset > 0 && statusCode(err) == http.StatusRequestedRangeNotSatisfiable {
+            e.Done, e.Size, e.Offset = true, offset, offset
+            return stateUpdate(state, task.FileName, e)
+        }
+        return err
+    }
+    defer res.Close()
+
+    flag := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+    if res.StatusCode == http.StatusPartialContent && offset > 0 {
+        var start int64
+        if _, err := fmt.Sscanf(res.Header.Get("Content-Range"), "bytes %d-", &start); err != nil || start != offset {
+            return errors.Errorf("unexpected content range: %s", res.Header.Get("Content-Range"))
+        }
+        flag = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+    } else {
+        offset = 0
+    }
+
+    e.ETag, e.LastModified = res.Header.Get("ETag"), res.Header.Get("Last-Modified")
+    e.Size, e.Offset, e.Done = -1, offset, false
+    if res.ContentLength >= 0 {
+        e.Size = offset + res.ContentLength
+    }
+    if err = stateUpdate(state, task.FileName, e); err != nil {
+        return err
+    }
+
+    if dir := filepath.Dir(task.FileName); dir != "" {
+        if err = os.MkdirAll(dir, os.ModePerm); err != nil {
+            return errors.WithStack(err)
+        }
+    }
+    if err = checkDiskSpace(task.FileName, res.ContentLength); err != nil {
+        return err
+    }
+    file, err := os.OpenFile(task.FileName, flag, 0644)
+    if err != nil {
+        return errors.WithStack(err)
+    }
+    written, err := io.Copy(file, res.Body)
+    if cerr := file.Close(); err == nil {
+        err = cerr
+    }
+
+    e.Offset += written
+    if err != nil {
+        _ = stateUpdate(state, task.FileName, e)
+        return errors.WithStack(err)
+    }
+    if e.Size >= 0 && e.Offset != e.Size {
+        _ = stateUpdate(state, task.FileName, e)
+        return errors.Errorf("incomplete download: %d of %d bytes", e.Offset, e.Size)
+    }
+    e.Done, e.Size = true, e.Offset
+    return stateUpdate(state, task.FileName, e)
+}
+
+// stateUpdate 更新状态文件，错误标记为状态文件错误
+func stateUpdate(state *downloadState, fileName string, e downloadEntry) error {
+    if err := state.update(fileName, e); err != nil {
+        return &downloadStateError{err: err}
+    }
+    return nil
+}
@@ -0,0 +1,101 @@
+package req
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// dumpConfig 原始内容输出配置
+type dumpConfig struct {
+    w     io.Writer
+    dir   string
+    body  bool
+    mutex sync.Mutex
+    seq   uint64
+}
+
+// SetDump 将每次请求（包括重试）的原始请求与响应输出到 w，w 为 nil 时关闭
+// body 为 true 时同时输出请求体与响应内容，内容会被完整读入内存
+func SetDump(w io.Writer, body bool) {
+    var dumper *dumpConfig
+    if w != nil {
+        dumper = &dumpConfig{w: w, body: body}
+    }
+    updateConfig(func(c *config) { c.dumper = dumper })
+    resetClient()
+}
+
+// SetDumpDir 将每次请求（包括重试）的原始请求与响应分别输出到目录下的单独文件，dir 为空时关闭
+func SetDumpDir(dir string, body bool) error {
+    if dir == "" {
+        updateConfig(func(c *config) { c.dumper = nil })
+        resetClient()
+        return nil
+    }
+    if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+        return errors.WithStack(err)
+    }
+
+    updateConfig(func(c *config) { c.dumper = &dumpConfig{dir: dir, body: body} })
+    resetClient()
+    return nil
+}
+
+// dumpTransport 输出原始请求与响应的传输层
+type dumpTransport struct {
+    base   http.RoundTripper
+    config *dumpConfig
+}
+
+// RoundTrip 发起请求并输出
+func (t *dumpTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+    var buf bytes.Buffer
+    seq := atomic.AddUint64(&t.config.seq, 1)
+    fmt.Fprintf(&buf, ">>> #%d %s\n", seq, time.Now().Format(time.RFC3339Nano))
+
+    // DumpRequestOut 会替换请求体，在副本上操作
+    r = r.Clone(r.Context())
+    if data, err := httputil.DumpRequestOut(r, t.config.body && r.Body != nil); err != nil {
+        fmt.Fprintf(&buf, "dump request: %v\n", err)
+    } else {
+        buf.Write(data)
+    }
+
+    start := time.Now()
+    rep, err := t.base.RoundTrip(r)
+    fmt.Fprintf(&buf, "\n<<< #%d %s\n", seq, time.Since(start))
+    if err != nil {
+        fmt.Fprintf(&buf, "error: %v\n", err)
+    } else if data, err := httputil.DumpResponse(rep, t.config.body); err != nil {
+        fmt.Fprintf(&buf, "dump response: %v\n", err)
+    } else {
+        buf.Write(data)
+    }
+    buf.WriteString("\n")
+
+    t.config.write(seq, buf.Bytes())
+    return rep, err
+}
+
+// write 输出内容，输出失败不影响请求
+func (c *dumpConfig) write(seq uint64, data []byte) {
+    if c.dir != "" {
+        name := fmt.Sprintf("%s-%06d.dump", time.Now().Format("20060102150405"), seq)
+        _ = os.WriteFile(filepath.Join(c.dir, name), data, os.ModePerm)
+        return
+    }
+
+    c.mutex.Lock()
+    _, _ = c.w.Write(data)
+    c.mutex.Unlock()
+}
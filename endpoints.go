@@ -0,0 +1,152 @@
+package req
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// defaultEndpointCooldown 地址失败后跳过的时长
+const defaultEndpointCooldown = time.Second * 30
+
+// GetAny 依次请求 urls 直到成功，连接失败或可重试的状态码时切换到下一个地址
+func GetAny(urls []string, v ...interface{}) (string, error) {
+    if len(urls) == 0 {
+        return "", errors.New("no urls")
+    }
+
+    var lastErr error
+    for _, url := range urls {
+        body, err := Get(url, v...)
+        if err == nil {
+            return body, nil
+        }
+        if !failover(err) {
+            return "", err
+        }
+        lastErr = err
+    }
+    return "", errors.Wrapf(lastErr, "all %d urls failed", len(urls))
+}
+
+// failover 错误是否应切换地址，不可重试的状态码说明地址可用，不切换
+func failover(err error) bool {
+    var status *StatusError
+    if errors.As(err, &status) {
+        return conf().retryStatus(status.StatusCode)
+    }
+    return true
+}
+
+// Endpoints 多地址接口，主地址不可用时按顺序切换到备用地址，
+// 之后优先使用最近一次成功的地址，失败的地址在冷却时间内排到最后
+type Endpoints struct {
+    // Cooldown 地址失败后排到最后的时长，默认 30 秒
+    Cooldown time.Duration
+
+    mutex     sync.Mutex
+    bases     []string
+    preferred int
+    down      map[int]time.Time
+}
+
+// NewEndpoints 创建多地址接口，第一个为主地址
+func NewEndpoints(bases ...string) *Endpoints {
+    return &Endpoints{Cooldown: defaultEndpointCooldown, bases: bases, down: map[int]time.Time{}}
+}
+
+// Get GET请求内容，path 拼接在地址之后
+func (e *Endpoints) Get(path string, v ...interface{}) (string, error) {
+    return e.Do(http.MethodGet, path, v...)
+}
+
+// Post POST请求内容，path 拼接在地址之后
+func (e *Endpoints) Post(path string, v ...interface{}) (string, error) {
+    return e.Do(http.MethodPost, path, v...)
+}
+
+// Do 按地址优先级依次请求直到成功
+func (e *Endpoints) Do(method, path string, v ...interface{}) (string, error) {
+    order := e.order()
+    if len(order) == 0 {
+        return "", errors.New("no endpoints")
+    }
+
+    var lastErr error
+    for _, i := range order {
+        body, err := doRequest(method, joinURL(e.bases[i], path), v...)
+        e.report(i, err)
+        if err == nil {
+            return body, nil
+        }
+        if !failover(err) {
+            return "", err
+        }
+        lastErr = err
+    }
+    return "", errors.Wrapf(lastErr, "all %d endpoints failed", len(order))
+}
+
+// Preferred 当前优先使用的地址
+func (e *Endpoints) Preferred() string {
+    e.mutex.Lock()
+    defer e.mutex.Unlock()
+    if len(e.bases) == 0 {
+        return ""
+    }
+    return e.bases[e.preferred]
+}
+
+// order 本次请求的地址顺序：优先地址，其余可用地址按原有顺序，最后是冷却中的地址
+func (e *Endpoints) order() []int {
+    e.mutex.Lock()
+    defer e.mutex.Unlock()
+
+    if len(e.bases) == 0 {
+        return nil
+    }
+
+    now := conf().clock.Now()
+    cooling := func(i int) bool {
+        until, ok := e.down[i]
+        return ok && now.Before(until)
+    }
+
+    var healthy, down []int
+    if !cooling(e.preferred) {
+        healthy = append(healthy, e.preferred)
+    }
+    for i := range e.bases {
+        switch {
+        case cooling(i):
+            down = append(down, i)
+        case i != e.preferred:
+            healthy = append(healthy, i)
+        }
+    }
+    return append(healthy, down...)
+}
+
+// report 记录地址请求结果
+func (e *Endpoints) report(i int, err error) {
+    e.mutex.Lock()
+    defer e.mutex.Unlock()
+
+    if err == nil {
+        e.preferred = i
+        delete(e.down, i)
+    } else if failover(err) {
+        e.down[i] = conf().clock.Now().Add(e.Cooldown)
+    }
+}
+
+// joinURL 拼接地址与路径
+func joinURL(base, path string) string {
+    if path == "" {
+        return base
+    }
+    return strings.TrimRight(base, "/") + "/" + strings.TrimLeft(path, "/")
+}
@@ -0,0 +1,169 @@
+package req
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+)
+
+// Expectation 响应断言，用于集成测试，请求在 Err 时发起
+type Expectation struct {
+    method string
+    url    string
+    args   []interface{}
+    checks []func(e *expectResult) string
+}
+
+// expectResult 断言使用的响应
+type expectResult struct {
+    res     *Response
+    body    []byte
+    latency time.Duration
+}
+
+// ExpectError 断言失败
+type ExpectError struct {
+    Method   string
+    URL      string
+    Failures []string
+}
+
+// Error 错误信息
+func (e *ExpectError) Error() string {
+    return fmt.Sprintf("expect %s %s:\n  %s", e.Method, e.URL, strings.Join(e.Failures, "\n  "))
+}
+
+// Expect 创建 GET 请求断言，如 Expect(url).Status(200).JSONPath("data.id", 42).Err()
+// 请求不经过缓存且不重试
+func Expect(url string, v ...interface{}) *Expectation {
+    return &Expectation{method: http.MethodGet, url: url, args: v}
+}
+
+// Method 设置请求方法
+func (e *Expectation) Method(method string) *Expectation {
+    e.method = method
+    return e
+}
+
+// Status 期望状态码
+func (e *Expectation) Status(code int) *Expectation {
+    return e.check(func(r *expectResult) string {
+        if r.res.StatusCode != code {
+            return fmt.Sprintf("status: want %d, got %d", code, r.res.StatusCode)
+        }
+        return ""
+    })
+}
+
+// Header 期望响应头等于 value
+func (e *Expectation) Header(key, value string) *Expectation {
+    return e.check(func(r *expectResult) string {
+        if got := r.res.Header.Get(key); got != value {
+            return fmt.Sprintf("header %s: want %q, got %q", key, value, got)
+        }
+        return ""
+    })
+}
+
+// HeaderContains 期望响应头包含 substr
+func (e *Expectation) HeaderContains(key, substr string) *Expectation {
+    return e.check(func(r *expectResult) string {
+        if got := r.res.Header.Get(key); !strings.Contains(got, substr) {
+            return fmt.Sprintf("header %s: want containing %q, got %q", key, substr, got)
+        }
+        return ""
+    })
+}
+
+// BodyContains 期望响应内容包含 substr
+func (e *Expectation) BodyContains(substr string) *Expectation {
+    return e.check(func(r *expectResult) string {
+        if !strings.Contains(string(r.body), substr) {
+            return fmt.Sprintf("body: want containing %q, got %s", substr, truncate(string(r.body), 200))
+        }
+        return ""
+    })
+}
+
+// JSONPath 期望 JSON 响应中路径对应的值等于 want，路径格式同 PageSpec，如 data.items.0.id
+// 按 JSON 编码比较，数值类型不同但值相同时视为相等
+func (e *Expectation) JSONPath(path string, want interface{}) *Expectation {
+    return e.check(func(r *expectResult) string {
+        var value interface{}
+        if err := jsoniter.Unmarshal(r.body, &value); err != nil {
+            return fmt.Sprintf("json %s: invalid json: %v", path, err)
+        }
+        got, ok := jsonPath(value, path)
+        if !ok {
+            return fmt.Sprintf("json %s: not found", path)
+        }
+
+        gotJSON, _ := jsoniter.MarshalToString(got)
+        wantJSON, _ := jsoniter.MarshalToString(want)
+        if gotJSON != wantJSON {
+            return fmt.Sprintf("json %s: want %s, got %s", path, wantJSON, gotJSON)
+        }
+        return ""
+    })
+}
+
+// MaxLatency 期望请求耗时不超过 d
+func (e *Expectation) MaxLatency(d time.Duration) *Expectation {
+    return e.check(func(r *expectResult) string {
+        if r.latency > d {
+            return fmt.Sprintf("latency: want <= %s, got %s", d, r.latency)
+        }
+        return ""
+    })
+}
+
+// check 添加断言
+func (e *Expectation) check(fn func(r *expectResult) string) *Expectation {
+    e.checks = append(e.checks, fn)
+    return e
+}
+
+// Err 发起请求并执行全部断言，失败时返回 *ExpectError，包含全部未通过的断言
+func (e *Expectation) Err() error {
+    return e.ErrContext(context.Background())
+}
+
+// ErrContext 同 Err，使用指定上下文
+func (e *Expectation) ErrContext(ctx context.Context) error {
+    start := time.Now()
+    request := Request{Method: e.method, URL: e.url}
+    res, err := request.Do(ctx, e.args...)
+    if err != nil {
+        return errors.Wrapf(err, "expect %s %s", e.method, e.url)
+    }
+
+    body, err := res.Bytes()
+    if err != nil {
+        return errors.Wrapf(err, "expect %s %s", e.method, e.url)
+    }
+
+    result := &expectResult{res: res, body: body, latency: time.Since(start)}
+    var failures []string
+    for _, check := range e.checks {
+        if failure := check(result); failure != "" {
+            failures = append(failures, failure)
+        }
+    }
+    if len(failures) > 0 {
+        return &ExpectError{Method: e.method, URL: e.url, Failures: failures}
+    }
+    return nil
+}
+
+// truncate 截断过长的字符串
+func truncate(s string, n int) string {
+    if len(s) <= n {
+        return s
+    }
+    return s[:n] + "..."
+}
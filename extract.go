@@ -0,0 +1,263 @@
+package req
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/antchfx/htmlquery"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+	"golang.org/x/net/html"
+	"gopkg.in/yaml.v3"
+)
+
+// Rule 字段提取规则，HTML 内容使用 Selector 或 XPath，JSON 内容使用 JSONPath
+type Rule struct {
+    // Field 字段名
+    Field string `json:"field" yaml:"field"`
+    // Selector CSS 选择器，Selector 与 XPath 均为空时取条目本身
+    Selector string `json:"selector" yaml:"selector"`
+    // XPath XPath 表达式，优先于 Selector
+    XPath string `json:"xpath" yaml:"xpath"`
+    // Attr 取值属性，为空时取文本
+    Attr string `json:"attr" yaml:"attr"`
+    // JSONPath 点分路径，如 data.items.0.id
+    JSONPath string `json:"json_path" yaml:"json_path"`
+    // Multiple 返回全部匹配值
+    Multiple bool `json:"multiple" yaml:"multiple"`
+    // Type 值类型 string/int/float/bool，为空时 HTML 取字符串，JSON 保留原始值
+    Type string `json:"type" yaml:"type"`
+    // Required 未匹配或值为空时返回错误
+    Required bool `json:"required" yaml:"required"`
+}
+
+// ExtractError 字段提取错误
+type ExtractError struct {
+    Field  string
+    Reason string
+}
+
+func (e *ExtractError) Error() string {
+    return "extract: field " + e.Field + ": " + e.Reason
+}
+
+// Extract 按规则从 HTML 或 JSON 内容提取字段，内容以 { 或 [ 开头时按 JSON 处理
+func Extract(body string, rules []Rule) (map[string]interface{}, error) {
+    records, err := extractRecords(body, "", "", rules)
+    if err != nil || len(records) == 0 {
+        return nil, err
+    }
+    return records[0], nil
+}
+
+// LoadRules 读取规则文件，扩展名为 .yaml/.yml 时按 YAML 解析，否则按 JSON 解析
+// 文件内容为 rules 字段下的规则列表
+func LoadRules(path string) ([]Rule, error) {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, errors.WithStack(err)
+    }
+
+    var c struct {
+        Rules []Rule `json:"rules" yaml:"rules"`
+    }
+    switch strings.ToLower(filepath.Ext(path)) {
+    case ".yaml", ".yml":
+        err = yaml.Unmarshal(data, &c)
+    default:
+        err = jsoniter.Unmarshal(data, &c)
+    }
+    if err != nil {
+        return nil, errors.Wrapf(err, "rules: %s", path)
+    }
+    return c.Rules, nil
+}
+
+// extractRecords 按条目选择器与字段规则提取记录，format 为空时根据内容识别
+func extractRecords(body, format, items string, rules []Rule) ([]Record, error) {
+    if format == "" {
+        format = "html"
+        if trimmed := strings.TrimSpace(body); strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+            format = "json"
+        }
+    }
+
+    switch format {
+    case "html":
+        return htmlRecords(body, items, rules)
+    case "json":
+        return jsonRecords(body, items, rules)
+    default:
+        return nil, errors.Errorf("extract: unsupported format %s", format)
+    }
+}
+
+// htmlRecords 按 CSS 选择器或 XPath 提取记录
+func htmlRecords(body, items string, rules []Rule) ([]Record, error) {
+    root, err := htmlquery.Parse(strings.NewReader(body))
+    if err != nil {
+        return nil, errors.WithStack(err)
+    }
+    doc := goquery.NewDocumentFromNode(root)
+
+    nodes := []*goquery.Selection{doc.Selection}
+    if items != "" {
+        nodes = nodes[:0]
+        doc.Find(items).Each(func(_ int, s *goquery.Selection) {
+            nodes = append(nodes, s)
+        })
+    }
+
+    records := make([]Record, 0, len(nodes))
+    for _, node := range nodes {
+        record := Record{}
+        for _, rule := range rules {
+            values, err := htmlValues(node, rule)
+            if err == nil {
+                err = setRuleValue(record, rule, values)
+            }
+            if err != nil {
+                return nil, err
+            }
+        }
+        records = append(records, record)
+    }
+    return records, nil
+}
+
+// htmlValues 条目中规则匹配的值
+func htmlValues(node *goquery.Selection, rule Rule) ([]interface{}, error) {
+    var values []interface{}
+    if rule.XPath != "" {
+        for _, n := range node.Nodes {
+            list, err := htmlquery.QueryAll(n, rule.XPath)
+            if err != nil {
+                return nil, &ExtractError{Field: rule.Field, Reason: err.Error()}
+            }
+            for _, m := range list {
+                values = append(values, nodeValue(m, rule.Attr))
+            }
+        }
+        return values, nil
+    }
+
+    matches := node
+    if rule.Selector != "" {
+        matches = node.Find(rule.Selector)
+    }
+    matches.Each(func(_ int, s *goquery.Selection) {
+        if rule.Attr == "" {
+            values = append(values, strings.TrimSpace(s.Text()))
+        } else {
+            value, _ := s.Attr(rule.Attr)
+            values = append(values, value)
+        }
+    })
+    return values, nil
+}
+
+// nodeValue 节点属性值或去除首尾空白的文本
+func nodeValue(n *html.Node, attr string) string {
+    if attr == "" {
+        return strings.TrimSpace(htmlquery.InnerText(n))
+    }
+    return htmlquery.SelectAttr(n, attr)
+}
+
+// jsonRecords 按点分路径提取记录
+func jsonRecords(body, items string, rules []Rule) ([]Record, error) {
+    var value interface{}
+    if err := jsoniter.UnmarshalFromString(body, &value); err != nil {
+        return nil, errors.WithStack(err)
+    }
+
+    nodes := []interface{}{value}
+    if items != "" {
+        node, ok := jsonPath(value, items)
+        if !ok {
+            return nil, nil
+        }
+        if list, ok := node.([]interface{}); ok {
+            nodes = list
+        } else {
+            nodes = []interface{}{node}
+        }
+    }
+
+    records := make([]Record, 0, len(nodes))
+    for _, node := range nodes {
+        record := Record{}
+        for _, rule := range rules {
+            var values []interface{}
+            if value, ok := jsonPath(node, rule.JSONPath); ok && value != nil {
+                if list, ok := value.([]interface{}); ok && rule.Multiple {
+                    values = list
+                } else {
+                    values = []interface{}{value}
+                }
+            }
+            if err := setRuleValue(record, rule, values); err != nil {
+                return nil, err
+            }
+        }
+        records = append(records, record)
+    }
+    return records, nil
+}
+
+// setRuleValue 转换类型后写入记录，必填字段未匹配时返回错误
+func setRuleValue(record Record, rule Rule, values []interface{}) error {
+    list := make([]interface{}, 0, len(values))
+    for _, value := range values {
+        if s, ok := value.(string); ok && s == "" {
+            continue
+        }
+        value, err := coerceValue(value, rule.Type)
+        if err != nil {
+            return &ExtractError{Field: rule.Field, Reason: err.Error()}
+        }
+        list = append(list, value)
+    }
+
+    switch {
+    case len(list) == 0 && rule.Required:
+        return &ExtractError{Field: rule.Field, Reason: "required"}
+    case rule.Multiple:
+        record[rule.Field] = list
+    case len(list) > 0:
+        record[rule.Field] = list[0]
+    }
+    return nil
+}
+
+// coerceValue 按规则类型转换值
+func coerceValue(value interface{}, kind string) (interface{}, error) {
+    if kind == "" {
+        return value, nil
+    }
+
+    s, ok := scalarString(value)
+    if !ok {
+        return nil, errors.Errorf("cannot convert %T to %s", value, kind)
+    }
+    s = strings.TrimSpace(s)
+
+    switch kind {
+    case "string":
+        return s, nil
+    case "int":
+        n, err := strconv.ParseInt(strings.ReplaceAll(s, ",", ""), 10, 64)
+        return n, errors.WithStack(err)
+    case "float":
+        n, err := strconv.ParseFloat(strings.ReplaceAll(s, ",", ""), 64)
+        return n, errors.WithStack(err)
+    case "bool":
+        b, err := strconv.ParseBool(s)
+        return b, errors.WithStack(err)
+    default:
+        return nil, errors.Errorf("unsupported type %s", kind)
+    }
+}
@@ -0,0 +1,150 @@
+package req
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// addressFailTTL 连接失败的地址标记为不可用的时长
+var addressFailTTL = time.Minute
+
+// hostAddrs 主机最近一次解析的地址与连接失败的地址
+type hostAddrs struct {
+    ips    []net.IP
+    failed map[string]time.Time
+}
+
+// addrHealth 按主机记录的地址状态
+var addrHealth = struct {
+    mutex sync.Mutex
+    hosts map[string]*hostAddrs
+}{hosts: map[string]*hostAddrs{}}
+
+// SetAddressFailover 开启连接失败时切换地址，连接错误对应的 IP 在一段时间内排到解析结果末尾，
+// 可重试的请求因连接错误失败且主机还有其他可用地址时立即改用下一个地址重新请求，不占用重试次数，
+// 没有可用地址后按 SetRetryCount 等待重试，默认关闭
+func SetAddressFailover(enable bool) {
+    updateConfig(func(c *config) { c.addressFailover = enable })
+    resetClient()
+}
+
+// healthyFirst 记录解析结果，连接失败的地址排到末尾
+func healthyFirst(host string, ips []net.IP) []net.IP {
+    addrHealth.mutex.Lock()
+    defer addrHealth.mutex.Unlock()
+    h := addrHealth.hosts[host]
+    if h == nil {
+        h = &hostAddrs{failed: map[string]time.Time{}}
+        addrHealth.hosts[host] = h
+    }
+    h.ips = ips
+
+    now := conf().clock.Now()
+    healthy := make([]net.IP, 0, len(ips))
+    var failed []net.IP
+    for _, ip := range ips {
+        if until, ok := h.failed[ip.String()]; ok && now.Before(until) {
+            failed = append(failed, ip)
+        } else {
+            delete(h.failed, ip.String())
+            healthy = append(healthy, ip)
+        }
+    }
+    return append(healthy, failed...)
+}
+
+// markFailed 标记连接失败的地址
+func markFailed(host, addr string) {
+    ip, _, err := net.SplitHostPort(addr)
+    if err != nil {
+        ip = addr
+    }
+
+    addrHealth.mutex.Lock()
+    defer addrHealth.mutex.Unlock()
+    h := addrHealth.hosts[host]
+    if h == nil {
+        h = &hostAddrs{failed: map[string]time.Time{}}
+        addrHealth.hosts[host] = h
+    }
+    h.failed[ip] = conf().clock.Now().Add(addressFailTTL)
+}
+
+// alternateAddrs 主机解析的地址数量与其中未标记失败的数量
+func alternateAddrs(host string) (total, healthy int) {
+    addrHealth.mutex.Lock()
+    defer addrHealth.mutex.Unlock()
+    h := addrHealth.hosts[host]
+    if h == nil {
+        return 0, 0
+    }
+
+    now := conf().clock.Now()
+    for _, ip := range h.ips {
+        if until, ok := h.failed[ip.String()]; !ok || !now.Before(until) {
+            healthy++
+        }
+    }
+    return len(h.ips), healthy
+}
+
+// connectionError 是否为连接错误，上下文取消与超时不属于连接错误
+func connectionError(err error) bool {
+    if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+        return false
+    }
+    var opErr *net.OpError
+    var recordErr tls.RecordHeaderError
+    return errors.As(err, &opErr) || errors.As(err, &recordErr) ||
+        errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// failoverTransport 记录连接失败地址的传输层
+type failoverTransport struct {
+    base http.RoundTripper
+}
+
+// RoundTrip 跟踪连接的地址，连接错误时标记该地址
+func (t *failoverTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+    if !conf().addressFailover {
+        return t.base.RoundTrip(r)
+    }
+
+    var (
+        mutex  sync.Mutex
+        remote string
+        host   = strings.ToLower(r.URL.Hostname())
+    )
+    trace := &httptrace.ClientTrace{
+        ConnectDone: func(network, addr string, err error) {
+            if err != nil {
+                markFailed(host, addr)
+            }
+        },
+        GotConn: func(info httptrace.GotConnInfo) {
+            mutex.Lock()
+            remote = info.Conn.RemoteAddr().String()
+            mutex.Unlock()
+        },
+    }
+
+    rep, err := t.base.RoundTrip(r.WithContext(httptrace.WithClientTrace(r.Context(), trace)))
+    if connectionError(err) {
+        mutex.Lock()
+        if remote != "" {
+            markFailed(host, remote)
+        }
+        mutex.Unlock()
+    }
+    return rep, err
+}
@@ -0,0 +1,338 @@
+package req
+
+import (
+	"bytes"
+	"encoding/xml"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/imroc/req"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+)
+
+// Feed 订阅源
+type Feed struct {
+    Title       string
+    Link        string
+    Description string
+    Updated     time.Time
+    Items       []FeedItem
+    // ETag 与 LastModified 用于条件请求
+    ETag         string
+    LastModified string
+    // NotModified 订阅源未变化，内容来自缓存
+    NotModified bool
+}
+
+// FeedItem 订阅条目
+type FeedItem struct {
+    ID        string
+    Title     string
+    Link      string
+    Summary   string
+    Content   string
+    Author    string
+    Published time.Time
+    Updated   time.Time
+}
+
+// GetFeed 获取订阅源，支持 RSS 2.0/Atom/JSON Feed
+// 设置缓存目录后会缓存订阅源内容，ETag/Last-Modified 保存在 SetValidatorStore 设置的存储中，未变化时不重复下载
+func GetFeed(url string, v ...interface{}) (*Feed, error) {
+    store := conf().validatorStore
+    name := cacheName("FEED", url)
+    var cached []byte
+    if name != "" && fileExist(name) {
+        cached, _ = readCache(name)
+    }
+
+    header := req.Header{}
+    validator, ok := store.Load(url)
+    if ok && len(cached) > 0 {
+        if validator.ETag != "" {
+            header["If-None-Match"] = validator.ETag
+        }
+        if validator.LastModified != "" {
+            header["If-Modified-Since"] = validator.LastModified
+        }
+    }
+
+    rep, err := doResponse(http.MethodGet, url, append([]interface{}{header}, v...)...)
+    if err != nil {
+        return nil, err
+    }
+
+    if rep.Response().StatusCode == http.StatusNotModified {
+        rep.Response().Body.Close()
+        if len(cached) == 0 {
+            return nil, errors.Errorf("feed not modified but no cache: %s", url)
+        }
+
+        feed, err := ParseFeed(cached)
+        if err != nil {
+            return nil, err
+        }
+        feed.ETag, feed.LastModified, feed.NotModified = validator.ETag, validator.LastModified, true
+        return feed, nil
+    }
+
+    body := rep.Bytes()
+    feed, err := ParseFeed(body)
+    if err != nil {
+        return nil, err
+    }
+    feed.ETag = rep.Response().Header.Get("ETag")
+    feed.LastModified = rep.Response().Header.Get("Last-Modified")
+
+    if name != "" {
+        if err = storeCache(name, "FEED", url, body); err != nil {
+            return nil, err
+        }
+        if validator := (Validator{ETag: feed.ETag, LastModified: feed.LastModified}); validator != (Validator{}) {
+            if err = store.Store(url, validator); err != nil {
+                return nil, err
+            }
+        }
+    }
+
+    return feed, nil
+}
+
+// ParseFeed 解析订阅源内容
+func ParseFeed(data []byte) (*Feed, error) {
+    data = bytes.TrimSpace(data)
+    if len(data) > 0 && data[0] == '{' {
+        return parseJSONFeed(data)
+    }
+
+    decoder := xml.NewDecoder(bytes.NewReader(data))
+    for {
+        token, err := decoder.Token()
+        if err != nil {
+            return nil, errors.WithStack(err)
+        }
+        if start, ok := token.(xml.StartElement); ok {
+            switch start.Name.Local {
+            case "rss":
+                return parseRSS(data)
+            case "feed":
+                return parseAtom(data)
+            default:
+                return nil, errors.Errorf("unknown feed root: %s", start.Name.Local)
+            }
+        }
+    }
+}
+
+func parseRSS(data []byte) (*Feed, error) {
+    var doc struct {
+        Channel struct {
+            Title         string `xml:"title"`
+            Link          string `xml:"link"`
+            Description   string `xml:"description"`
+            LastBuildDate string `xml:"lastBuildDate"`
+            Items         []struct {
+                GUID        string `xml:"guid"`
+                Title       string `xml:"title"`
+                Link        string `xml:"link"`
+                Description string `xml:"description"`
+                Content     string `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+                Author      string `xml:"author"`
+                Creator     string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+                PubDate     string `xml:"pubDate"`
+            } `xml:"item"`
+        } `xml:"channel"`
+    }
+
+    err := xml.Unmarshal(data, &doc)
+    if err != nil {
+        return nil, errors.WithStack(err)
+    }
+
+    feed := &Feed{
+        Title:       doc.Channel.Title,
+        Link:        doc.Channel.Link,
+        Description: doc.Channel.Description,
+        Updated:     parseFeedTime(doc.Channel.LastBuildDate),
+    }
+    for _, item := range doc.Channel.Items {
+        author := item.Author
+        if author == "" {
+            author = item.Creator
+        }
+        id := item.GUID
+        if id == "" {
+            id = item.Link
+        }
+        published := parseFeedTime(item.PubDate)
+        feed.Items = append(feed.Items, FeedItem{
+            ID:        id,
+            Title:     item.Title,
+            Link:      item.Link,
+            Summary:   item.Description,
+            Content:   item.Content,
+            Author:    author,
+            Published: published,
+            Updated:   published,
+        })
+    }
+
+    return feed, nil
+}
+
+// atomLink Atom 链接
+type atomLink struct {
+    Href string `xml:"href,attr"`
+    Rel  string `xml:"rel,attr"`
+}
+
+// atomHref 取 alternate 链接
+func atomHref(links []atomLink) string {
+    for _, link := range links {
+        if link.Rel == "" || link.Rel == "alternate" {
+            return link.Href
+        }
+    }
+    if len(links) > 0 {
+        return links[0].Href
+    }
+    return ""
+}
+
+func parseAtom(data []byte) (*Feed, error) {
+    var doc struct {
+        Title    string     `xml:"title"`
+        Subtitle string     `xml:"subtitle"`
+        Updated  string     `xml:"updated"`
+        Links    []atomLink `xml:"link"`
+        Entries  []struct {
+            ID        string     `xml:"id"`
+            Title     string     `xml:"title"`
+            Links     []atomLink `xml:"link"`
+            Summary   string     `xml:"summary"`
+            Content   string     `xml:"content"`
+            Published string     `xml:"published"`
+            Updated   string     `xml:"updated"`
+            Author    struct {
+                Name string `xml:"name"`
+            } `xml:"author"`
+        } `xml:"entry"`
+    }
+
+    err := xml.Unmarshal(data, &doc)
+    if err != nil {
+        return nil, errors.WithStack(err)
+    }
+
+    feed := &Feed{
+        Title:       doc.Title,
+        Link:        atomHref(doc.Links),
+        Description: doc.Subtitle,
+        Updated:     parseFeedTime(doc.Updated),
+    }
+    for _, entry := range doc.Entries {
+        feed.Items = append(feed.Items, FeedItem{
+            ID:        entry.ID,
+            Title:     entry.Title,
+            Link:      atomHref(entry.Links),
+            Summary:   entry.Summary,
+            Content:   entry.Content,
+            Author:    entry.Author.Name,
+            Published: parseFeedTime(entry.Published),
+            Updated:   parseFeedTime(entry.Updated),
+        })
+    }
+
+    return feed, nil
+}
+
+func parseJSONFeed(data []byte) (*Feed, error) {
+    type author struct {
+        Name string `json:"name"`
+    }
+
+    var doc struct {
+        Version     string `json:"version"`
+        Title       string `json:"title"`
+        HomePageURL string `json:"home_page_url"`
+        Description string `json:"description"`
+        Items       []struct {
+            ID            string   `json:"id"`
+            URL           string   `json:"url"`
+            Title         string   `json:"title"`
+            Summary       string   `json:"summary"`
+            ContentHTML   string   `json:"content_html"`
+            ContentText   string   `json:"content_text"`
+            DatePublished string   `json:"date_published"`
+            DateModified  string   `json:"date_modified"`
+            Author        author   `json:"author"`
+            Authors       []author `json:"authors"`
+        } `json:"items"`
+    }
+
+    err := jsoniter.Unmarshal(data, &doc)
+    if err != nil {
+        return nil, errors.WithStack(err)
+    }
+
+    if !strings.HasPrefix(doc.Version, "https://jsonfeed.org/version/") {
+        return nil, errors.Errorf("unknown json feed version: %s", doc.Version)
+    }
+
+    feed := &Feed{
+        Title:       doc.Title,
+        Link:        doc.HomePageURL,
+        Description: doc.Description,
+    }
+    for _, item := range doc.Items {
+        content := item.ContentHTML
+        if content == "" {
+            content = item.ContentText
+        }
+        name := item.Author.Name
+        if len(item.Authors) > 0 {
+            name = item.Authors[0].Name
+        }
+        updated := parseFeedTime(item.DateModified)
+        if updated.IsZero() {
+            updated = parseFeedTime(item.DatePublished)
+        }
+        if updated.After(feed.Updated) {
+            feed.Updated = updated
+        }
+        feed.Items = append(feed.Items, FeedItem{
+            ID:        item.ID,
+            Title:     item.Title,
+            Link:      item.URL,
+            Summary:   item.Summary,
+            Content:   content,
+            Author:    name,
+            Published: parseFeedTime(item.DatePublished),
+            Updated:   updated,
+        })
+    }
+
+    return feed, nil
+}
+
+// parseFeedTime 解析订阅源中常见的时间格式
+func parseFeedTime(value string) time.Time {
+    value = strings.TrimSpace(value)
+    for _, layout := range []string{
+        time.RFC3339,
+        time.RFC1123Z,
+        time.RFC1123,
+        "Mon, 2 Jan 2006 15:04:05 -0700",
+        "Mon, 2 Jan 2006 15:04:05 MST",
+        "2 Jan 2006 15:04:05 -0700",
+        "2006-01-02 15:04:05",
+        "2006-01-02",
+    } {
+        if t, err := time.Parse(layout, value); err == nil {
+            return t
+        }
+    }
+    return time.Time{}
+}
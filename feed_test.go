@@ -0,0 +1,52 @@
+package req
+
+import (
+	"net/http"
+	"testing"
+)
+
+const testRSS = `<?xml version="1.0"?>
+<rss version="2.0"><channel><title>News</title><link>http://news.example.com/</link>
+<item><guid>1</guid><title>First</title><link>http://news.example.com/1</link></item>
+</channel></rss>`
+
+func TestGetFeedConditional(t *testing.T) {
+    withCachePath(t)
+    server := NewMockServer()
+    defer server.Close()
+    var full int
+    server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if r.Header.Get("If-None-Match") == `"f1"` {
+            w.WriteHeader(http.StatusNotModified)
+            return
+        }
+        full++
+        w.Header().Set("ETag", `"f1"`)
+        w.Header().Set("Content-Type", "application/rss+xml")
+        w.Write([]byte(testRSS))
+    })
+    defer server.Install()()
+
+    url := "http://news.example.com/feed.xml"
+    feed, err := GetFeed(url)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if feed.NotModified || len(feed.Items) != 1 {
+        t.Fatalf("first fetch: not modified = %v, items = %d", feed.NotModified, len(feed.Items))
+    }
+
+    feed, err = GetFeed(url)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if !feed.NotModified || feed.ETag != `"f1"` || len(feed.Items) != 1 || feed.Items[0].Title != "First" {
+        t.Fatalf("second fetch: %+v", feed)
+    }
+    if full != 1 {
+        t.Fatalf("full downloads = %d, want 1", full)
+    }
+    if validator, ok := conf().validatorStore.Load(url); !ok || validator.ETag != `"f1"` {
+        t.Fatalf("validator not in shared store: %+v", validator)
+    }
+}
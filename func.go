@@ -1,14 +1,34 @@
 package req
 
 import (
+    "context"
+    "fmt"
     "io"
     "net/http"
-    "os"
+    neturl "net/url"
+    "strings"
+
+    "github.com/pkg/errors"
 )
 
 // Check 检查文件
 func Check(url string) (bool, error) {
-    resp, err := http.Head(url)
+    if err := ValidateURL(url); err != nil {
+        return false, err
+    }
+    done, err := track()
+    if err != nil {
+        return false, err
+    }
+    defer done()
+
+    request, err := http.NewRequest(http.MethodHead, url, nil)
+    if err != nil {
+        return false, err
+    }
+    setCommonHeader(request)
+
+    resp, err := conf().client.Do(request)
     if err != nil {
         return false, err
     }
@@ -17,24 +37,107 @@ func Check(url string) (bool, error) {
     return resp.StatusCode == http.StatusOK, nil
 }
 
-// Download 下载文件
-func Download(url string, fileName string) error {
-    resp, err := http.Get(url)
+// Download 下载文件，支持 http/https/ftp/sftp 链接，SetAllowedSchemes 设置后 ftp/sftp 需在允许的协议中
+// v 可传入 context.Context、WithResume 与 WithRemoteAuth
+// HTTP 续传时发送上次响应的 ETag/Last-Modified 作为 If-Range，校验值保存在 SetValidatorStore 设置的存储中
+func Download(url string, fileName string, v ...interface{}) error {
+    if err := validateURL(url, "ftp", "sftp"); err != nil {
+        return err
+    }
+    done, err := track()
     if err != nil {
         return err
     }
-    defer resp.Body.Close()
+    defer done()
+
+    opts, _ := splitOptions(v)
+    if opts.err != nil {
+        return opts.err
+    }
 
-    file, err := os.Create(fileName)
+    u, err := neturl.Parse(url)
     if err != nil {
         return err
     }
-    defer file.Close()
+    ctx := argContext(v)
+    switch strings.ToLower(u.Scheme) {
+    case "ftp":
+        return ftpDownload(ctx, u, fileName, opts)
+    case "sftp":
+        return sftpDownload(ctx, u, fileName, opts)
+    }
 
-    _, err = io.Copy(file, resp.Body)
+    offset := resumeOffset(fileName, opts.resume)
+    restart, err := httpDownload(ctx, url, fileName, offset)
+    if restart {
+        // 已有内容与远端文件不一致，重新下载
+        _, err = httpDownload(ctx, url, fileName, 0)
+    }
+    return err
+}
+
+// httpDownload 通过 HTTP 下载文件，offset 大于 0 时续传，已有长度超出远端文件长度时返回 restart
+func httpDownload(ctx context.Context, url, fileName string, offset int64) (restart bool, err error) {
+    store := conf().validatorStore
+    request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
     if err != nil {
-        return err
+        return false, err
+    }
+    if offset > 0 {
+        request.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+        if validator, ok := store.Load(url); ok {
+            // 弱 ETag 不能用于 If-Range
+            if validator.ETag != "" && !strings.HasPrefix(validator.ETag, "W/") {
+                request.Header.Set("If-Range", validator.ETag)
+            } else if validator.LastModified != "" {
+                request.Header.Set("If-Range", validator.LastModified)
+            }
+        }
+    }
+    setCommonHeader(request)
+
+    resp, err := streamClient().Do(request)
+    if err != nil {
+        return false, err
+    }
+    defer resp.Body.Close()
+
+    switch {
+    case offset > 0 && resp.StatusCode == http.StatusRequestedRangeNotSatisfiable:
+        // 已有长度等于远端文件长度时视为已下载完成
+        var size int64
+        if _, err := fmt.Sscanf(resp.Header.Get("Content-Range"), "bytes */%d", &size); err == nil && size == offset {
+            return false, nil
+        }
+        return true, nil
+    case offset > 0 && resp.StatusCode == http.StatusPartialContent:
+        var start int64
+        if _, err := fmt.Sscanf(resp.Header.Get("Content-Range"), "bytes %d-", &start); err != nil || start != offset {
+            return false, errors.Errorf("unexpected content range: %s", resp.Header.Get("Content-Range"))
+        }
+    case resp.StatusCode/100 == 2:
+        // 未续传、远端文件已变化或服务端不支持续传，重新下载
+        offset = 0
+    default:
+        // 错误页不写入文件
+        return false, errors.WithStack(&StatusError{StatusCode: resp.StatusCode})
+    }
+
+    validator := Validator{ETag: resp.Header.Get("ETag"), LastModified: resp.Header.Get("Last-Modified")}
+    if validator != (Validator{}) {
+        if err = store.Store(url, validator); err != nil {
+            return false, err
+        }
+    }
+    if err = checkDiskSpace(fileName, resp.ContentLength); err != nil {
+        return false, err
     }
+    file, err := openDownload(fileName, offset)
+    if err != nil {
+        return false, err
+    }
+    defer file.Close()
 
-    return nil
+    _, err = io.Copy(file, resp.Body)
+    return false, errors.WithStack(err)
 }
@@ -0,0 +1,73 @@
+package req
+
+import (
+	"bytes"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// serveFile 模拟支持 Range 与 If-Range 的文件服务
+func serveFile(t *testing.T, content, etag string) {
+    server := NewMockServer()
+    t.Cleanup(server.Close)
+    server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.Header().Set("ETag", etag)
+        http.ServeContent(w, r, "file", time.Time{}, strings.NewReader(content))
+    })
+    t.Cleanup(server.Install())
+}
+
+func TestDownloadResume(t *testing.T) {
+    tests := []struct {
+        name  string
+        local string
+        // stored 已保存的校验值，与服务端不一致时 If-Range 不生效
+        stored string
+    }{
+        {"partial", "0123", `"v1"`},
+        {"complete", "0123456789", `"v1"`},
+        {"local longer", "0123456789abc", `"v1"`},
+        {"remote changed", "abcd", `"v0"`},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            serveFile(t, "0123456789", `"v1"`)
+            url := "http://files.example.com/" + strings.ReplaceAll(tt.name, " ", "-")
+            if err := conf().validatorStore.Store(url, Validator{ETag: tt.stored}); err != nil {
+                t.Fatal(err)
+            }
+            name := filepath.Join(t.TempDir(), "file")
+            if err := os.WriteFile(name, []byte(tt.local), 0644); err != nil {
+                t.Fatal(err)
+            }
+
+            if err := Download(url, name, WithResume()); err != nil {
+                t.Fatal(err)
+            }
+            data, err := os.ReadFile(name)
+            if err != nil {
+                t.Fatal(err)
+            }
+            if !bytes.Equal(data, []byte("0123456789")) {
+                t.Fatalf("file = %q", data)
+            }
+        })
+    }
+}
+
+func TestDownloadAllowedSchemes(t *testing.T) {
+    SetAllowedSchemes("ws")
+    t.Cleanup(func() { updateConfig(func(c *config) { c.allowedSchemes = nil }) })
+
+    err := Download("ftp://files.example.com/a.txt", filepath.Join(t.TempDir(), "a.txt"))
+    var invalid *URLError
+    if !errors.As(err, &invalid) {
+        t.Fatalf("err = %v, want scheme not allowed", err)
+    }
+}
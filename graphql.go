@@ -0,0 +1,160 @@
+package req
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/imroc/req"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+)
+
+// GraphQLError GraphQL 错误
+type GraphQLError struct {
+    Message   string        `json:"message"`
+    Path      []interface{} `json:"path,omitempty"`
+    Locations []struct {
+        Line   int `json:"line"`
+        Column int `json:"column"`
+    } `json:"locations,omitempty"`
+    Extensions map[string]interface{} `json:"extensions,omitempty"`
+}
+
+// GraphQLErrors 响应中的错误列表，存在部分数据时 data 仍会被解析
+type GraphQLErrors []GraphQLError
+
+// Error 错误信息
+func (e GraphQLErrors) Error() string {
+    messages := make([]string, 0, len(e))
+    for _, item := range e {
+        messages = append(messages, item.Message)
+    }
+    return "graphql: " + strings.Join(messages, "; ")
+}
+
+// persistedQueryNotFound 服务端未缓存该查询
+func (e GraphQLErrors) persistedQueryNotFound() bool {
+    for _, item := range e {
+        if item.Message == "PersistedQueryNotFound" || item.Extensions["code"] == "PERSISTED_QUERY_NOT_FOUND" {
+            return true
+        }
+    }
+    return false
+}
+
+// WithPersistedQuery GraphQL 请求优先只发送查询哈希，服务端未缓存时再发送完整查询
+func WithPersistedQuery() Option {
+    return func(o *options) {
+        o.persistedQuery = true
+    }
+}
+
+// GraphQL 发起 GraphQL 请求并解析 data 到 result，不经过缓存
+// 响应包含 errors 时返回 GraphQLErrors；只包含 query 操作时按重试配置重试，
+// 包含 mutation 或 subscription 时与 POST 相同，需 WithUnsafeRetry 或 WithIdempotencyKey 才会重试
+func GraphQL(ctx context.Context, endpoint, query string, variables map[string]interface{}, result interface{}, v ...interface{}) error {
+    opts, _ := splitOptions(v)
+    if graphQLReadOnly(query) {
+        v = append(v[:len(v):len(v)], WithUnsafeRetry())
+    }
+
+    envelope := map[string]interface{}{"query": query}
+    if len(variables) > 0 {
+        envelope["variables"] = variables
+    }
+
+    if opts.persistedQuery {
+        envelope["extensions"] = map[string]interface{}{
+            "persistedQuery": map[string]interface{}{
+                "version":    1,
+                "sha256Hash": fmt.Sprintf("%x", sha256.Sum256([]byte(query))),
+            },
+        }
+        delete(envelope, "query")
+
+        err := graphQLDo(ctx, endpoint, envelope, result, v...)
+        if errs, ok := errors.Cause(err).(GraphQLErrors); !ok || !errs.persistedQueryNotFound() {
+            return err
+        }
+        envelope["query"] = query
+    }
+
+    return graphQLDo(ctx, endpoint, envelope, result, v...)
+}
+
+// graphQLReadOnly 查询文档是否只包含 query 操作，按顶层的操作类型判断，忽略注释与字符串
+func graphQLReadOnly(query string) bool {
+    depth := 0
+    for i := 0; i < len(query); i++ {
+        switch ch := query[i]; {
+        case ch == '#':
+            for i < len(query) && query[i] != '\n' {
+                i++
+            }
+        case ch == '"':
+            if strings.HasPrefix(query[i:], `"""`) {
+                end := strings.Index(query[i+3:], `"""`)
+                if end < 0 {
+                    return false
+                }
+                i += end + 5
+                continue
+            }
+            for i++; i < len(query) && query[i] != '"'; i++ {
+                if query[i] == '\\' {
+                    i++
+                }
+            }
+        case ch == '{' || ch == '(':
+            depth++
+        case ch == '}' || ch == ')':
+            depth--
+        case depth == 0 && (ch == '_' || ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z'):
+            j := i
+            for j < len(query) && (query[j] == '_' || query[j] >= 'a' && query[j] <= 'z' || query[j] >= 'A' && query[j] <= 'Z' || query[j] >= '0' && query[j] <= '9') {
+                j++
+            }
+            if name := query[i:j]; name == "mutation" || name == "subscription" {
+                return false
+            }
+            i = j - 1
+        }
+    }
+    return true
+}
+
+func graphQLDo(ctx context.Context, endpoint string, envelope map[string]interface{}, result interface{}, v ...interface{}) error {
+    body, err := jsoniter.Marshal(envelope)
+    if err != nil {
+        return errors.WithStack(err)
+    }
+
+    args := withHeader(v, req.Header{"Content-Type": "application/json", "Accept": "application/json"})
+    rep, err := doResponse(http.MethodPost, endpoint, append([]interface{}{ctx, body}, args...)...)
+    if err != nil {
+        return err
+    }
+
+    var res struct {
+        Data   jsoniter.RawMessage `json:"data"`
+        Errors GraphQLErrors       `json:"errors"`
+    }
+    err = jsoniter.Unmarshal(rep.Bytes(), &res)
+    if err != nil {
+        return errors.WithStack(err)
+    }
+
+    if result != nil && len(res.Data) > 0 && string(res.Data) != "null" {
+        if err = jsoniter.Unmarshal(res.Data, result); err != nil {
+            return errors.WithStack(err)
+        }
+    }
+
+    if len(res.Errors) > 0 {
+        return res.Errors
+    }
+    return nil
+}
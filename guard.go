@@ -0,0 +1,236 @@
+package req
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// HostGuard 请求目标限制，用于防止服务端请求伪造（SSRF）
+// 主机名在发送请求时检查，IP 在 DNS 解析后、建立连接前检查，重定向后的请求同样受限
+// 经代理发送的请求由代理解析 DNS，只检查主机名与 IP 字面量；不作用于 ChromeGet 与 CurlGet
+type HostGuard struct {
+    // Allow 允许的主机或 CIDR，为空时不限制，*.example.com 匹配全部子域名
+    Allow []string
+    // Deny 禁止的主机或 CIDR，优先于 Allow
+    Deny []string
+    // SafeMode 禁止回环、私有、链路本地与云元数据地址，Allow 中的 CIDR 可放行
+    SafeMode bool
+}
+
+// BlockedError 请求目标被限制
+type BlockedError struct {
+    Host   string
+    IP     net.IP
+    Reason string
+}
+
+// Error 错误信息
+func (e *BlockedError) Error() string {
+    if e.IP != nil {
+        return fmt.Sprintf("blocked host %s (%s): %s", e.Host, e.IP, e.Reason)
+    }
+    return fmt.Sprintf("blocked host %s: %s", e.Host, e.Reason)
+}
+
+// metadataNets 云元数据地址
+var metadataNets = mustCIDRs("169.254.169.254/32", "fd00:ec2::254/128", "100.100.100.200/32")
+
+// SetHostGuard 设置请求目标限制，为 nil 时取消限制
+func SetHostGuard(guard *HostGuard) error {
+    var g *hostGuard
+    if guard != nil {
+        var err error
+        if g, err = compileGuard(*guard); err != nil {
+            return err
+        }
+    }
+
+    updateConfig(func(c *config) { c.guard = g })
+    resetClient()
+    return nil
+}
+
+// hostGuard 解析后的请求目标限制
+type hostGuard struct {
+    allowHosts []string
+    allowNets  []*net.IPNet
+    denyHosts  []string
+    denyNets   []*net.IPNet
+    safeMode   bool
+}
+
+// compileGuard 解析主机与 CIDR 规则
+func compileGuard(guard HostGuard) (*hostGuard, error) {
+    g := &hostGuard{safeMode: guard.SafeMode}
+    var err error
+    if g.allowHosts, g.allowNets, err = parseHostRules(guard.Allow); err != nil {
+        return nil, err
+    }
+    if g.denyHosts, g.denyNets, err = parseHostRules(guard.Deny); err != nil {
+        return nil, err
+    }
+    return g, nil
+}
+
+// parseHostRules 拆分主机与 CIDR 规则，单个 IP 视为 /32 或 /128
+func parseHostRules(rules []string) (hosts []string, nets []*net.IPNet, err error) {
+    for _, rule := range rules {
+        rule = strings.ToLower(strings.TrimSpace(rule))
+        switch {
+        case rule == "":
+            continue
+        case strings.Contains(rule, "/"):
+            _, n, err := net.ParseCIDR(rule)
+            if err != nil {
+                return nil, nil, errors.Wrapf(err, "invalid host rule: %s", rule)
+            }
+            nets = append(nets, n)
+        case net.ParseIP(rule) != nil:
+            ip := net.ParseIP(rule)
+            bits := 8 * net.IPv6len
+            if ip.To4() != nil {
+                ip, bits = ip.To4(), 8*net.IPv4len
+            }
+            nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+        default:
+            hosts = append(hosts, strings.TrimSuffix(rule, "."))
+        }
+    }
+    return hosts, nets, nil
+}
+
+// mustCIDRs 解析内置 CIDR
+func mustCIDRs(cidrs ...string) []*net.IPNet {
+    nets := make([]*net.IPNet, 0, len(cidrs))
+    for _, cidr := range cidrs {
+        _, n, err := net.ParseCIDR(cidr)
+        if err != nil {
+            panic(err)
+        }
+        nets = append(nets, n)
+    }
+    return nets
+}
+
+// matchHost 主机名是否匹配规则
+func matchHost(host string, rules []string) bool {
+    host = strings.TrimSuffix(strings.ToLower(host), ".")
+    for _, rule := range rules {
+        if strings.HasPrefix(rule, "*.") {
+            if strings.HasSuffix(host, rule[1:]) {
+                return true
+            }
+        } else if host == rule {
+            return true
+        }
+    }
+    return false
+}
+
+// matchIP IP 是否属于网段
+func matchIP(ip net.IP, nets []*net.IPNet) bool {
+    for _, n := range nets {
+        if n.Contains(ip) {
+            return true
+        }
+    }
+    return false
+}
+
+// checkHost 检查主机名，IP 字面量同时检查 IP
+func (g *hostGuard) checkHost(host string) error {
+    if ip := net.ParseIP(host); ip != nil {
+        return g.checkIP(host, ip)
+    }
+    if matchHost(host, g.denyHosts) {
+        return &BlockedError{Host: host, Reason: "denied host"}
+    }
+    // 仅配置了 CIDR 时，主机名需解析后再检查
+    if len(g.allowHosts) > 0 && len(g.allowNets) == 0 && !matchHost(host, g.allowHosts) {
+        return &BlockedError{Host: host, Reason: "host not allowed"}
+    }
+    return nil
+}
+
+// checkIP 检查解析后的 IP
+func (g *hostGuard) checkIP(host string, ip net.IP) error {
+    if matchIP(ip, g.denyNets) {
+        return &BlockedError{Host: host, IP: ip, Reason: "denied address"}
+    }
+
+    allowed := matchIP(ip, g.allowNets)
+    if g.safeMode && !allowed && unsafeIP(ip) {
+        return &BlockedError{Host: host, IP: ip, Reason: "internal address"}
+    }
+    if (len(g.allowHosts) > 0 || len(g.allowNets) > 0) && !allowed && !matchHost(host, g.allowHosts) {
+        return &BlockedError{Host: host, IP: ip, Reason: "host not allowed"}
+    }
+    return nil
+}
+
+// unsafeIP 是否为回环、私有、链路本地或云元数据地址
+func unsafeIP(ip net.IP) bool {
+    return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+        ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() ||
+        matchIP(ip, metadataNets)
+}
+
+// dialContext 解析 DNS 并检查全部 IP 后交给 dial 建立连接，proxies 中的代理地址不检查
+// dial 通过 resolvedIPs 取得已检查的 IP，避免再次解析得到不同结果
+func (g *hostGuard) dialContext(dial func(ctx context.Context, network, addr string) (net.Conn, error), proxies []string) func(ctx context.Context, network, addr string) (net.Conn, error) {
+    return func(ctx context.Context, network, addr string) (net.Conn, error) {
+        // 经代理的请求由 guardTransport 检查目标主机
+        if containsString(proxies, addr) {
+            return dial(ctx, network, addr)
+        }
+        host, _, err := net.SplitHostPort(addr)
+        if err != nil {
+            return nil, errors.WithStack(err)
+        }
+        if err = g.checkHost(host); err != nil {
+            return nil, errors.WithStack(err)
+        }
+
+        ips, err := lookupIPs(ctx, host)
+        if err != nil {
+            return nil, err
+        }
+        for _, ip := range ips {
+            if err = g.checkIP(host, ip); err != nil {
+                return nil, errors.WithStack(err)
+            }
+        }
+        return dial(context.WithValue(ctx, resolvedKey{}, ips), network, addr)
+    }
+}
+
+// resolvedKey 上下文中已解析并检查的 IP
+type resolvedKey struct{}
+
+// resolvedIPs 上下文中已解析的 IP
+func resolvedIPs(ctx context.Context) ([]net.IP, bool) {
+    ips, ok := ctx.Value(resolvedKey{}).([]net.IP)
+    return ips, ok
+}
+
+// guardTransport 发送前检查请求主机名，覆盖重定向与代理请求
+type guardTransport struct {
+    base  http.RoundTripper
+    guard *hostGuard
+}
+
+// RoundTrip 检查并发送请求
+func (t *guardTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+    if err := t.guard.checkHost(r.URL.Hostname()); err != nil {
+        if r.Body != nil {
+            r.Body.Close()
+        }
+        return nil, errors.WithStack(err)
+    }
+    return t.base.RoundTrip(r)
+}
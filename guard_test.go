@@ -0,0 +1,102 @@
+package req
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestHostGuardCheckHost(t *testing.T) {
+    tests := []struct {
+        name    string
+        guard   HostGuard
+        host    string
+        blocked bool
+    }{
+        {"public", HostGuard{SafeMode: true}, "93.184.216.34", false},
+        {"loopback", HostGuard{SafeMode: true}, "127.0.0.1", true},
+        {"ipv6 loopback", HostGuard{SafeMode: true}, "::1", true},
+        {"link-local", HostGuard{SafeMode: true}, "169.254.10.20", true},
+        {"ipv6 link-local", HostGuard{SafeMode: true}, "fe80::1", true},
+        {"metadata", HostGuard{SafeMode: true}, "169.254.169.254", true},
+        {"private 10", HostGuard{SafeMode: true}, "10.1.2.3", true},
+        {"private 192.168", HostGuard{SafeMode: true}, "192.168.1.1", true},
+        {"unspecified", HostGuard{SafeMode: true}, "0.0.0.0", true},
+        {"ipv4-mapped loopback", HostGuard{SafeMode: true}, "::ffff:127.0.0.1", true},
+        {"ipv4-mapped private", HostGuard{SafeMode: true}, "::ffff:10.0.0.1", true},
+        {"allow cidr", HostGuard{SafeMode: true, Allow: []string{"10.0.0.0/8"}}, "10.1.2.3", false},
+        {"deny cidr", HostGuard{Deny: []string{"93.184.216.0/24"}}, "93.184.216.34", true},
+        {"deny cidr ipv4-mapped", HostGuard{Deny: []string{"93.184.216.0/24"}}, "::ffff:93.184.216.34", true},
+        {"deny host", HostGuard{Deny: []string{"*.internal"}}, "api.internal", true},
+        {"allow host", HostGuard{Allow: []string{"*.example.com"}}, "api.example.com", false},
+        {"host not allowed", HostGuard{Allow: []string{"*.example.com"}}, "example.org", true},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            g, err := compileGuard(tt.guard)
+            if err != nil {
+                t.Fatal(err)
+            }
+            err = g.checkHost(tt.host)
+            var blocked *BlockedError
+            if got := errors.As(err, &blocked); got != tt.blocked {
+                t.Fatalf("checkHost(%s) = %v, blocked %v", tt.host, err, tt.blocked)
+            }
+        })
+    }
+}
+
+func TestHostGuardDialResolved(t *testing.T) {
+    if err := SetHostIPs("internal.example.com", "10.0.0.5"); err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(func() { _ = SetHostIPs("internal.example.com") })
+
+    g, err := compileGuard(HostGuard{SafeMode: true})
+    if err != nil {
+        t.Fatal(err)
+    }
+    var dialed []string
+    dial := g.dialContext(func(ctx context.Context, network, addr string) (net.Conn, error) {
+        dialed = append(dialed, addr)
+        return nil, errors.New("dialed")
+    }, []string{"127.0.0.1:8080"})
+
+    // 主机名解析到私有地址时不建立连接
+    _, err = dial(context.Background(), "tcp", "internal.example.com:443")
+    var blocked *BlockedError
+    if !errors.As(err, &blocked) || blocked.IP.String() != "10.0.0.5" {
+        t.Fatalf("err = %v, want blocked 10.0.0.5", err)
+    }
+    // 配置的代理地址不检查
+    _, _ = dial(context.Background(), "tcp", "127.0.0.1:8080")
+    if len(dialed) != 1 || dialed[0] != "127.0.0.1:8080" {
+        t.Fatalf("dialed = %v", dialed)
+    }
+}
+
+func TestHostGuardRedirect(t *testing.T) {
+    withRetry(t, 0, 0)
+    if err := SetHostGuard(&HostGuard{SafeMode: true}); err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(func() { _ = SetHostGuard(nil) })
+
+    server := NewMockServer()
+    defer server.Close()
+    server.Route(http.MethodGet, "/redirect").Status(http.StatusFound).Header("Location", "http://127.0.0.1/admin")
+    admin := server.Route(http.MethodGet, "/admin")
+    defer server.Install()()
+
+    _, err := Get("http://api.example.com/redirect")
+    var blocked *BlockedError
+    if !errors.As(err, &blocked) || blocked.Host != "127.0.0.1" {
+        t.Fatalf("err = %v, want blocked redirect", err)
+    }
+    if admin.Calls() != 0 {
+        t.Fatal("redirect to blocked host was sent")
+    }
+}
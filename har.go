@@ -0,0 +1,525 @@
+package req
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	neturl "net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/imroc/req"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+)
+
+// HAR HAR 1.2 文件
+type HAR struct {
+    Log HARLog `json:"log"`
+}
+
+// HARLog HAR 日志
+type HARLog struct {
+    Version string     `json:"version"`
+    Creator HARCreator `json:"creator"`
+    Entries []HAREntry `json:"entries"`
+}
+
+// HARCreator HAR 生成工具
+type HARCreator struct {
+    Name    string `json:"name"`
+    Version string `json:"version"`
+}
+
+// HAREntry HAR 请求记录
+type HAREntry struct {
+    StartedDateTime string      `json:"startedDateTime"`
+    Time            float64     `json:"time"`
+    Request         HARRequest  `json:"request"`
+    Response        HARResponse `json:"response"`
+    Cache           struct{}    `json:"cache"`
+    Timings         HARTimings  `json:"timings"`
+    // Labels 请求标签，HAR 自定义字段
+    Labels map[string]string `json:"_labels,omitempty"`
+}
+
+// HARNameValue HAR 键值对
+type HARNameValue struct {
+    Name  string `json:"name"`
+    Value string `json:"value"`
+}
+
+// HARPostData HAR 请求体
+type HARPostData struct {
+    MimeType string         `json:"mimeType"`
+    Text     string         `json:"text"`
+    Params   []HARNameValue `json:"params,omitempty"`
+}
+
+// HARRequest HAR 请求
+type HARRequest struct {
+    Method      string         `json:"method"`
+    URL         string         `json:"url"`
+    HTTPVersion string         `json:"httpVersion"`
+    Headers     []HARNameValue `json:"headers"`
+    QueryString []HARNameValue `json:"queryString"`
+    Cookies     []HARNameValue `json:"cookies"`
+    PostData    *HARPostData   `json:"postData,omitempty"`
+    HeadersSize int            `json:"headersSize"`
+    BodySize    int            `json:"bodySize"`
+}
+
+// HARContent HAR 响应内容
+type HARContent struct {
+    Size     int    `json:"size"`
+    MimeType string `json:"mimeType"`
+    Text     string `json:"text,omitempty"`
+    Encoding string `json:"encoding,omitempty"`
+}
+
+// HARResponse HAR 响应
+type HARResponse struct {
+    Status      int            `json:"status"`
+    StatusText  string         `json:"statusText"`
+    HTTPVersion string         `json:"httpVersion"`
+    Headers     []HARNameValue `json:"headers"`
+    Cookies     []HARNameValue `json:"cookies"`
+    Content     HARContent     `json:"content"`
+    RedirectURL string         `json:"redirectURL"`
+    HeadersSize int            `json:"headersSize"`
+    BodySize    int            `json:"bodySize"`
+}
+
+// HARTimings HAR 各阶段耗时，单位毫秒，-1 表示不适用
+type HARTimings struct {
+    Blocked float64 `json:"blocked"`
+    DNS     float64 `json:"dns"`
+    Connect float64 `json:"connect"`
+    SSL     float64 `json:"ssl"`
+    Send    float64 `json:"send"`
+    Wait    float64 `json:"wait"`
+    Receive float64 `json:"receive"`
+}
+
+// Request 请求描述，可重复执行
+type Request struct {
+    Method string
+    URL    string
+    Header req.Header
+    Body   []byte
+}
+
+// harSkipHeaders 回放时忽略的请求头，由客户端自动生成
+var harSkipHeaders = map[string]bool{
+    "Host":              true,
+    "Content-Length":    true,
+    "Connection":        true,
+    "Accept-Encoding":   true,
+    "Transfer-Encoding": true,
+}
+
+// ImportHAR 读取 HAR 文件，返回可回放的请求列表
+func ImportHAR(path string) ([]Request, error) {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, errors.WithStack(err)
+    }
+
+    var har HAR
+    if err = jsoniter.Unmarshal(data, &har); err != nil {
+        return nil, errors.Wrapf(err, "har: %s", path)
+    }
+
+    requests := make([]Request, 0, len(har.Log.Entries))
+    for _, entry := range har.Log.Entries {
+        requests = append(requests, harToRequest(entry.Request))
+    }
+    return requests, nil
+}
+
+// harToRequest HAR 请求转换为请求描述
+func harToRequest(r HARRequest) Request {
+    request := Request{Method: r.Method, URL: r.URL, Header: req.Header{}}
+    for _, h := range r.Headers {
+        name := http.CanonicalHeaderKey(h.Name)
+        if strings.HasPrefix(h.Name, ":") || harSkipHeaders[name] {
+            continue
+        }
+        if old, ok := request.Header[name]; ok {
+            sep := ", "
+            if name == "Cookie" {
+                sep = "; "
+            }
+            request.Header[name] = old + sep + h.Value
+            continue
+        }
+        request.Header[name] = h.Value
+    }
+
+    if r.PostData != nil {
+        if r.PostData.Text != "" {
+            request.Body = []byte(r.PostData.Text)
+        } else if len(r.PostData.Params) > 0 {
+            form := neturl.Values{}
+            for _, p := range r.PostData.Params {
+                form.Add(p.Name, p.Value)
+            }
+            request.Body = []byte(form.Encode())
+        }
+        if _, ok := request.Header["Content-Type"]; !ok && r.PostData.MimeType != "" {
+            request.Header["Content-Type"] = r.PostData.MimeType
+        }
+    }
+    return request
+}
+
+// Do 执行请求，不经过缓存且不检查状态码
+func (r Request) Do(ctx context.Context, v ...interface{}) (*Response, error) {
+    args := []interface{}{ctx, r.Header}
+    if len(r.Body) > 0 {
+        args = append(args, r.Body)
+    }
+
+    rep, err := doOnce(r.Method, r.URL, append(args, v...)...)
+    if err != nil {
+        return nil, err
+    }
+    return newResponse(rep), nil
+}
+
+// ReplayHAR 按顺序回放 HAR 文件中的请求，单个请求失败时对应响应为 nil
+// 回放前调用 StartHAR 可将回放结果重新录制
+func ReplayHAR(ctx context.Context, path string, v ...interface{}) ([]*Response, []error, error) {
+    requests, err := ImportHAR(path)
+    if err != nil {
+        return nil, nil, err
+    }
+
+    responses := make([]*Response, len(requests))
+    errs := make([]error, len(requests))
+    for i, request := range requests {
+        if ctx.Err() != nil {
+            return responses, errs, errors.WithStack(ctx.Err())
+        }
+        responses[i], errs[i] = request.Do(ctx, v...)
+        if responses[i] != nil {
+            _, _ = responses[i].Bytes()
+        }
+    }
+    return responses, errs, nil
+}
+
+// defaultHARBodyLimit 默认单个内容记录上限
+const defaultHARBodyLimit = 1 << 20
+
+// harRecord HAR 记录器
+type harRecord struct {
+    path    string
+    limit   int
+    mutex   sync.Mutex
+    entries []*HAREntry
+}
+
+// StartHAR 开始记录全部请求与响应，StopHAR 时写入 HAR 文件
+// limit 为请求体与响应内容的记录上限，<=0 时为 1MB，超出部分不记录
+func StartHAR(path string, limit int) error {
+    if limit <= 0 {
+        limit = defaultHARBodyLimit
+    }
+
+    var err error
+    updateConfig(func(c *config) {
+        if c.harRecorder != nil {
+            err = errors.New("har: recording already started")
+            return
+        }
+        c.harRecorder = &harRecord{path: path, limit: limit}
+    })
+    if err != nil {
+        return err
+    }
+    resetClient()
+    return nil
+}
+
+// StopHAR 停止记录并写入 HAR 文件，未读取完的响应只记录已读取的内容
+func StopHAR() error {
+    var recorder *harRecord
+    updateConfig(func(c *config) {
+        recorder, c.harRecorder = c.harRecorder, nil
+    })
+    if recorder == nil {
+        return errors.New("har: recording not started")
+    }
+
+    resetClient()
+    return recorder.save()
+}
+
+// add 添加记录
+func (r *harRecord) add(entry *HAREntry) {
+    r.mutex.Lock()
+    r.entries = append(r.entries, entry)
+    r.mutex.Unlock()
+}
+
+// save 写入 HAR 文件
+func (r *harRecord) save() error {
+    r.mutex.Lock()
+    har := HAR{Log: HARLog{
+        Version: "1.2",
+        Creator: HARCreator{Name: "itnxs/req", Version: "1.0"},
+        Entries: make([]HAREntry, 0, len(r.entries)),
+    }}
+    for _, entry := range r.entries {
+        har.Log.Entries = append(har.Log.Entries, *entry)
+    }
+    r.mutex.Unlock()
+
+    data, err := jsoniter.MarshalIndent(har, "", "  ")
+    if err != nil {
+        return errors.WithStack(err)
+    }
+    return errors.WithStack(os.WriteFile(r.path, data, os.ModePerm))
+}
+
+// harTransport 记录请求与响应的传输层
+type harTransport struct {
+    base     http.RoundTripper
+    recorder *harRecord
+}
+
+// RoundTrip 发起请求并记录
+func (t *harTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+    timing := &harTiming{start: time.Now()}
+    entry := &HAREntry{StartedDateTime: timing.start.Format(time.RFC3339Nano), Labels: Labels(r.Context())}
+
+    r = r.WithContext(httptrace.WithClientTrace(r.Context(), timing.trace()))
+    var body *harCapture
+    if r.Body != nil && r.Body != http.NoBody {
+        body = &harCapture{ReadCloser: r.Body, limit: t.recorder.limit}
+        r.Body = body
+    }
+
+    rep, err := t.base.RoundTrip(r)
+    entry.Request = harRequest(r, body)
+    if err != nil {
+        // 失败的请求同样记录，状态码为 0
+        entry.Response = HARResponse{
+            StatusText:  err.Error(),
+            Headers:     []HARNameValue{},
+            Cookies:     []HARNameValue{},
+            HeadersSize: -1,
+            BodySize:    -1,
+        }
+        entry.Timings, entry.Time = timing.timings(time.Now())
+        t.recorder.add(entry)
+        return nil, err
+    }
+
+    entry.Request.HTTPVersion = rep.Proto
+    entry.Response = HARResponse{
+        Status:      rep.StatusCode,
+        StatusText:  http.StatusText(rep.StatusCode),
+        HTTPVersion: rep.Proto,
+        Headers:     harHeaders(rep.Header),
+        Cookies:     []HARNameValue{},
+        Content:     HARContent{MimeType: rep.Header.Get("Content-Type")},
+        RedirectURL: rep.Header.Get("Location"),
+        HeadersSize: -1,
+        BodySize:    -1,
+    }
+    for _, cookie := range rep.Cookies() {
+        entry.Response.Cookies = append(entry.Response.Cookies, HARNameValue{Name: cookie.Name, Value: cookie.Value})
+    }
+    entry.Timings, entry.Time = timing.timings(time.Now())
+    t.recorder.add(entry)
+
+    rep.Body = &harCapture{ReadCloser: rep.Body, limit: t.recorder.limit, done: func(c *harCapture) {
+        data, size := c.captured()
+        t.recorder.mutex.Lock()
+        defer t.recorder.mutex.Unlock()
+        entry.Response.Content.Size = size
+        entry.Response.Content.Text, entry.Response.Content.Encoding = harText(data)
+        entry.Response.BodySize = size
+        entry.Timings, entry.Time = timing.timings(time.Now())
+    }}
+    return rep, nil
+}
+
+// harRequest 生成 HAR 请求记录
+func harRequest(r *http.Request, body *harCapture) HARRequest {
+    request := HARRequest{
+        Method:      r.Method,
+        URL:         r.URL.String(),
+        HTTPVersion: r.Proto,
+        Headers:     harHeaders(r.Header),
+        QueryString: []HARNameValue{},
+        Cookies:     []HARNameValue{},
+        HeadersSize: -1,
+        BodySize:    0,
+    }
+    for name, values := range r.URL.Query() {
+        for _, value := range values {
+            request.QueryString = append(request.QueryString, HARNameValue{Name: name, Value: value})
+        }
+    }
+    for _, cookie := range r.Cookies() {
+        request.Cookies = append(request.Cookies, HARNameValue{Name: cookie.Name, Value: cookie.Value})
+    }
+
+    if body != nil {
+        data, size := body.captured()
+        text, _ := harText(data)
+        request.BodySize = size
+        request.PostData = &HARPostData{MimeType: r.Header.Get("Content-Type"), Text: text}
+    }
+    return request
+}
+
+// harHeaders 请求头转换为 HAR 键值对
+func harHeaders(header http.Header) []HARNameValue {
+    list := make([]HARNameValue, 0, len(header))
+    for name, values := range header {
+        for _, value := range values {
+            list = append(list, HARNameValue{Name: name, Value: value})
+        }
+    }
+    return list
+}
+
+// harText 内容为 UTF-8 文本时原样记录，否则使用 base64 编码
+func harText(data []byte) (text, encoding string) {
+    if utf8.Valid(data) {
+        return string(data), ""
+    }
+    return base64.StdEncoding.EncodeToString(data), "base64"
+}
+
+// harCapture 读取时记录内容，超过上限的部分只计数
+type harCapture struct {
+    io.ReadCloser
+    limit int
+    done  func(c *harCapture)
+
+    mutex sync.Mutex
+    data  bytes.Buffer
+    size  int
+    once  sync.Once
+}
+
+// Read 读取内容
+func (c *harCapture) Read(p []byte) (int, error) {
+    n, err := c.ReadCloser.Read(p)
+
+    c.mutex.Lock()
+    c.size += n
+    if room := c.limit - c.data.Len(); room > 0 {
+        if room > n {
+            room = n
+        }
+        c.data.Write(p[:room])
+    }
+    c.mutex.Unlock()
+
+    if err == io.EOF {
+        c.finish()
+    }
+    return n, err
+}
+
+// Close 关闭
+func (c *harCapture) Close() error {
+    err := c.ReadCloser.Close()
+    c.finish()
+    return err
+}
+
+// finish 内容读取结束
+func (c *harCapture) finish() {
+    if c.done != nil {
+        c.once.Do(func() { c.done(c) })
+    }
+}
+
+// captured 已记录的内容与读取总长度
+func (c *harCapture) captured() ([]byte, int) {
+    c.mutex.Lock()
+    defer c.mutex.Unlock()
+    return append([]byte{}, c.data.Bytes()...), c.size
+}
+
+// harTiming 请求各阶段时间点
+type harTiming struct {
+    mutex sync.Mutex
+    start time.Time
+
+    dnsStart, dnsDone         time.Time
+    connectStart, connectDone time.Time
+    tlsStart, tlsDone         time.Time
+    gotConn, wrote, firstByte time.Time
+}
+
+// trace 记录各阶段时间点的 httptrace 回调
+func (t *harTiming) trace() *httptrace.ClientTrace {
+    set := func(at *time.Time) {
+        t.mutex.Lock()
+        if at.IsZero() {
+            *at = time.Now()
+        }
+        t.mutex.Unlock()
+    }
+    return &httptrace.ClientTrace{
+        DNSStart:             func(httptrace.DNSStartInfo) { set(&t.dnsStart) },
+        DNSDone:              func(httptrace.DNSDoneInfo) { set(&t.dnsDone) },
+        ConnectStart:         func(string, string) { set(&t.connectStart) },
+        ConnectDone:          func(string, string, error) { set(&t.connectDone) },
+        TLSHandshakeStart:    func() { set(&t.tlsStart) },
+        TLSHandshakeDone:     func(tls.ConnectionState, error) { set(&t.tlsDone) },
+        GotConn:              func(httptrace.GotConnInfo) { set(&t.gotConn) },
+        WroteRequest:         func(httptrace.WroteRequestInfo) { set(&t.wrote) },
+        GotFirstResponseByte: func() { set(&t.firstByte) },
+    }
+}
+
+// timings 计算各阶段耗时与总耗时，单位毫秒
+func (t *harTiming) timings(end time.Time) (HARTimings, float64) {
+    t.mutex.Lock()
+    defer t.mutex.Unlock()
+
+    ms := func(from, to time.Time) float64 {
+        if from.IsZero() || to.IsZero() {
+            return -1
+        }
+        return float64(to.Sub(from)) / float64(time.Millisecond)
+    }
+    positive := func(v float64) float64 {
+        if v < 0 {
+            return 0
+        }
+        return v
+    }
+
+    connected := t.connectDone
+    if !t.tlsDone.IsZero() {
+        connected = t.tlsDone
+    }
+    timings := HARTimings{
+        DNS:     ms(t.dnsStart, t.dnsDone),
+        Connect: ms(t.connectStart, connected),
+        SSL:     ms(t.tlsStart, t.tlsDone),
+        Send:    positive(ms(t.gotConn, t.wrote)),
+        Wait:    positive(ms(t.wrote, t.firstByte)),
+        Receive: positive(ms(t.firstByte, end)),
+    }
+    // 等待连接的时间扣除 DNS 与建立连接耗时
+    timings.Blocked = positive(ms(t.start, t.gotConn) - positive(timings.DNS) - positive(timings.Connect))
+    return timings, ms(t.start, end)
+}
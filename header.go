@@ -0,0 +1,239 @@
+package req
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/imroc/req"
+	"github.com/pkg/errors"
+)
+
+// hasHeader 参数中是否已设置请求头
+func hasHeader(v []interface{}, key string) bool {
+    key = http.CanonicalHeaderKey(key)
+    for _, arg := range v {
+        switch h := arg.(type) {
+        case req.Header:
+            for k := range h {
+                if http.CanonicalHeaderKey(k) == key {
+                    return true
+                }
+            }
+        case http.Header:
+            if len(h.Values(key)) > 0 {
+                return true
+            }
+        }
+    }
+    return false
+}
+
+// headerValue 参数中的请求头值，未设置时返回空
+func headerValue(v []interface{}, key string) string {
+    key = http.CanonicalHeaderKey(key)
+    for _, arg := range v {
+        switch h := arg.(type) {
+        case req.Header:
+            for k, value := range h {
+                if http.CanonicalHeaderKey(k) == key {
+                    return value
+                }
+            }
+        case http.Header:
+            if value := h.Get(key); value != "" {
+                return value
+            }
+        }
+    }
+    return ""
+}
+
+// withHeader 追加参数中未设置的请求头，调用方设置的请求头优先
+func withHeader(v []interface{}, header req.Header) []interface{} {
+    h := req.Header{}
+    for key, value := range header {
+        if !hasHeader(v, key) {
+            h[key] = value
+        }
+    }
+    if len(h) == 0 {
+        return v
+    }
+    return append(v[:len(v):len(v)], h)
+}
+
+// SetCommonHeaders 设置全部请求附加的公共请求头，如认证、User-Agent、租户 ID
+// 对 Get/Post、批量请求、Download、Check、CurlGet 与 Dial 生效，单次请求设置的同名请求头优先
+func SetCommonHeaders(header req.Header) {
+    headers := make(req.Header, len(header))
+    for k, v := range header {
+        headers[k] = v
+    }
+    updateConfig(func(c *config) { c.headers = headers })
+}
+
+// withCommonHeader 追加公共请求头与 User-Agent，调用方设置的请求头优先
+func withCommonHeader(v []interface{}) []interface{} {
+    v = withHeader(v, conf().headers)
+    return withHeader(v, userAgentHeader())
+}
+
+// setCommonHeader 为标准库请求设置未设置的公共请求头与 User-Agent
+func setCommonHeader(r *http.Request) {
+    for _, h := range withCommonHeader([]interface{}{r.Header}) {
+        if header, ok := h.(req.Header); ok {
+            for k, v := range header {
+                r.Header.Set(k, v)
+            }
+        }
+    }
+}
+
+// HeaderField 请求头字段
+type HeaderField struct {
+    Key   string
+    Value string
+}
+
+// HeaderProfile 请求头模板，字段按浏览器实际发送顺序排列，Content-Type 只在有请求体时发送
+// CurlGet 按模板顺序发送；标准库客户端写出请求头时按名称排序，无法保留模板顺序
+// 模板中不包含 Accept-Encoding，以保留标准库的自动解压
+type HeaderProfile []HeaderField
+
+// Header 生成请求头
+func (p HeaderProfile) Header() req.Header {
+    header := make(req.Header, len(p))
+    for _, field := range p {
+        header[field.Key] = field.Value
+    }
+    return header
+}
+
+// requestHeader 本次请求使用的模板请求头，没有请求体时不设置 Content-Type
+func (p HeaderProfile) requestHeader(method string, v []interface{}) req.Header {
+    header := p.Header()
+    if !hasBody(method, v) {
+        for key := range header {
+            if http.CanonicalHeaderKey(key) == "Content-Type" {
+                delete(header, key)
+            }
+        }
+    }
+    return header
+}
+
+// hasBody 参数中是否有请求体
+func hasBody(method string, v []interface{}) bool {
+    for _, arg := range v {
+        switch arg.(type) {
+        case []byte, string, io.Reader:
+            return true
+        case req.Param:
+            // GET/HEAD 请求的参数放在链接中
+            if method != http.MethodGet && method != http.MethodHead {
+                return true
+            }
+        default:
+            if wrappedBody(arg) {
+                return true
+            }
+        }
+    }
+    return false
+}
+
+// curlHeaders 生成 curl 请求头参数，模板中的请求头按模板顺序在前，其他请求头按名称排序在后
+func curlHeaders(profile HeaderProfile, v []interface{}) []string {
+    values := map[string]string{}
+    var names []string
+    for _, arg := range v {
+        h, ok := arg.(req.Header)
+        if !ok {
+            continue
+        }
+        for k, value := range h {
+            // 调用方设置的请求头在前，同名时优先
+            if key := http.CanonicalHeaderKey(k); !containsString(names, key) {
+                names = append(names, key)
+                values[key] = fmt.Sprintf("%s: %v", k, value)
+            }
+        }
+    }
+    sort.Strings(names)
+
+    args := make([]string, 0, 2*len(names))
+    for _, field := range profile {
+        key := http.CanonicalHeaderKey(field.Key)
+        if value, ok := values[key]; ok {
+            args = append(args, "-H", value)
+            delete(values, key)
+        }
+    }
+    for _, key := range names {
+        if value, ok := values[key]; ok {
+            args = append(args, "-H", value)
+        }
+    }
+    return args
+}
+
+// headerProfiles 已注册的请求头模板
+var headerProfiles = map[string]HeaderProfile{
+    "chrome-desktop": {
+        {"Sec-Ch-Ua", UserAgentChromeWindows.SecCHUA},
+        {"Sec-Ch-Ua-Mobile", UserAgentChromeWindows.SecCHUAMobile},
+        {"Sec-Ch-Ua-Platform", UserAgentChromeWindows.SecCHUAPlatform},
+        {"Upgrade-Insecure-Requests", "1"},
+        {"User-Agent", UserAgentChromeWindows.Value},
+        {"Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8,application/signed-exchange;v=b3;q=0.7"},
+        {"Sec-Fetch-Site", "none"},
+        {"Sec-Fetch-Mode", "navigate"},
+        {"Sec-Fetch-User", "?1"},
+        {"Sec-Fetch-Dest", "document"},
+        {"Accept-Language", UserAgentChromeWindows.AcceptLanguage},
+    },
+    "safari-ios": {
+        {"Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"},
+        {"Sec-Fetch-Site", "none"},
+        {"Sec-Fetch-Dest", "document"},
+        {"Accept-Language", UserAgentSafariIOS.AcceptLanguage},
+        {"Sec-Fetch-Mode", "navigate"},
+        {"User-Agent", UserAgentSafariIOS.Value},
+    },
+    "googlebot": {
+        {"Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"},
+        {"From", "googlebot(at)googlebot.com"},
+        {"User-Agent", "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)"},
+    },
+    "api-json": {
+        {"Accept", "application/json"},
+        {"Content-Type", "application/json"},
+    },
+}
+
+// headerProfilesMutex 请求头模板读写锁
+var headerProfilesMutex sync.RWMutex
+
+// RegisterHeaderProfile 注册请求头模板，同名模板会被覆盖
+func RegisterHeaderProfile(name string, profile HeaderProfile) {
+    headerProfilesMutex.Lock()
+    headerProfiles[name] = profile
+    headerProfilesMutex.Unlock()
+}
+
+// WithHeaderProfile 使用请求头模板，调用方传入的请求头优先
+func WithHeaderProfile(name string) Option {
+    return func(o *options) {
+        headerProfilesMutex.RLock()
+        profile, ok := headerProfiles[name]
+        headerProfilesMutex.RUnlock()
+        if !ok {
+            o.err = errors.Errorf("unknown header profile: %s", name)
+            return
+        }
+        o.profile = profile
+    }
+}
@@ -0,0 +1,52 @@
+package req
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/imroc/req"
+)
+
+func TestCurlHeadersProfileOrder(t *testing.T) {
+    profile := HeaderProfile{
+        {"Sec-Ch-Ua", "\"Chromium\""},
+        {"User-Agent", "profile-agent"},
+        {"Accept", "text/html"},
+    }
+    v := []interface{}{req.Header{"user-agent": "caller-agent", "X-Trace": "1", "Authorization": "Bearer t"}}
+    v = withHeader(v, profile.Header())
+
+    got := curlHeaders(profile, v)
+    want := []string{
+        "-H", "Sec-Ch-Ua: \"Chromium\"",
+        "-H", "user-agent: caller-agent",
+        "-H", "Accept: text/html",
+        "-H", "Authorization: Bearer t",
+        "-H", "X-Trace: 1",
+    }
+    if !reflect.DeepEqual(got, want) {
+        t.Fatalf("curl headers = %q, want %q", got, want)
+    }
+}
+
+func TestProfileContentTypeNeedsBody(t *testing.T) {
+    profile := headerProfiles["api-json"]
+    tests := []struct {
+        method string
+        args   []interface{}
+        want   bool
+    }{
+        {http.MethodGet, nil, false},
+        {http.MethodHead, nil, false},
+        {http.MethodGet, []interface{}{req.Param{"q": "x"}}, false},
+        {http.MethodPost, []interface{}{[]byte(`{}`)}, true},
+        {http.MethodPut, []interface{}{req.BodyJSON(map[string]int{"a": 1})}, true},
+    }
+    for _, tt := range tests {
+        _, ok := profile.requestHeader(tt.method, tt.args)["Content-Type"]
+        if ok != tt.want {
+            t.Errorf("%s %v: Content-Type set = %v, want %v", tt.method, tt.args, ok, tt.want)
+        }
+    }
+}
@@ -0,0 +1,120 @@
+package req
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/imroc/req"
+	"github.com/pkg/errors"
+)
+
+// WithHedge 请求在 delay 内未返回时发起一次备份请求，取先成功的结果并取消另一个
+// 只用于幂等方法与携带 Idempotency-Key 的请求，请求体为 io.Reader 时无法重复发送，不启用备份请求
+func WithHedge(delay time.Duration) Option {
+    return func(o *options) {
+        o.hedgeDelay = delay
+    }
+}
+
+// canHedge 请求是否可以重复发送，非幂等方法需携带幂等键，避免重复产生副作用
+func canHedge(method string, args []interface{}) bool {
+    if !idempotentMethod(method) && !hasHeader(args, idempotencyHeader) {
+        return false
+    }
+    for _, arg := range args {
+        if _, ok := arg.(io.Reader); ok {
+            return false
+        }
+    }
+    return true
+}
+
+// cancelBody 关闭响应时释放请求上下文
+type cancelBody struct {
+    io.ReadCloser
+    cancel context.CancelFunc
+}
+
+// Close 关闭响应
+func (b *cancelBody) Close() error {
+    defer b.cancel()
+    return b.ReadCloser.Close()
+}
+
+// doHedged 发起带备份的请求
+func doHedged(method, url string, delay time.Duration, args []interface{}) (*req.Resp, error) {
+    type result struct {
+        rep    *req.Resp
+        err    error
+        cancel context.CancelFunc
+    }
+
+    parent, index := context.Background(), -1
+    for i, arg := range args {
+        if ctx, ok := arg.(context.Context); ok {
+            parent, index = ctx, i
+        }
+    }
+
+    var (
+        results  = make(chan result, 2)
+        launched int
+        pending  int
+    )
+    launch := func() {
+        ctx, cancel := context.WithCancel(parent)
+        attempt := append([]interface{}{}, args...)
+        if index >= 0 {
+            attempt[index] = ctx
+        } else {
+            attempt = append(attempt, ctx)
+        }
+
+        launched++
+        pending++
+        go func() {
+            rep, err := req.Do(method, url, attempt...)
+            results <- result{rep: rep, err: err, cancel: cancel}
+        }()
+    }
+
+    // discard 释放未被采用的请求
+    discard := func(n int) {
+        go func() {
+            for i := 0; i < n; i++ {
+                res := <-results
+                res.cancel()
+                if res.err == nil {
+                    res.rep.Response().Body.Close()
+                }
+            }
+        }()
+    }
+
+    launch()
+    timer := time.NewTimer(delay)
+    defer timer.Stop()
+
+    for {
+        select {
+        case <-timer.C:
+            if launched == 1 {
+                launch()
+            }
+        case res := <-results:
+            pending--
+            if res.err == nil {
+                response := res.rep.Response()
+                response.Body = &cancelBody{ReadCloser: response.Body, cancel: res.cancel}
+                discard(pending)
+                return res.rep, nil
+            }
+
+            res.cancel()
+            if pending == 0 {
+                return nil, errors.WithStack(res.err)
+            }
+        }
+    }
+}
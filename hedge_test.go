@@ -0,0 +1,38 @@
+package req
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestHedgeOnlyIdempotent(t *testing.T) {
+    SetAutoIdempotencyKey(false)
+    t.Cleanup(func() { SetAutoIdempotencyKey(true) })
+    server := NewMockServer()
+    defer server.Close()
+    slow := server.Route("", "/slow").Delay(time.Millisecond * 200).Body("ok")
+    defer server.Install()()
+
+    tests := []struct {
+        method string
+        args   []interface{}
+        calls  int
+    }{
+        {http.MethodGet, nil, 2},
+        {http.MethodPost, nil, 1},
+        {http.MethodPost, []interface{}{WithIdempotencyKey("order-1")}, 2},
+    }
+    for _, tt := range tests {
+        before := slow.Calls()
+        args := append([]interface{}{WithHedge(time.Millisecond * 20)}, tt.args...)
+        if _, err := doRequest(tt.method, "http://api.example.com/slow", args...); err != nil {
+            t.Fatal(err)
+        }
+        // 等待被取消的备份请求到达服务端
+        time.Sleep(time.Millisecond * 50)
+        if calls := slow.Calls() - before; calls != tt.calls {
+            t.Errorf("%s %v: calls = %d, want %d", tt.method, tt.args, calls, tt.calls)
+        }
+    }
+}
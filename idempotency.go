@@ -0,0 +1,40 @@
+package req
+
+import (
+	"net/http"
+
+	"github.com/imroc/req"
+)
+
+// idempotencyHeader 幂等键请求头
+const idempotencyHeader = "Idempotency-Key"
+
+// SetAutoIdempotencyKey 设置是否为 POST/PATCH 请求自动生成 Idempotency-Key
+// 自动生成的幂等键不会使请求被重试，需要重试时使用 WithIdempotencyKey 或 WithUnsafeRetry
+func SetAutoIdempotencyKey(enable bool) {
+    updateConfig(func(c *config) { c.autoIdempotencyKey = enable })
+}
+
+// WithIdempotencyKey 指定本次请求的 Idempotency-Key，重试时保持不变
+func WithIdempotencyKey(key string) Option {
+    return func(o *options) {
+        o.idempotencyKey = key
+    }
+}
+
+// withIdempotencyKey 为一次逻辑请求设置幂等键，调用方已设置请求头时不覆盖
+func withIdempotencyKey(method string, v []interface{}) []interface{} {
+    if hasHeader(v, idempotencyHeader) {
+        return v
+    }
+
+    opts, _ := splitOptions(v)
+    key := opts.idempotencyKey
+    if key == "" && conf().autoIdempotencyKey && (method == http.MethodPost || method == http.MethodPatch) {
+        key = newUUID()
+    }
+    if key == "" {
+        return v
+    }
+    return withHeader(v, req.Header{idempotencyHeader: key})
+}
@@ -0,0 +1,105 @@
+package req
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// batchWindow 迭代批量请求中已读取未完成的最大条目数量
+var batchWindow = 1000
+
+// URLIterator 链接迭代器，ok 为 false 时结束，可用于从文件或数据库游标逐条读取链接
+type URLIterator func() (url string, ok bool, err error)
+
+// URLsFromSlice 切片链接迭代器
+func URLsFromSlice(urls []string) URLIterator {
+    i := 0
+    return func() (string, bool, error) {
+        if i >= len(urls) {
+            return "", false, nil
+        }
+        i++
+        return urls[i-1], true, nil
+    }
+}
+
+// URLsFromReader 按行读取链接，忽略空行与 # 开头的注释行
+func URLsFromReader(r io.Reader) URLIterator {
+    scanner := bufio.NewScanner(r)
+    scanner.Buffer(make([]byte, 64<<10), 1<<20)
+    return func() (string, bool, error) {
+        for scanner.Scan() {
+            line := strings.TrimSpace(scanner.Text())
+            if line == "" || strings.HasPrefix(line, "#") {
+                continue
+            }
+            return line, true, nil
+        }
+        return "", false, errors.WithStack(scanner.Err())
+    }
+}
+
+// BatchEach 从迭代器逐条读取链接批量请求，每完成一条回调 fn，不去重
+// 最多预读 1000 条未完成的链接，内存占用与链接总数无关；fn 会被并发调用
+func BatchEach(ctx context.Context, next URLIterator, fn func(url, body string, err error), v ...interface{}) error {
+    opts, _ := splitOptions(v)
+    return streamBatch(ctx, next, opts, func(ctx context.Context, url string) error {
+        body, err := Get(url, append([]interface{}{ctx}, v...)...)
+        if ctx.Err() != nil {
+            return err
+        }
+        if fn != nil {
+            fn(url, body, err)
+        }
+        return err
+    })
+}
+
+// streamBatch 从迭代器读取链接并调度执行，返回前等待已调度的条目完成
+// 超出时间预算或失败率阈值时停止读取并返回 ErrBudgetExceeded/ErrErrorRateExceeded
+func streamBatch(ctx context.Context, next URLIterator, opts *options, run func(ctx context.Context, url string) error) error {
+    var (
+        wg     sync.WaitGroup
+        sem    = make(chan struct{}, batchWindow)
+        budget = newBatchBudget(ctx, opts)
+    )
+    defer budget.stop()
+    defer wg.Wait()
+
+    for i := 0; ; i++ {
+        if budget.expired() {
+            return budget.err()
+        }
+        if ctx.Err() != nil {
+            return errors.WithStack(ctx.Err())
+        }
+        url, ok, err := next()
+        if err != nil || !ok {
+            return err
+        }
+
+        select {
+        case sem <- struct{}{}:
+        case <-ctx.Done():
+            return errors.WithStack(ctx.Err())
+        }
+        wg.Add(1)
+        getScheduler().submit(newBatchItem(i, url, opts.priority, func() error {
+            defer func() {
+                <-sem
+                wg.Done()
+            }()
+            if budget.expired() || budget.ctx.Err() != nil {
+                return nil
+            }
+            err := run(budget.ctx, url)
+            budget.record(err)
+            return err
+        }))
+    }
+}
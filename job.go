@@ -0,0 +1,341 @@
+package req
+
+import (
+	"context"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+// 任务条目状态
+const (
+    JobPending = "pending"
+    JobDone    = "done"
+    JobFailed  = "failed"
+)
+
+var (
+    // jobChunkSize 每次调度的待处理条目数量
+    jobChunkSize = 1000
+
+    bucketMeta  = []byte("meta")
+    bucketItems = []byte("items")
+    bucketIndex = []byte("index")
+)
+
+// SetJobPath 设置任务存储目录
+func SetJobPath(dir string) {
+    updateConfig(func(c *config) { c.jobPath = dir })
+}
+
+// JobItem 任务条目
+type JobItem struct {
+    URL       string    `json:"url"`
+    State     string    `json:"state"`
+    Error     string    `json:"error,omitempty"`
+    UpdatedAt time.Time `json:"updated_at"`
+}
+
+// JobStatus 任务进度
+type JobStatus struct {
+    ID        string
+    Total     int
+    Pending   int
+    Done      int
+    Failed    int
+    CreatedAt time.Time
+}
+
+// Job 可恢复的批量任务，待处理与已完成的链接保存在磁盘中
+type Job struct {
+    ID string
+    db *bolt.DB
+}
+
+// jobFile 任务存储文件
+func jobFile(id string) string {
+    return filepath.Join(conf().jobPath, id+".db")
+}
+
+// NewJob 创建任务，同名任务已存在时返回错误
+func NewJob(id string, urls []string) (*Job, error) {
+    if fileExist(jobFile(id)) {
+        return nil, errors.Errorf("job already exists: %s", id)
+    }
+    if err := os.MkdirAll(conf().jobPath, os.ModePerm); err != nil {
+        return nil, errors.WithStack(err)
+    }
+
+    job, err := openJob(id)
+    if err != nil {
+        return nil, err
+    }
+
+    err = job.db.Update(func(tx *bolt.Tx) error {
+        meta, err := tx.CreateBucketIfNotExists(bucketMeta)
+        if err != nil {
+            return err
+        }
+        created, _ := time.Now().MarshalText()
+        return meta.Put([]byte("created_at"), created)
+    })
+    if err == nil {
+        err = job.Add(urls...)
+    }
+    if err != nil {
+        job.Close()
+        return nil, errors.WithStack(err)
+    }
+    return job, nil
+}
+
+// Resume 打开已有任务，继续处理未完成的链接
+func Resume(id string) (*Job, error) {
+    if !fileExist(jobFile(id)) {
+        return nil, errors.Errorf("job not found: %s", id)
+    }
+    return openJob(id)
+}
+
+// Jobs 已保存的任务列表
+func Jobs() ([]string, error) {
+    files, err := filepath.Glob(filepath.Join(conf().jobPath, "*.db"))
+    if err != nil {
+        return nil, errors.WithStack(err)
+    }
+
+    ids := make([]string, 0, len(files))
+    for _, file := range files {
+        ids = append(ids, strings.TrimSuffix(filepath.Base(file), ".db"))
+    }
+    return ids, nil
+}
+
+// RemoveJob 删除任务
+func RemoveJob(id string) error {
+    return fileRemove(jobFile(id))
+}
+
+func openJob(id string) (*Job, error) {
+    db, err := bolt.Open(jobFile(id), 0600, &bolt.Options{Timeout: time.Second})
+    if err != nil {
+        return nil, errors.WithStack(err)
+    }
+
+    err = db.Update(func(tx *bolt.Tx) error {
+        for _, name := range [][]byte{bucketMeta, bucketItems, bucketIndex} {
+            if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+                return err
+            }
+        }
+        return nil
+    })
+    if err != nil {
+        db.Close()
+        return nil, errors.WithStack(err)
+    }
+
+    return &Job{ID: id, db: db}, nil
+}
+
+// Close 关闭任务
+func (j *Job) Close() error {
+    return errors.WithStack(j.db.Close())
+}
+
+// Add 添加待处理链接，已存在的链接会被忽略
+func (j *Job) Add(urls ...string) error {
+    return errors.WithStack(j.db.Update(func(tx *bolt.Tx) error {
+        items, index := tx.Bucket(bucketItems), tx.Bucket(bucketIndex)
+        now := time.Now()
+        for _, url := range urls {
+            if index.Get([]byte(url)) != nil {
+                continue
+            }
+
+            seq, err := items.NextSequence()
+            if err != nil {
+                return err
+            }
+            key := make([]byte, 8)
+            binary.BigEndian.PutUint64(key, seq)
+
+            data, _ := jsoniter.Marshal(JobItem{URL: url, State: JobPending, UpdatedAt: now})
+            if err = items.Put(key, data); err != nil {
+                return err
+            }
+            if err = index.Put([]byte(url), key); err != nil {
+                return err
+            }
+        }
+        return nil
+    }))
+}
+
+// Items 按状态查询任务条目，state 为空时返回全部
+func (j *Job) Items(state string) ([]JobItem, error) {
+    var list []JobItem
+    err := j.db.View(func(tx *bolt.Tx) error {
+        return tx.Bucket(bucketItems).ForEach(func(k, v []byte) error {
+            var item JobItem
+            if err := jsoniter.Unmarshal(v, &item); err != nil {
+                return err
+            }
+            if state == "" || item.State == state {
+                list = append(list, item)
+            }
+            return nil
+        })
+    })
+    return list, errors.WithStack(err)
+}
+
+// Status 任务进度
+func (j *Job) Status() (JobStatus, error) {
+    status := JobStatus{ID: j.ID}
+    err := j.db.View(func(tx *bolt.Tx) error {
+        if created := tx.Bucket(bucketMeta).Get([]byte("created_at")); created != nil {
+            _ = status.CreatedAt.UnmarshalText(created)
+        }
+        return tx.Bucket(bucketItems).ForEach(func(k, v []byte) error {
+            var item JobItem
+            if err := jsoniter.Unmarshal(v, &item); err != nil {
+                return err
+            }
+            status.Total++
+            switch item.State {
+            case JobPending:
+                status.Pending++
+            case JobDone:
+                status.Done++
+            case JobFailed:
+                status.Failed++
+            }
+            return nil
+        })
+    })
+    return status, errors.WithStack(err)
+}
+
+// RetryFailed 将失败条目重新标记为待处理
+func (j *Job) RetryFailed() error {
+    return errors.WithStack(j.db.Update(func(tx *bolt.Tx) error {
+        items := tx.Bucket(bucketItems)
+        updates := make(map[string][]byte)
+        err := items.ForEach(func(k, v []byte) error {
+            var item JobItem
+            if err := jsoniter.Unmarshal(v, &item); err != nil {
+                return err
+            }
+            if item.State == JobFailed {
+                item.State, item.Error, item.UpdatedAt = JobPending, "", time.Now()
+                updates[string(k)], _ = jsoniter.Marshal(item)
+            }
+            return nil
+        })
+        if err != nil {
+            return err
+        }
+
+        for k, data := range updates {
+            if err = items.Put([]byte(k), data); err != nil {
+                return err
+            }
+        }
+        return nil
+    }))
+}
+
+// Run 处理全部待处理链接，每完成一条回调 fn 后记录状态，中断后可通过 Resume 继续
+// 回调在状态写入前执行，进程异常退出时同一链接可能被再次回调
+func (j *Job) Run(ctx context.Context, fn func(url, body string, err error), v ...interface{}) error {
+    opts, _ := splitOptions(v)
+    var after []byte
+    for {
+        if ctx.Err() != nil {
+            return errors.WithStack(ctx.Err())
+        }
+
+        keys, urls, err := j.pending(after, jobChunkSize)
+        if err != nil {
+            return err
+        }
+        if len(keys) == 0 {
+            return nil
+        }
+        after = keys[len(keys)-1]
+
+        var (
+            wg    sync.WaitGroup
+            items = make([]*batchItem, 0, len(keys))
+        )
+        for i := range keys {
+            key, url := keys[i], urls[i]
+            wg.Add(1)
+            items = append(items, newBatchItem(i, url, opts.priority, func() error {
+                defer wg.Done()
+                if ctx.Err() != nil {
+                    return nil
+                }
+
+                body, err := Get(url, append([]interface{}{ctx}, v...)...)
+                if ctx.Err() != nil {
+                    return err
+                }
+                if fn != nil {
+                    fn(url, body, err)
+                }
+                j.mark(key, url, err)
+                return err
+            }))
+        }
+
+        getScheduler().submit(items...)
+        wg.Wait()
+    }
+}
+
+// pending 读取 after 之后的待处理条目
+func (j *Job) pending(after []byte, limit int) (keys [][]byte, urls []string, err error) {
+    err = j.db.View(func(tx *bolt.Tx) error {
+        cursor := tx.Bucket(bucketItems).Cursor()
+        k, v := cursor.First()
+        if after != nil {
+            if k, v = cursor.Seek(after); k != nil && string(k) == string(after) {
+                k, v = cursor.Next()
+            }
+        }
+
+        for ; k != nil && len(keys) < limit; k, v = cursor.Next() {
+            var item JobItem
+            if err := jsoniter.Unmarshal(v, &item); err != nil {
+                return err
+            }
+            if item.State == JobPending {
+                keys = append(keys, append([]byte{}, k...))
+                urls = append(urls, item.URL)
+            }
+        }
+        return nil
+    })
+    return keys, urls, errors.WithStack(err)
+}
+
+// mark 记录条目处理结果
+func (j *Job) mark(key []byte, url string, err error) {
+    item := JobItem{URL: url, State: JobDone, UpdatedAt: time.Now()}
+    if err != nil {
+        item.State, item.Error = JobFailed, err.Error()
+    }
+    data, _ := jsoniter.Marshal(item)
+    _ = j.db.Batch(func(tx *bolt.Tx) error {
+        return tx.Bucket(bucketItems).Put(key, data)
+    })
+}
@@ -0,0 +1,39 @@
+package req
+
+import "context"
+
+// labelsKey 请求上下文中的标签
+type labelsKey struct{}
+
+// WithLabels 附加请求标签，如任务 ID、租户、来源，传递到回调、HAR 记录与批量结果，多次设置时合并
+func WithLabels(labels map[string]string) Option {
+    return func(o *options) {
+        merged := make(map[string]string, len(o.labels)+len(labels))
+        for k, v := range o.labels {
+            merged[k] = v
+        }
+        for k, v := range labels {
+            merged[k] = v
+        }
+        o.labels = merged
+    }
+}
+
+// WithLabel 附加单个请求标签
+func WithLabel(key, value string) Option {
+    return WithLabels(map[string]string{key: value})
+}
+
+// Labels 请求上下文中的标签，可在自定义 Transport 中通过 r.Context() 读取
+func Labels(ctx context.Context) map[string]string {
+    labels, _ := ctx.Value(labelsKey{}).(map[string]string)
+    return labels
+}
+
+// withLabels 将标签写入请求上下文
+func withLabels(args []interface{}, labels map[string]string) []interface{} {
+    if len(labels) == 0 {
+        return args
+    }
+    return withContextValue(args, labelsKey{}, labels)
+}
@@ -0,0 +1,153 @@
+package req
+
+import (
+	"net"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+)
+
+// localIndex SetLocalAddrs 轮流使用的下标
+var localIndex uint32
+
+// WithLocalAddr 本次请求绑定本地 IP 或网卡，网卡使用第一个 IPv4 地址，没有时使用第一个 IPv6 地址
+// 同时作用于直连与连接代理，目标地址需与本地地址协议族一致
+func WithLocalAddr(addr string) Option {
+    ip, err := resolveLocalAddr(addr)
+    return func(o *options) {
+        if err != nil {
+            o.err = err
+            return
+        }
+        o.localAddr = ip
+    }
+}
+
+// SetLocalAddrs 设置轮流绑定的本地 IP 或网卡，未使用 WithLocalAddr 的请求依次使用，为空时取消
+func SetLocalAddrs(addrs ...string) error {
+    ips := make([]net.IP, 0, len(addrs))
+    for _, addr := range addrs {
+        ip, err := resolveLocalAddr(addr)
+        if err != nil {
+            return err
+        }
+        ips = append(ips, ip)
+    }
+
+    updateConfig(func(c *config) { c.localAddrs = ips })
+    return nil
+}
+
+// resolveLocalAddr 解析本地 IP 或网卡名
+func resolveLocalAddr(addr string) (net.IP, error) {
+    if ip := net.ParseIP(addr); ip != nil {
+        return ip, nil
+    }
+
+    iface, err := net.InterfaceByName(addr)
+    if err != nil {
+        return nil, errors.Wrapf(err, "local addr %s", addr)
+    }
+    addrs, err := iface.Addrs()
+    if err != nil {
+        return nil, errors.Wrapf(err, "local addr %s", addr)
+    }
+
+    var v6 net.IP
+    for _, a := range addrs {
+        ipNet, ok := a.(*net.IPNet)
+        if !ok || ipNet.IP.IsLinkLocalUnicast() {
+            continue
+        }
+        if ipNet.IP.To4() != nil {
+            return ipNet.IP, nil
+        }
+        if v6 == nil {
+            v6 = ipNet.IP
+        }
+    }
+    if v6 == nil {
+        return nil, errors.Errorf("local addr %s: no usable address", addr)
+    }
+    return v6, nil
+}
+
+// requestLocalAddr 本次请求绑定的本地地址，未设置时为 nil
+func requestLocalAddr(opts *options) net.IP {
+    if opts.localAddr != nil {
+        return opts.localAddr
+    }
+    ips := conf().localAddrs
+    if len(ips) == 0 {
+        return nil
+    }
+    return ips[int(atomic.AddUint32(&localIndex, 1)-1)%len(ips)]
+}
+
+// requestClient 本次请求使用的客户端，绑定本地地址、指定 TLS 指纹或认证时使用对应连接池的客户端
+// 参数中已有客户端时以其为基础，共用 Cookie，都未设置且未设置超时时返回 nil 使用参数中的客户端
+// 参数中的客户端使用自定义传输层时保留其传输层，服务端认证在外层完成，不支持绑定本地地址、TLS 指纹与代理认证
+func requestClient(opts *options, args []interface{}) (*http.Client, error) {
+    c := conf()
+    base := c.client
+    custom := false
+    for _, arg := range args {
+        if client, ok := arg.(*http.Client); ok {
+            base, custom = client, true
+        }
+    }
+    // 按配置创建的传输层最外层为 editTransport
+    if _, builtin := base.Transport.(*editTransport); custom && base.Transport != nil && !builtin {
+        return customClient(base, opts)
+    }
+
+    key := defaultKey(c)
+    if local := requestLocalAddr(opts); local != nil {
+        key.local = local.String()
+    }
+    if opts.tlsFingerprint != nil {
+        key.fingerprint = *opts.tlsFingerprint
+    }
+    if opts.serverAuth != nil {
+        key.serverAuth = opts.serverAuth
+    }
+    if opts.proxyAuth != nil {
+        key.proxyAuth = opts.proxyAuth
+    }
+
+    if opts.timeout <= 0 && key == defaultKey(c) {
+        if custom {
+            return nil, nil
+        }
+        return base, nil
+    }
+
+    client := *base
+    if opts.timeout > 0 {
+        client.Timeout = opts.timeout
+    }
+    if key != defaultKey(c) {
+        client.Transport = c.clients.transport(key)
+    }
+    return &client, nil
+}
+
+// customClient 以调用方传输层为基础的客户端，不使用 SetLocalAddrs 轮流绑定的本地地址
+func customClient(base *http.Client, opts *options) (*http.Client, error) {
+    if opts.localAddr != nil || opts.tlsFingerprint != nil || opts.proxyAuth != nil {
+        return nil, errors.New("WithLocalAddr, WithTLSFingerprint and WithProxyAuth cannot be used with a custom transport")
+    }
+    if opts.timeout <= 0 && opts.serverAuth == nil {
+        return nil, nil
+    }
+
+    client := *base
+    if opts.timeout > 0 {
+        client.Timeout = opts.timeout
+    }
+    if auth := opts.serverAuth; auth != nil {
+        client.Transport = &negotiateTransport{base: base.Transport, auth: auth}
+    }
+    return &client, nil
+}
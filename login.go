@@ -0,0 +1,196 @@
+package req
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/cookiejar"
+	neturl "net/url"
+	"sync"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/pkg/errors"
+)
+
+// loginKey 上下文中标记登录请求，登录请求不触发重新登录
+type loginKey struct{}
+
+// formLogin 表单登录信息
+type formLogin struct {
+    url    *neturl.URL
+    fields map[string]string
+    check  func(body string) bool
+    csrf   string
+    v      []interface{}
+
+    mutex sync.Mutex
+    // at 最近一次登录成功的时间
+    at time.Time
+}
+
+// WithCSRFSelector 设置 LoginForm 提取 CSRF 令牌的 CSS 选择器，
+// 匹配元素的 name 属性为字段名，value 或 content 属性为令牌值
+func WithCSRFSelector(selector string) Option {
+    return func(o *options) {
+        o.csrfSelector = selector
+    }
+}
+
+// LoginForm 表单登录，请求登录页提取表单隐藏字段与 CSRF 令牌，合并 fields 后提交到表单地址
+// 会话 Cookie 保存在客户端 Cookie 中，之后同一主机的 GET/POST 请求被重定向到登录页时自动重新登录并重试一次
+// successCheck 判断提交后的响应内容是否登录成功，为 nil 时以未停留在登录页为成功，v 为登录请求的参数
+func LoginForm(ctx context.Context, loginURL string, fields map[string]string, successCheck func(body string) bool, v ...interface{}) error {
+    u, err := neturl.Parse(loginURL)
+    if err != nil {
+        return errors.WithStack(err)
+    }
+    opts, _ := splitOptions(v)
+    if opts.err != nil {
+        return opts.err
+    }
+
+    if conf().jar == nil {
+        jar, err := cookiejar.New(nil)
+        if err != nil {
+            return errors.WithStack(err)
+        }
+        updateConfig(func(c *config) {
+            if c.jar == nil {
+                c.jar = jar
+            }
+        })
+        resetClient()
+    }
+
+    l := &formLogin{url: u, fields: fields, check: successCheck, csrf: opts.csrfSelector, v: v}
+    if err = l.login(ctx); err != nil {
+        return err
+    }
+
+    updateConfig(func(c *config) {
+        logins := make(map[string]*formLogin, len(c.logins)+1)
+        for host, login := range c.logins {
+            logins[host] = login
+        }
+        logins[u.Host] = l
+        c.logins = logins
+    })
+    return nil
+}
+
+// login 请求登录页并提交表单
+func (l *formLogin) login(ctx context.Context) error {
+    args := withContextValue(append([]interface{}{ctx}, l.v...), loginKey{}, true)
+    rep, err := doResponse(http.MethodGet, l.url.String(), args...)
+    if err != nil {
+        return errors.Wrap(err, "login")
+    }
+    opts, _ := splitOptions(args)
+    page, err := readBody(rep.Response(), opts)
+    if err != nil {
+        return errors.Wrap(err, "login")
+    }
+
+    action, form, err := l.form(rep.Response().Request.URL, page)
+    if err != nil {
+        return err
+    }
+    rep, err = doResponse(http.MethodPost, action, append(args, FormBody(form))...)
+    if err != nil {
+        return errors.Wrap(err, "login")
+    }
+    body, err := readBody(rep.Response(), opts)
+    if err != nil {
+        return errors.Wrap(err, "login")
+    }
+
+    ok := !l.matches(rep.Response().Request.URL)
+    if l.check != nil {
+        ok = l.check(string(body))
+    }
+    if !ok {
+        return errors.Errorf("login: %s: authentication failed", l.url)
+    }
+    l.at = time.Now()
+    return nil
+}
+
+// form 登录表单的提交地址与字段，表单为包含 fields 中任一字段的 form 元素，未找到时使用第一个 form 元素
+func (l *formLogin) form(base *neturl.URL, page []byte) (string, neturl.Values, error) {
+    doc, err := goquery.NewDocumentFromReader(bytes.NewReader(page))
+    if err != nil {
+        return "", nil, errors.Wrap(err, "login")
+    }
+
+    form := doc.Find("form").First()
+    doc.Find("form").Each(func(_ int, s *goquery.Selection) {
+        for name := range l.fields {
+            if s.Find(`[name="`+name+`"]`).Length() > 0 {
+                form = s
+            }
+        }
+    })
+
+    values := neturl.Values{}
+    form.Find(`input[type="hidden"]`).Each(func(_ int, s *goquery.Selection) {
+        if name, ok := s.Attr("name"); ok {
+            value, _ := s.Attr("value")
+            values.Set(name, value)
+        }
+    })
+    if l.csrf != "" {
+        token := doc.Find(l.csrf).First()
+        name, _ := token.Attr("name")
+        value, ok := token.Attr("value")
+        if !ok {
+            value, ok = token.Attr("content")
+        }
+        if name == "" || !ok {
+            return "", nil, errors.Errorf("login: csrf token %s not found", l.csrf)
+        }
+        values.Set(name, value)
+    }
+    for name, value := range l.fields {
+        values.Set(name, value)
+    }
+
+    action := base
+    if href, ok := form.Attr("action"); ok && href != "" {
+        if action, err = base.Parse(href); err != nil {
+            return "", nil, errors.WithStack(err)
+        }
+    }
+    return action.String(), values, nil
+}
+
+// matches 链接是否为登录页
+func (l *formLogin) matches(u *neturl.URL) bool {
+    return u != nil && u.Host == l.url.Host && u.Path == l.url.Path
+}
+
+// refresh 重新登录，since 之后已有其他请求完成登录时直接返回
+func (l *formLogin) refresh(ctx context.Context, since time.Time) error {
+    l.mutex.Lock()
+    defer l.mutex.Unlock()
+    if l.at.After(since) {
+        return nil
+    }
+    return l.login(ctx)
+}
+
+// loginRedirect 响应被重定向到已登录主机的登录页时返回对应登录信息，登录请求本身不检查
+func loginRedirect(rep *http.Response, url string) *formLogin {
+    final := rep.Request
+    if final == nil || final.Context().Value(loginKey{}) != nil {
+        return nil
+    }
+    login, ok := conf().logins[final.URL.Host]
+    if !ok || !login.matches(final.URL) {
+        return nil
+    }
+    if u, err := neturl.Parse(url); err == nil && login.matches(u) {
+        return nil
+    }
+    return login
+}
@@ -0,0 +1,221 @@
+package req
+
+import (
+	"context"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/imroc/req"
+	"github.com/pkg/errors"
+	"golang.org/x/net/html"
+)
+
+// MirrorResult 目录镜像结果，路径相对目标目录
+type MirrorResult struct {
+    Downloaded []string
+    // Skipped 未变化而跳过的文件
+    Skipped []string
+    Failed  map[string]error
+}
+
+// MirrorIndex 镜像 HTML 目录索引页（如 nginx/Apache autoindex）中的文件，递归进入子目录
+// pattern 按文件名匹配 glob，以 re: 开头时为正则表达式，为空时匹配全部文件
+// 已下载的文件通过 ETag/Last-Modified 条件请求判断是否变化，未变化时跳过，状态保存在 destDir/.mirror.json
+// 只访问 indexURL 之下的链接，并发受 SetLimit 与 SetHostLimit 限制
+func MirrorIndex(ctx context.Context, indexURL, destDir, pattern string, v ...interface{}) (*MirrorResult, error) {
+    match, err := mirrorMatcher(pattern)
+    if err != nil {
+        return nil, err
+    }
+    if !strings.HasSuffix(indexURL, "/") {
+        indexURL += "/"
+    }
+    if err = os.MkdirAll(destDir, os.ModePerm); err != nil {
+        return nil, errors.WithStack(err)
+    }
+    state, err := loadDownloadState(filepath.Join(destDir, ".mirror.json"))
+    if err != nil {
+        return nil, err
+    }
+
+    files, err := mirrorFiles(ctx, indexURL, match, v)
+    if err != nil {
+        return nil, err
+    }
+
+    var (
+        mutex   sync.Mutex
+        result  = &MirrorResult{Failed: map[string]error{}}
+        opts, _ = splitOptions(v)
+    )
+    err = streamBatch(ctx, URLsFromSlice(files), opts, func(ctx context.Context, url string) error {
+        rel := mirrorPath(indexURL, url)
+        changed, err := mirrorFile(ctx, DownloadTask{URL: url, FileName: rel}, destDir, state, v)
+
+        mutex.Lock()
+        defer mutex.Unlock()
+        switch {
+        case err != nil:
+            result.Failed[rel] = err
+        case changed:
+            result.Downloaded = append(result.Downloaded, rel)
+        default:
+            result.Skipped = append(result.Skipped, rel)
+        }
+        return err
+    })
+    return result, err
+}
+
+// mirrorMatcher 文件名匹配函数
+func mirrorMatcher(pattern string) (func(name string) bool, error) {
+    if strings.HasPrefix(pattern, "re:") {
+        re, err := regexp.Compile(pattern[3:])
+        if err != nil {
+            return nil, errors.WithStack(err)
+        }
+        return re.MatchString, nil
+    }
+    if pattern == "" {
+        return func(string) bool { return true }, nil
+    }
+    if _, err := path.Match(pattern, ""); err != nil {
+        return nil, errors.Wrapf(err, "invalid pattern: %s", pattern)
+    }
+    return func(name string) bool {
+        ok, _ := path.Match(pattern, name)
+        return ok
+    }, nil
+}
+
+// mirrorFiles 递归读取目录索引，返回匹配的文件链接
+func mirrorFiles(ctx context.Context, root string, match func(name string) bool, v []interface{}) ([]string, error) {
+    var (
+        files   []string
+        queue   = []string{root}
+        visited = map[string]bool{root: true}
+    )
+    for len(queue) > 0 {
+        dir := queue[0]
+        queue = queue[1:]
+
+        links, err := indexLinks(ctx, dir, v)
+        if err != nil {
+            return nil, err
+        }
+        for _, link := range links {
+            // 只处理当前目录之下的链接，忽略上级目录与排序链接
+            if !strings.HasPrefix(link, dir) || link == dir {
+                continue
+            }
+            if strings.HasSuffix(link, "/") {
+                if !visited[link] {
+                    visited[link] = true
+                    queue = append(queue, link)
+                }
+            } else if name, _ := neturl.PathUnescape(path.Base(link)); match(name) && !visited[link] {
+                visited[link] = true
+                files = append(files, link)
+            }
+        }
+    }
+    return files, nil
+}
+
+// indexLinks 目录索引页中的链接，已解析为绝对地址并去除查询参数
+func indexLinks(ctx context.Context, url string, v []interface{}) ([]string, error) {
+    res, err := GetStream(ctx, url, v...)
+    if err != nil {
+        return nil, err
+    }
+    defer res.Close()
+
+    var links []string
+    tokenizer := html.NewTokenizer(res.Body)
+    for {
+        switch tokenizer.Next() {
+        case html.ErrorToken:
+            if err := tokenizer.Err(); err != io.EOF {
+                return nil, errors.WithStack(err)
+            }
+            return links, nil
+        case html.StartTagToken, html.SelfClosingTagToken:
+            token := tokenizer.Token()
+            if token.Data != "a" {
+                continue
+            }
+            for _, attr := range token.Attr {
+                if attr.Key != "href" || strings.HasPrefix(attr.Val, "?") || strings.HasPrefix(attr.Val, "#") {
+                    continue
+                }
+                if u, err := neturl.Parse(resolveURL(url, attr.Val)); err == nil {
+                    u.RawQuery, u.Fragment = "", ""
+                    links = append(links, u.String())
+                }
+            }
+        }
+    }
+}
+
+// mirrorPath 文件链接相对索引地址的本地路径
+func mirrorPath(root, url string) string {
+    rel := strings.TrimPrefix(url, root)
+    if unescaped, err := neturl.PathUnescape(rel); err == nil {
+        rel = unescaped
+    }
+    // 以 / 为根清理路径，.. 无法逃出目标目录
+    return filepath.FromSlash(path.Clean("/" + rel))[1:]
+}
+
+// mirrorFile 条件请求文件，变化时写入临时文件后替换
+func mirrorFile(ctx context.Context, task DownloadTask, destDir string, state *downloadState, v []interface{}) (bool, error) {
+    fileName := filepath.Join(destDir, task.FileName)
+    e := state.entry(task)
+
+    header := req.Header{}
+    if _, err := os.Stat(fileName); err == nil && e.Done {
+        if e.ETag != "" {
+            header["If-None-Match"] = e.ETag
+        }
+        if e.LastModified != "" {
+            header["If-Modified-Since"] = e.LastModified
+        }
+    }
+
+    res, err := GetStream(ctx, task.URL, append([]interface{}{header}, v...)...)
+    if err != nil {
+        return false, err
+    }
+    defer res.Close()
+
+    etag := res.Header.Get("ETag")
+    if res.StatusCode == http.StatusNotModified || (len(header) > 0 && etag != "" && etag == e.ETag && res.ContentLength == e.Size) {
+        return false, nil
+    }
+
+    if err = os.MkdirAll(filepath.Dir(fileName), os.ModePerm); err != nil {
+        return false, errors.WithStack(err)
+    }
+    if err = checkDiskSpace(fileName, res.ContentLength); err != nil {
+        return false, err
+    }
+    tmp := fileName + ".part"
+    size, err := saveBody(tmp, res.Body)
+    if err == nil {
+        err = errors.WithStack(os.Rename(tmp, fileName))
+    }
+    if err != nil {
+        _ = os.Remove(tmp)
+        return false, err
+    }
+
+    e = downloadEntry{URL: task.URL, Size: size, Offset: size, Done: true, ETag: etag, LastModified: res.Header.Get("Last-Modified")}
+    return true, state.update(task.FileName, e)
+}
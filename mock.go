@@ -0,0 +1,187 @@
+package req
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// MockServer 本地模拟服务，用于测试基于本包的重试与退避等逻辑
+type MockServer struct {
+    *httptest.Server
+
+    mutex  sync.Mutex
+    routes []*MockRoute
+}
+
+// MockRoute 模拟路由
+type MockRoute struct {
+    method   string
+    segments []string
+
+    mutex    sync.Mutex
+    statuses []int
+    delay    time.Duration
+    header   http.Header
+    body     string
+    calls    int
+}
+
+// NewMockServer 启动模拟服务，未匹配的请求返回 404
+func NewMockServer() *MockServer {
+    s := &MockServer{}
+    s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+    return s
+}
+
+// Route 注册路由，路径中的 {name} 匹配单个路径段，method 为空时匹配全部方法
+// 先注册的路由优先匹配
+func (s *MockServer) Route(method, pattern string) *MockRoute {
+    route := &MockRoute{
+        method:   method,
+        segments: strings.Split(strings.Trim(pattern, "/"), "/"),
+        statuses: []int{http.StatusOK},
+        header:   http.Header{},
+    }
+
+    s.mutex.Lock()
+    s.routes = append(s.routes, route)
+    s.mutex.Unlock()
+    return route
+}
+
+// Install 将全局客户端的全部请求转发到模拟服务，保留原有请求头与路径，重建客户端后仍然生效，返回恢复函数
+// HTTPS 请求同样以明文发送到模拟服务
+func (s *MockServer) Install() (restore func()) {
+    var old string
+    updateConfig(func(c *config) { old, c.mockAddr = c.mockAddr, s.Listener.Addr().String() })
+    resetClient()
+    return func() {
+        updateConfig(func(c *config) { c.mockAddr = old })
+        resetClient()
+    }
+}
+
+// serve 处理请求
+func (s *MockServer) serve(w http.ResponseWriter, r *http.Request) {
+    s.mutex.Lock()
+    routes := append([]*MockRoute{}, s.routes...)
+    s.mutex.Unlock()
+
+    for _, route := range routes {
+        if vars, ok := route.match(r); ok {
+            route.serve(w, r, vars)
+            return
+        }
+    }
+    http.NotFound(w, r)
+}
+
+// Status 设置状态码序列，第 n 次请求返回第 n 个状态码，超出后重复最后一个，如 Status(503, 503, 200)
+func (r *MockRoute) Status(codes ...int) *MockRoute {
+    r.mutex.Lock()
+    defer r.mutex.Unlock()
+    if len(codes) > 0 {
+        r.statuses = codes
+    }
+    return r
+}
+
+// Delay 设置响应延迟
+func (r *MockRoute) Delay(d time.Duration) *MockRoute {
+    r.mutex.Lock()
+    defer r.mutex.Unlock()
+    r.delay = d
+    return r
+}
+
+// Header 设置响应头
+func (r *MockRoute) Header(key, value string) *MockRoute {
+    r.mutex.Lock()
+    defer r.mutex.Unlock()
+    r.header.Set(key, value)
+    return r
+}
+
+// Body 设置响应内容，可使用路径中的 {name} 变量与查询参数
+func (r *MockRoute) Body(body string) *MockRoute {
+    r.mutex.Lock()
+    defer r.mutex.Unlock()
+    r.body = body
+    return r
+}
+
+// JSON 设置 JSON 响应内容
+func (r *MockRoute) JSON(v interface{}) *MockRoute {
+    data, _ := jsoniter.Marshal(v)
+    return r.Header("Content-Type", "application/json").Body(string(data))
+}
+
+// Calls 已处理的请求数量
+func (r *MockRoute) Calls() int {
+    r.mutex.Lock()
+    defer r.mutex.Unlock()
+    return r.calls
+}
+
+// match 匹配请求，返回路径变量与查询参数
+func (r *MockRoute) match(request *http.Request) (map[string]string, bool) {
+    if r.method != "" && !strings.EqualFold(r.method, request.Method) {
+        return nil, false
+    }
+
+    segments := strings.Split(strings.Trim(request.URL.Path, "/"), "/")
+    if len(segments) != len(r.segments) {
+        return nil, false
+    }
+
+    vars := map[string]string{}
+    for key, values := range request.URL.Query() {
+        vars[key] = values[0]
+    }
+    for i, segment := range r.segments {
+        if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+            vars[segment[1:len(segment)-1]] = segments[i]
+        } else if segment != segments[i] {
+            return nil, false
+        }
+    }
+    return vars, true
+}
+
+// serve 返回模拟响应
+func (r *MockRoute) serve(w http.ResponseWriter, request *http.Request, vars map[string]string) {
+    r.mutex.Lock()
+    status := r.statuses[len(r.statuses)-1]
+    if r.calls < len(r.statuses) {
+        status = r.statuses[r.calls]
+    }
+    r.calls++
+    delay, body := r.delay, r.body
+    header := r.header.Clone()
+    r.mutex.Unlock()
+
+    if delay > 0 {
+        select {
+        case <-request.Context().Done():
+            return
+        case <-time.After(delay):
+        }
+    }
+
+    body, err := substitute(body, vars, nil)
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+
+    for key, values := range header {
+        w.Header()[key] = values
+    }
+    w.WriteHeader(status)
+    _, _ = w.Write([]byte(body))
+}
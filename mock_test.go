@@ -0,0 +1,76 @@
+package req
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// withRetry 临时修改重试配置，测试结束后恢复
+func withRetry(t *testing.T, count int, sleep time.Duration) {
+    c := conf()
+    SetRetryCount(count)
+    SetRetrySleepTime(sleep)
+    t.Cleanup(func() {
+        SetRetryCount(c.retryCount)
+        SetRetrySleepTime(c.retrySleepTime)
+    })
+}
+
+func TestMockServerRetry(t *testing.T) {
+    server := NewMockServer()
+    defer server.Close()
+    route := server.Route(http.MethodGet, "/items/{id}").Status(503, 503, 200).Body("item {id}")
+    defer server.Install()()
+    withRetry(t, 3, 0)
+
+    body, err := Get("https://api.example.com/items/7")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if body != "item 7" {
+        t.Fatalf("body = %q, want %q", body, "item 7")
+    }
+    if calls := route.Calls(); calls != 3 {
+        t.Fatalf("calls = %d, want 3", calls)
+    }
+}
+
+func TestMockServerRetryExhausted(t *testing.T) {
+    server := NewMockServer()
+    defer server.Close()
+    route := server.Route(http.MethodGet, "/down").Status(503)
+    defer server.Install()()
+    withRetry(t, 2, 0)
+
+    _, err := Get("http://api.example.com/down")
+    var status *StatusError
+    if !errors.As(err, &status) || status.StatusCode != http.StatusServiceUnavailable {
+        t.Fatalf("err = %v, want status 503", err)
+    }
+    if calls := route.Calls(); calls != 3 {
+        t.Fatalf("calls = %d, want 3", calls)
+    }
+}
+
+func TestMockServerSurvivesReset(t *testing.T) {
+    server := NewMockServer()
+    defer server.Close()
+    route := server.Route("", "/ping").Body("pong")
+    defer server.Install()()
+
+    // 重建客户端的配置不应使请求回到真实网络
+    timeout := conf().timeout
+    SetTimeout(time.Second * 5)
+    defer SetTimeout(timeout)
+
+    body, err := Get("https://api.example.com/ping")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if body != "pong" || route.Calls() != 1 {
+        t.Fatalf("body = %q, calls = %d", body, route.Calls())
+    }
+}
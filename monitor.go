@@ -0,0 +1,239 @@
+package req
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// 监控状态
+const (
+    MonitorUnknown = "unknown"
+    MonitorUp      = "up"
+    MonitorDown    = "down"
+)
+
+// defaultMonitorHistory 默认保留的检查记录数量
+const defaultMonitorHistory = 100
+
+// MonitorTarget 监控目标
+type MonitorTarget struct {
+    URL string
+    // Interval 检查间隔，默认 1 分钟
+    Interval time.Duration
+    // Status 期望状态码，默认 200
+    Status int
+    // Contains 期望响应内容包含的字符串
+    Contains string
+    // MaxLatency 延迟上限，超过时视为不可用，为 0 时不检查
+    MaxLatency time.Duration
+    // Failures 连续失败多少次后判定为不可用，默认 1
+    Failures int
+}
+
+// MonitorResult 单次检查结果
+type MonitorResult struct {
+    Time       time.Time
+    StatusCode int
+    Latency    time.Duration
+    Err        error
+    Up         bool
+}
+
+// Monitor 可用性监控，按间隔检查目标并在状态变化时回调
+type Monitor struct {
+    // OnChange 状态变化回调，首次检查时 from 为 MonitorUnknown
+    OnChange func(target MonitorTarget, from, to string, result MonitorResult)
+    // HistorySize 每个目标保留的检查记录数量，默认 100
+    HistorySize int
+
+    mutex   sync.Mutex
+    ctx     context.Context
+    targets map[string]*monitorEntry
+}
+
+// monitorEntry 监控目标状态
+type monitorEntry struct {
+    target   MonitorTarget
+    state    string
+    failures int
+    history  []MonitorResult
+    cancel   context.CancelFunc
+}
+
+// NewMonitor 创建监控
+func NewMonitor() *Monitor {
+    return &Monitor{HistorySize: defaultMonitorHistory, targets: map[string]*monitorEntry{}}
+}
+
+// Add 添加监控目标，同一链接重复添加时替换原有配置，运行中添加时立即开始检查
+func (m *Monitor) Add(target MonitorTarget) error {
+    if target.URL == "" {
+        return errors.New("monitor: empty url")
+    }
+    if target.Interval <= 0 {
+        target.Interval = time.Minute
+    }
+    if target.Status == 0 {
+        target.Status = http.StatusOK
+    }
+    if target.Failures <= 0 {
+        target.Failures = 1
+    }
+
+    m.mutex.Lock()
+    defer m.mutex.Unlock()
+    if old, ok := m.targets[target.URL]; ok && old.cancel != nil {
+        old.cancel()
+    }
+    entry := &monitorEntry{target: target, state: MonitorUnknown}
+    m.targets[target.URL] = entry
+    if m.ctx != nil {
+        m.start(entry)
+    }
+    return nil
+}
+
+// Remove 移除监控目标
+func (m *Monitor) Remove(url string) {
+    m.mutex.Lock()
+    defer m.mutex.Unlock()
+    if entry, ok := m.targets[url]; ok {
+        if entry.cancel != nil {
+            entry.cancel()
+        }
+        delete(m.targets, url)
+    }
+}
+
+// State 目标当前状态
+func (m *Monitor) State(url string) string {
+    m.mutex.Lock()
+    defer m.mutex.Unlock()
+    if entry, ok := m.targets[url]; ok {
+        return entry.state
+    }
+    return MonitorUnknown
+}
+
+// History 目标的检查记录，按时间先后排列
+func (m *Monitor) History(url string) []MonitorResult {
+    m.mutex.Lock()
+    defer m.mutex.Unlock()
+    if entry, ok := m.targets[url]; ok {
+        return append([]MonitorResult{}, entry.history...)
+    }
+    return nil
+}
+
+// Run 开始监控，阻塞直到 ctx 结束
+func (m *Monitor) Run(ctx context.Context) error {
+    m.mutex.Lock()
+    if m.ctx != nil {
+        m.mutex.Unlock()
+        return errors.New("monitor: already running")
+    }
+    m.ctx = ctx
+    for _, entry := range m.targets {
+        m.start(entry)
+    }
+    m.mutex.Unlock()
+
+    <-ctx.Done()
+
+    m.mutex.Lock()
+    m.ctx = nil
+    for _, entry := range m.targets {
+        entry.cancel = nil
+    }
+    m.mutex.Unlock()
+    return errors.WithStack(ctx.Err())
+}
+
+// start 启动目标检查协程，需持有锁
+func (m *Monitor) start(entry *monitorEntry) {
+    ctx, cancel := context.WithCancel(m.ctx)
+    entry.cancel = cancel
+    go func() {
+        for {
+            m.record(entry, m.probe(ctx, entry.target))
+            select {
+            case <-ctx.Done():
+                return
+            case <-conf().clock.After(entry.target.Interval):
+            }
+        }
+    }()
+}
+
+// probe 检查一次目标
+func (m *Monitor) probe(ctx context.Context, target MonitorTarget) MonitorResult {
+    result := MonitorResult{Time: conf().clock.Now()}
+    start := time.Now()
+    rep, err := doOnce(http.MethodGet, target.URL, ctx)
+    if err != nil {
+        result.Err = err
+        return result
+    }
+
+    res := rep.Response()
+    defer res.Body.Close()
+    result.StatusCode = res.StatusCode
+    if target.Contains != "" {
+        body, err := io.ReadAll(res.Body)
+        if err != nil {
+            result.Err = errors.WithStack(err)
+            return result
+        }
+        if !bytes.Contains(body, []byte(target.Contains)) {
+            result.Err = errors.Errorf("monitor: body does not contain %q", target.Contains)
+        }
+    }
+    result.Latency = time.Since(start)
+
+    switch {
+    case result.Err != nil:
+    case res.StatusCode != target.Status:
+        result.Err = errors.WithStack(&StatusError{StatusCode: res.StatusCode})
+    case target.MaxLatency > 0 && result.Latency > target.MaxLatency:
+        result.Err = errors.Errorf("monitor: latency %s exceeds %s", result.Latency, target.MaxLatency)
+    default:
+        result.Up = true
+    }
+    return result
+}
+
+// record 记录检查结果，状态变化时回调
+func (m *Monitor) record(entry *monitorEntry, result MonitorResult) {
+    m.mutex.Lock()
+    if m.targets[entry.target.URL] != entry {
+        // 目标已被移除或替换
+        m.mutex.Unlock()
+        return
+    }
+
+    entry.history = append(entry.history, result)
+    if size := m.HistorySize; size > 0 && len(entry.history) > size {
+        entry.history = entry.history[len(entry.history)-size:]
+    }
+
+    from, to := entry.state, entry.state
+    if result.Up {
+        entry.failures = 0
+        to = MonitorUp
+    } else if entry.failures++; entry.failures >= entry.target.Failures {
+        to = MonitorDown
+    }
+    entry.state = to
+    onChange := m.OnChange
+    m.mutex.Unlock()
+
+    if from != to && onChange != nil {
+        onChange(entry.target, from, to, result)
+    }
+}
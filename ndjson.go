@@ -0,0 +1,47 @@
+package req
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+)
+
+// GetNDJSON 流式读取 JSON Lines 响应，每解析一行回调一次，回调返回错误时停止读取
+func GetNDJSON[T any](ctx context.Context, url string, fn func(T) error, v ...interface{}) error {
+    rep, err := GetStream(ctx, url, v...)
+    if err != nil {
+        return err
+    }
+    defer rep.Close()
+
+    return DecodeNDJSON(rep.Body, fn)
+}
+
+// DecodeNDJSON 逐行解析 JSON Lines 内容
+func DecodeNDJSON[T any](r io.Reader, fn func(T) error) error {
+    reader := bufio.NewReader(r)
+    for line := 1; ; line++ {
+        data, err := reader.ReadBytes('\n')
+        if err != nil && err != io.EOF {
+            return errors.WithStack(err)
+        }
+
+        if data = bytes.TrimSpace(data); len(data) > 0 {
+            var item T
+            if e := jsoniter.Unmarshal(data, &item); e != nil {
+                return errors.Wrapf(e, "ndjson line %d", line)
+            }
+            if e := fn(item); e != nil {
+                return e
+            }
+        }
+
+        if err == io.EOF {
+            return nil
+        }
+    }
+}
@@ -0,0 +1,407 @@
+package req
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/Azure/go-ntlmssp"
+	"github.com/jcmturner/gokrb5/v8/client"
+	krb5config "github.com/jcmturner/gokrb5/v8/config"
+	"github.com/jcmturner/gokrb5/v8/credentials"
+	"github.com/jcmturner/gokrb5/v8/spnego"
+	"github.com/pkg/errors"
+)
+
+// Authenticator 连接级认证，NTLM 与 Negotiate 在同一连接上按质询交换令牌
+type Authenticator interface {
+    // Scheme 认证方案，NTLM 或 Negotiate
+    Scheme() string
+    // Token 按质询生成令牌，challenge 为空时生成首个令牌，host 为服务端或代理主机名
+    Token(host string, challenge []byte) ([]byte, error)
+}
+
+// ntlmAuth NTLM 认证
+type ntlmAuth struct {
+    user     string
+    password string
+}
+
+// NTLMAuth NTLM 认证，user 支持 DOMAIN\user 与 user@domain 形式，相同账号的认证可比较相等，共用连接池
+func NTLMAuth(user, password string) Authenticator {
+    return ntlmAuth{user: user, password: password}
+}
+
+func (a ntlmAuth) Scheme() string {
+    return "NTLM"
+}
+
+func (a ntlmAuth) Token(host string, challenge []byte) ([]byte, error) {
+    user, domain, domainNeeded := ntlmssp.GetDomain(a.user)
+    if challenge == nil {
+        token, err := ntlmssp.NewNegotiateMessage(domain, "")
+        return token, errors.WithStack(err)
+    }
+    token, err := ntlmssp.ProcessChallenge(challenge, user, a.password, domainNeeded)
+    return token, errors.WithStack(err)
+}
+
+// kerberosAuth SPNEGO/Kerberos 认证
+type kerberosAuth struct {
+    client *client.Client
+}
+
+// KerberosAuth 使用凭据缓存的 SPNEGO/Kerberos 认证，服务主体为 HTTP/主机名
+// krb5Conf 为空时读取 KRB5_CONFIG 或 /etc/krb5.conf，ccache 为空时读取 KRB5CCNAME 或 /tmp/krb5cc_<uid>
+func KerberosAuth(krb5Conf, ccache string) (Authenticator, error) {
+    if krb5Conf == "" {
+        if krb5Conf = os.Getenv("KRB5_CONFIG"); krb5Conf == "" {
+            krb5Conf = "/etc/krb5.conf"
+        }
+    }
+    if ccache == "" {
+        if ccache = strings.TrimPrefix(os.Getenv("KRB5CCNAME"), "FILE:"); ccache == "" {
+            ccache = fmt.Sprintf("/tmp/krb5cc_%d", os.Getuid())
+        }
+    }
+
+    cfg, err := krb5config.Load(krb5Conf)
+    if err != nil {
+        return nil, errors.Wrapf(err, "kerberos: %s", krb5Conf)
+    }
+    cache, err := credentials.LoadCCache(ccache)
+    if err != nil {
+        return nil, errors.Wrapf(err, "kerberos: %s", ccache)
+    }
+    cl, err := client.NewFromCCache(cache, cfg)
+    if err != nil {
+        return nil, errors.Wrap(err, "kerberos")
+    }
+    return &kerberosAuth{client: cl}, nil
+}
+
+func (a *kerberosAuth) Scheme() string {
+    return "Negotiate"
+}
+
+func (a *kerberosAuth) Token(host string, challenge []byte) ([]byte, error) {
+    if challenge != nil {
+        return nil, errors.New("kerberos: unexpected continuation challenge")
+    }
+
+    s := spnego.SPNEGOClient(a.client, "HTTP/"+host)
+    if err := s.AcquireCred(); err != nil {
+        return nil, errors.Wrap(err, "kerberos")
+    }
+    ct, err := s.InitSecContext()
+    if err != nil {
+        return nil, errors.Wrap(err, "kerberos")
+    }
+    token, err := ct.Marshal()
+    return token, errors.WithStack(err)
+}
+
+// SetServerAuth 设置目标服务端的 NTLM/Negotiate 认证，收到 401 质询时在同一连接上完成认证，为 nil 时关闭
+// auth 需可比较，要求同 WithServerAuth
+func SetServerAuth(auth Authenticator) error {
+    if auth != nil {
+        if err := checkAuthenticator(auth); err != nil {
+            return err
+        }
+    }
+    updateConfig(func(c *config) { c.serverAuth = auth })
+    resetClient()
+    return nil
+}
+
+// SetProxyAuth 设置 HTTP/HTTPS 代理的 NTLM/Negotiate 认证，同时作用于 HTTP 请求与 HTTPS 隧道，为 nil 时关闭
+// auth 需可比较，要求同 WithServerAuth
+func SetProxyAuth(auth Authenticator) error {
+    if auth != nil {
+        if err := checkAuthenticator(auth); err != nil {
+            return err
+        }
+    }
+    updateConfig(func(c *config) { c.proxyAuth = auth })
+    resetClient()
+    return nil
+}
+
+// WithServerAuth 本次请求的服务端 NTLM/Negotiate 认证，覆盖 SetServerAuth
+// 认证在连接上保持，相同认证的请求共用连接池，auth 需可比较，如 NTLMAuth 与 KerberosAuth 的返回值
+func WithServerAuth(auth Authenticator) Option {
+    err := checkAuthenticator(auth)
+    return func(o *options) {
+        if err != nil {
+            o.err = err
+            return
+        }
+        o.serverAuth = auth
+    }
+}
+
+// WithProxyAuth 本次请求的代理 NTLM/Negotiate 认证，覆盖 SetProxyAuth，连接池与 auth 要求同 WithServerAuth
+func WithProxyAuth(auth Authenticator) Option {
+    err := checkAuthenticator(auth)
+    return func(o *options) {
+        if err != nil {
+            o.err = err
+            return
+        }
+        o.proxyAuth = auth
+    }
+}
+
+// checkAuthenticator 检查认证能否作为连接池的键
+func checkAuthenticator(auth Authenticator) error {
+    if auth == nil {
+        return errors.New("authenticator is nil")
+    }
+    if !reflect.TypeOf(auth).Comparable() {
+        return errors.Errorf("authenticator %T is not comparable", auth)
+    }
+    return nil
+}
+
+// authChallenge 认证头中指定方案的质询，ok 为 false 时服务端不支持该方案
+func authChallenge(values []string, scheme string) (challenge []byte, ok bool) {
+    for _, value := range values {
+        name, token, _ := strings.Cut(strings.TrimSpace(value), " ")
+        if !strings.EqualFold(name, scheme) {
+            continue
+        }
+        if token = strings.TrimSpace(token); token == "" {
+            return nil, true
+        }
+        challenge, err := base64.StdEncoding.DecodeString(token)
+        return challenge, err == nil
+    }
+    return nil, false
+}
+
+// authHeader 认证请求头的值
+func authHeader(auth Authenticator, token []byte) string {
+    return auth.Scheme() + " " + base64.StdEncoding.EncodeToString(token)
+}
+
+// negotiateTransport 处理 NTLM/Negotiate 质询的传输层，proxy 为 true 时处理 HTTP 请求的代理认证
+type negotiateTransport struct {
+    base  http.RoundTripper
+    auth  Authenticator
+    proxy bool
+}
+
+// RoundTrip 发送请求，收到质询时在同一连接上交换令牌后重新发送
+func (t *negotiateTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+    status, challengeKey, authKey, host := http.StatusUnauthorized, "WWW-Authenticate", "Authorization", r.URL.Hostname()
+    if t.proxy {
+        // HTTPS 请求的代理认证在建立隧道时完成
+        if r.URL.Scheme != "http" {
+            return t.base.RoundTrip(r)
+        }
+        proxy, err := proxyFor(r.URL.String())
+        if err != nil || proxy == nil {
+            return t.base.RoundTrip(r)
+        }
+        status, challengeKey, authKey, host = http.StatusProxyAuthRequired, "Proxy-Authenticate", "Proxy-Authorization", proxy.Hostname()
+    }
+
+    r, err := replayable(r)
+    if err != nil {
+        return nil, err
+    }
+    rep, err := t.base.RoundTrip(r)
+    for round := 0; err == nil && rep.StatusCode == status && round < 3; round++ {
+        challenge, ok := authChallenge(rep.Header.Values(challengeKey), t.auth.Scheme())
+        if !ok || (round > 0 && challenge == nil) {
+            break
+        }
+        var (
+            token []byte
+            next  *http.Request
+        )
+        if token, err = t.auth.Token(host, challenge); err == nil {
+            next, err = resend(r, authKey, authHeader(t.auth, token))
+        }
+        if err != nil {
+            rep.Body.Close()
+            return nil, err
+        }
+        // 读完响应体使连接回到连接池，后续请求复用同一连接
+        io.Copy(io.Discard, rep.Body)
+        rep.Body.Close()
+        rep, err = t.base.RoundTrip(next)
+    }
+    return rep, err
+}
+
+// tunnelProxy HTTPS 请求不使用传输层代理，由 dialTunnel 建立带认证的隧道
+func tunnelProxy(proxy func(*http.Request) (*neturl.URL, error)) func(*http.Request) (*neturl.URL, error) {
+    return func(r *http.Request) (*neturl.URL, error) {
+        if proxy == nil {
+            return nil, nil
+        }
+        u, err := proxy(r)
+        if err != nil || u == nil || r.URL.Scheme != "https" {
+            return u, err
+        }
+        if u.Scheme == "http" || u.Scheme == "https" {
+            return nil, nil
+        }
+        return u, nil
+    }
+}
+
+// dialTunnel 经 HTTP/HTTPS 代理建立到目标地址的隧道，auth 为 nil 时使用代理地址中的用户信息认证，不使用代理时直接连接
+func dialTunnel(dial func(ctx context.Context, network, addr string) (net.Conn, error), auth Authenticator) func(ctx context.Context, network, addr string) (net.Conn, error) {
+    return func(ctx context.Context, network, addr string) (net.Conn, error) {
+        proxy, err := proxyFor("https://" + addr)
+        if err != nil {
+            return nil, err
+        }
+        if proxy == nil || (proxy.Scheme != "http" && proxy.Scheme != "https") {
+            return dial(ctx, network, addr)
+        }
+
+        proxyAddr := proxy.Host
+        if proxy.Port() == "" {
+            port := "80"
+            if proxy.Scheme == "https" {
+                port = "443"
+            }
+            proxyAddr = net.JoinHostPort(proxy.Hostname(), port)
+        }
+        conn, err := dial(ctx, network, proxyAddr)
+        if err != nil {
+            return nil, err
+        }
+        if proxy.Scheme == "https" {
+            tlsConn := tls.Client(conn, &tls.Config{ServerName: proxy.Hostname()})
+            if err = tlsConn.HandshakeContext(ctx); err != nil {
+                conn.Close()
+                return nil, errors.WithStack(err)
+            }
+            conn = tlsConn
+        }
+
+        if deadline, ok := ctx.Deadline(); ok {
+            conn.SetDeadline(deadline)
+            defer conn.SetDeadline(time.Time{})
+        }
+        if err = connectTunnel(conn, proxy, addr, auth); err != nil {
+            conn.Close()
+            return nil, err
+        }
+        return conn, nil
+    }
+}
+
+// connectTunnel 发送 CONNECT 请求，在同一连接上完成代理认证
+func connectTunnel(conn net.Conn, proxy *neturl.URL, addr string, auth Authenticator) error {
+    header := http.Header{}
+    if auth == nil {
+        if proxy.User != nil {
+            password, _ := proxy.User.Password()
+            header.Set("Proxy-Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(proxy.User.Username()+":"+password)))
+        }
+        return connectOnce(conn, addr, header)
+    }
+
+    proxyHost := proxy.Hostname()
+    token, err := auth.Token(proxyHost, nil)
+    if err != nil {
+        return err
+    }
+
+    br := bufio.NewReader(conn)
+    for round := 0; round < 3; round++ {
+        r := &http.Request{
+            Method: http.MethodConnect,
+            URL:    &neturl.URL{Opaque: addr},
+            Host:   addr,
+            Header: http.Header{"Proxy-Authorization": {authHeader(auth, token)}},
+        }
+        if err = r.Write(conn); err != nil {
+            return errors.WithStack(err)
+        }
+        rep, err := http.ReadResponse(br, r)
+        if err != nil {
+            return errors.WithStack(err)
+        }
+        if rep.StatusCode == http.StatusOK {
+            rep.Body.Close()
+            if br.Buffered() > 0 {
+                return errors.Errorf("proxy: unexpected data after CONNECT %s", addr)
+            }
+            return nil
+        }
+
+        io.Copy(io.Discard, rep.Body)
+        rep.Body.Close()
+        challenge, ok := authChallenge(rep.Header.Values("Proxy-Authenticate"), auth.Scheme())
+        if rep.StatusCode != http.StatusProxyAuthRequired || !ok || challenge == nil || rep.Close {
+            return errors.Errorf("proxy: CONNECT %s: %s", addr, rep.Status)
+        }
+        if token, err = auth.Token(proxyHost, challenge); err != nil {
+            return err
+        }
+    }
+    return errors.Errorf("proxy: CONNECT %s: authentication failed", addr)
+}
+
+// connectOnce 发送单次 CONNECT 请求
+func connectOnce(conn net.Conn, addr string, header http.Header) error {
+    r := &http.Request{
+        Method: http.MethodConnect,
+        URL:    &neturl.URL{Opaque: addr},
+        Host:   addr,
+        Header: header,
+    }
+    if err := r.Write(conn); err != nil {
+        return errors.WithStack(err)
+    }
+    br := bufio.NewReader(conn)
+    rep, err := http.ReadResponse(br, r)
+    if err != nil {
+        return errors.WithStack(err)
+    }
+    rep.Body.Close()
+    if rep.StatusCode != http.StatusOK {
+        return errors.Errorf("proxy: CONNECT %s: %s", addr, rep.Status)
+    }
+    if br.Buffered() > 0 {
+        return errors.Errorf("proxy: unexpected data after CONNECT %s", addr)
+    }
+    return nil
+}
+
+// dialTLS 在 dial 建立的连接上完成 TLS 握手，只协商 HTTP/1.1
+func dialTLS(dial func(ctx context.Context, network, addr string) (net.Conn, error)) func(ctx context.Context, network, addr string) (net.Conn, error) {
+    return func(ctx context.Context, network, addr string) (net.Conn, error) {
+        host, _, err := net.SplitHostPort(addr)
+        if err != nil {
+            return nil, errors.WithStack(err)
+        }
+        conn, err := dial(ctx, network, addr)
+        if err != nil {
+            return nil, err
+        }
+
+        tlsConn := tls.Client(conn, &tls.Config{ServerName: host, NextProtos: []string{"http/1.1"}})
+        if err = tlsConn.HandshakeContext(ctx); err != nil {
+            conn.Close()
+            return nil, errors.WithStack(err)
+        }
+        return tlsConn, nil
+    }
+}
@@ -0,0 +1,61 @@
+package req
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// sliceAuth 不可比较的认证
+type sliceAuth []byte
+
+// Scheme 认证方案
+func (a sliceAuth) Scheme() string {
+    return "NTLM"
+}
+
+// Token 生成令牌
+func (a sliceAuth) Token(host string, challenge []byte) ([]byte, error) {
+    return a, nil
+}
+
+func TestSetAuthRejectsNonComparable(t *testing.T) {
+    if err := SetServerAuth(sliceAuth("x")); err == nil {
+        t.Error("SetServerAuth accepted a non-comparable authenticator")
+    }
+    if err := SetProxyAuth(sliceAuth("x")); err == nil {
+        t.Error("SetProxyAuth accepted a non-comparable authenticator")
+    }
+    if c := conf(); c.serverAuth != nil || c.proxyAuth != nil {
+        t.Fatal("rejected authenticator was installed")
+    }
+}
+
+// countTransport 记录请求次数的传输层
+type countTransport struct {
+    calls int
+}
+
+// RoundTrip 返回固定响应
+func (t *countTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+    t.calls++
+    return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: io.NopCloser(strings.NewReader("ok")), Request: r}, nil
+}
+
+func TestServerAuthKeepsCustomTransport(t *testing.T) {
+    transport := &countTransport{}
+    client := &http.Client{Transport: transport}
+
+    body, err := Get("http://intranet.example.com/", client, WithServerAuth(NTLMAuth("user", "secret")))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if body != "ok" || transport.calls != 1 {
+        t.Fatalf("body = %q, calls = %d", body, transport.calls)
+    }
+
+    if _, err = Get("http://intranet.example.com/", client, WithProxyAuth(NTLMAuth("user", "secret"))); err == nil {
+        t.Fatal("proxy auth with a custom transport not rejected")
+    }
+}
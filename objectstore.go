@@ -0,0 +1,70 @@
+package req
+
+import (
+	"context"
+	"io"
+	neturl "net/url"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gocloud.dev/blob"
+	_ "gocloud.dev/blob/gcsblob"
+	_ "gocloud.dev/blob/s3blob"
+)
+
+// objectPartSize 分片上传的分片大小
+const objectPartSize = 16 << 20
+
+// DownloadToObjectStore 下载链接内容并流式上传到对象存储，不写入本地磁盘，大文件自动分片上传
+// bucketURL 如 s3://bucket/dir/、gs://bucket/name.zip，MinIO 使用 s3://bucket/?endpoint=host:9000&s3ForcePathStyle=true
+// 路径以 / 结尾或为空时使用链接中的文件名作为对象名
+func DownloadToObjectStore(ctx context.Context, url, bucketURL string, v ...interface{}) error {
+    u, err := neturl.Parse(bucketURL)
+    if err != nil {
+        return errors.WithStack(err)
+    }
+    key := strings.TrimPrefix(u.Path, "/")
+    if key == "" || strings.HasSuffix(key, "/") {
+        source, err := neturl.Parse(url)
+        if err != nil {
+            return errors.WithStack(err)
+        }
+        name := path.Base(source.Path)
+        if name == "." || name == "/" {
+            return errors.Errorf("object name required: %s", bucketURL)
+        }
+        key += name
+    }
+    u.Path = ""
+
+    bucket, err := blob.OpenBucket(ctx, u.String())
+    if err != nil {
+        return errors.WithStack(err)
+    }
+    defer bucket.Close()
+
+    res, err := GetStream(ctx, url, v...)
+    if err != nil {
+        return err
+    }
+    defer res.Close()
+
+    // 上传失败时取消上下文，放弃未完成的分片上传
+    ctx, cancel := context.WithCancel(ctx)
+    defer cancel()
+    writer, err := bucket.NewWriter(ctx, key, &blob.WriterOptions{
+        BufferSize:  objectPartSize,
+        ContentType: res.Header.Get("Content-Type"),
+    })
+    if err != nil {
+        return errors.WithStack(err)
+    }
+
+    if _, err = io.Copy(writer, res.Body); err != nil {
+        cancel()
+        _ = writer.Close()
+        return errors.WithStack(err)
+    }
+    return errors.WithStack(writer.Close())
+}
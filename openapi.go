@@ -0,0 +1,240 @@
+package req
+
+import (
+	"bytes"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/imroc/req"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// openAPIMethods OpenAPI 路径中的请求方法
+var openAPIMethods = []string{
+    http.MethodGet, http.MethodPut, http.MethodPost, http.MethodDelete,
+    http.MethodOptions, http.MethodHead, http.MethodPatch, http.MethodTrace,
+}
+
+// OpenAPIParam 接口参数
+type OpenAPIParam struct {
+    Name string
+    // In 参数位置 path/query/header
+    In       string
+    Required bool
+}
+
+// OpenAPIOperation 接口定义
+type OpenAPIOperation struct {
+    // ID operationId，未设置时为 "METHOD /path"
+    ID     string
+    Method string
+    // Path 路径模板，如 /pets/{id}
+    Path   string
+    Params []OpenAPIParam
+    // HasBody 是否定义了请求体
+    HasBody bool
+}
+
+// OpenAPIClient 按 OpenAPI 3 文档生成的接口客户端，请求经过本包的重试、缓存与并发控制
+type OpenAPIClient struct {
+    // BaseURL 接口地址，默认取文档 servers 中的第一个地址
+    BaseURL string
+    // Operations 按 ID 索引的接口
+    Operations map[string]*OpenAPIOperation
+    v          []interface{}
+}
+
+// FromOpenAPI 解析 JSON 或 YAML 格式的 OpenAPI 3 文档，v 为全部请求共用的参数
+func FromOpenAPI(spec []byte, v ...interface{}) (*OpenAPIClient, error) {
+    var (
+        doc map[string]interface{}
+        err error
+    )
+    if trimmed := bytes.TrimSpace(spec); len(trimmed) > 0 && trimmed[0] == '{' {
+        err = jsoniter.Unmarshal(spec, &doc)
+    } else {
+        err = yaml.Unmarshal(spec, &doc)
+    }
+    if err != nil {
+        return nil, errors.Wrap(err, "openapi")
+    }
+    doc, _ = stringKeys(doc).(map[string]interface{})
+
+    c := &OpenAPIClient{Operations: map[string]*OpenAPIOperation{}, v: v}
+    if servers, ok := doc["servers"].([]interface{}); ok && len(servers) > 0 {
+        if server, ok := servers[0].(map[string]interface{}); ok {
+            c.BaseURL, _ = server["url"].(string)
+        }
+    }
+
+    paths, _ := doc["paths"].(map[string]interface{})
+    for path, value := range paths {
+        item, _ := openAPIResolve(doc, value).(map[string]interface{})
+        shared := openAPIParams(doc, item["parameters"])
+        for _, method := range openAPIMethods {
+            operation, ok := item[strings.ToLower(method)].(map[string]interface{})
+            if !ok {
+                continue
+            }
+
+            op := &OpenAPIOperation{Method: method, Path: path}
+            if op.ID, _ = operation["operationId"].(string); op.ID == "" {
+                op.ID = method + " " + path
+            }
+            _, op.HasBody = operation["requestBody"]
+            op.Params = mergeOpenAPIParams(shared, openAPIParams(doc, operation["parameters"]))
+            c.Operations[op.ID] = op
+        }
+    }
+    return c, nil
+}
+
+// LoadOpenAPI 读取 OpenAPI 3 文档文件
+func LoadOpenAPI(path string, v ...interface{}) (*OpenAPIClient, error) {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, errors.WithStack(err)
+    }
+    return FromOpenAPI(data, v...)
+}
+
+// OperationIDs 全部接口 ID，按字母排序
+func (c *OpenAPIClient) OperationIDs() []string {
+    ids := make([]string, 0, len(c.Operations))
+    for id := range c.Operations {
+        ids = append(ids, id)
+    }
+    sort.Strings(ids)
+    return ids
+}
+
+// Call 调用接口，params 按定义填入路径、查询参数与请求头，body 为 string/[]byte 时原样发送，其他值按 JSON 编码
+func (c *OpenAPIClient) Call(id string, params map[string]string, body interface{}, v ...interface{}) (string, error) {
+    op, ok := c.Operations[id]
+    if !ok {
+        return "", errors.Errorf("openapi: unknown operation %s", id)
+    }
+
+    url, args, err := op.request(c.BaseURL, params, body)
+    if err != nil {
+        return "", err
+    }
+    args = append(args, c.v...)
+    return doRequest(op.Method, url, append(args, v...)...)
+}
+
+// request 按参数生成请求链接与参数
+func (op *OpenAPIOperation) request(baseURL string, params map[string]string, body interface{}) (string, []interface{}, error) {
+    var (
+        query  = neturl.Values{}
+        header = req.Header{}
+        path   = map[string]string{}
+    )
+    for _, param := range op.Params {
+        value, ok := params[param.Name]
+        if !ok {
+            if param.Required {
+                return "", nil, errors.Errorf("openapi: %s: missing required %s parameter %s", op.ID, param.In, param.Name)
+            }
+            continue
+        }
+        switch param.In {
+        case "path":
+            path[param.Name] = value
+        case "query":
+            query.Set(param.Name, value)
+        case "header":
+            header[param.Name] = value
+        }
+    }
+
+    rendered, err := substitute(op.Path, path, neturl.PathEscape)
+    if err != nil {
+        return "", nil, errors.Wrapf(err, "openapi: %s", op.ID)
+    }
+    url := strings.TrimRight(baseURL, "/") + rendered
+    if len(query) > 0 {
+        url += "?" + query.Encode()
+    }
+
+    args := []interface{}{header}
+    switch b := body.(type) {
+    case nil:
+    case string:
+        args = append(args, []byte(b))
+    case []byte:
+        args = append(args, b)
+    default:
+        args = append(args, JSONBody(b))
+    }
+    return url, args, nil
+}
+
+// openAPIParams 解析参数列表，支持文档内 $ref 引用
+func openAPIParams(doc map[string]interface{}, value interface{}) []OpenAPIParam {
+    list, _ := value.([]interface{})
+    params := make([]OpenAPIParam, 0, len(list))
+    for _, item := range list {
+        m, ok := openAPIResolve(doc, item).(map[string]interface{})
+        if !ok {
+            continue
+        }
+        param := OpenAPIParam{}
+        param.Name, _ = m["name"].(string)
+        param.In, _ = m["in"].(string)
+        param.Required, _ = m["required"].(bool)
+        if param.Name != "" {
+            params = append(params, param)
+        }
+    }
+    return params
+}
+
+// mergeOpenAPIParams 合并路径级与接口级参数，接口级同名同位置参数优先
+func mergeOpenAPIParams(shared, own []OpenAPIParam) []OpenAPIParam {
+    params := append([]OpenAPIParam{}, own...)
+    for _, param := range shared {
+        overridden := false
+        for _, p := range own {
+            if p.Name == param.Name && p.In == param.In {
+                overridden = true
+                break
+            }
+        }
+        if !overridden {
+            params = append(params, param)
+        }
+    }
+    return params
+}
+
+// openAPIResolve 解析文档内 #/ 开头的 $ref 引用
+func openAPIResolve(doc map[string]interface{}, value interface{}) interface{} {
+    for depth := 0; depth < 16; depth++ {
+        m, ok := value.(map[string]interface{})
+        if !ok {
+            return value
+        }
+        ref, ok := m["$ref"].(string)
+        if !ok || !strings.HasPrefix(ref, "#/") {
+            return value
+        }
+
+        var node interface{} = doc
+        for _, part := range strings.Split(ref[2:], "/") {
+            part = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+            next, ok := node.(map[string]interface{})
+            if !ok {
+                return nil
+            }
+            node = next[part]
+        }
+        value = node
+    }
+    return value
+}
@@ -0,0 +1,150 @@
+package req
+
+import (
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// Option 请求选项，与 imroc/req 参数一起传入 Get/Post 等方法
+type Option func(*options)
+
+// options 单次请求选项
+type options struct {
+    // err 选项校验错误，发起请求前返回
+    err     error
+    profile HeaderProfile
+    // persistedQuery GraphQL 持久化查询
+    persistedQuery bool
+    // idempotencyKey 幂等键
+    idempotencyKey string
+    // requestID 请求 ID
+    requestID string
+    // hedgeDelay 备份请求延迟
+    hedgeDelay time.Duration
+    // priority 批量请求优先级
+    priority int
+    // stats 批量请求统计
+    stats *BatchStats
+    // cache 非 GET 请求是否缓存
+    cache bool
+    // cacheHeaders 参与缓存名称计算的请求头
+    cacheHeaders []string
+    // unsafeRetry 允许非幂等请求重试
+    unsafeRetry bool
+    // body 选项编码的请求体，[]byte 或 io.Reader
+    body interface{}
+    // contentType 请求体内容类型
+    contentType string
+    // oneShotBody 请求体只能读取一次，不重试
+    oneShotBody bool
+    // stream 流式读取响应，不识别拦截页
+    stream bool
+    // edits 发送前修改请求
+    edits []func(r *http.Request) error
+    // maxBodySize 响应体最大长度，为 0 时不限制
+    maxBodySize int64
+    // textOnly 二进制内容返回错误
+    textOnly bool
+    // downloadFallback 二进制或超长内容写入的文件
+    downloadFallback string
+    // batchBudget 批量请求时间预算
+    batchBudget time.Duration
+    // budgetCancel 超出预算时取消执行中的条目
+    budgetCancel bool
+    // errorRate 批量请求失败率阈值
+    errorRate float64
+    // errorMin 计算失败率前至少完成的条目数量
+    errorMin int
+    // remoteAuth FTP/SFTP 认证信息
+    remoteAuth *RemoteAuth
+    // resume 下载续传
+    resume bool
+    // cacheRefresh 忽略已有缓存重新请求
+    cacheRefresh bool
+    // watchNormalize Watch 比较前规范化内容
+    watchNormalize func([]byte) []byte
+    // labels 请求标签
+    labels map[string]string
+    // schema 响应 JSON Schema
+    schema *jsonschema.Schema
+    // soapHeader SOAP 信封 Header 内容
+    soapHeader []byte
+    // acceptStatus 视为成功的其他状态码
+    acceptStatus []int
+    // digest Digest 认证信息
+    digest *digestCredentials
+    // csrfSelector LoginForm 提取 CSRF 令牌的选择器
+    csrfSelector string
+    // timeout 本次请求超时时间
+    timeout time.Duration
+    // retries 本次请求重试次数，为 nil 时使用 SetRetryCount
+    retries *int
+    // localAddr 本次请求绑定的本地地址
+    localAddr net.IP
+    // tlsFingerprint 本次请求的 TLS 握手指纹，为 nil 时使用 SetTLSFingerprint
+    tlsFingerprint *TLSFingerprint
+    // serverAuth 本次请求的服务端认证
+    serverAuth Authenticator
+    // proxyAuth 本次请求的代理认证
+    proxyAuth Authenticator
+    // chromeWait ChromeGet 等待可见的选择器
+    chromeWait string
+    // chromeSelector ChromeGet 提取内容的选择器
+    chromeSelector string
+    // chromeSleep ChromeGet 页面加载后等待的时长
+    chromeSleep time.Duration
+    // chromeProfile ChromeGet 使用的 Chrome 配置
+    chromeProfile string
+}
+
+// WithTimeout 设置本次请求的超时时间，包含读取响应体，覆盖 SetTimeout
+func WithTimeout(timeout time.Duration) Option {
+    return func(o *options) {
+        o.timeout = timeout
+    }
+}
+
+// withAcceptStatus 将指定状态码视为成功，不重试并返回响应
+func withAcceptStatus(codes ...int) Option {
+    return func(o *options) {
+        o.acceptStatus = append(o.acceptStatus, codes...)
+    }
+}
+
+// withStream 响应由调用方流式读取
+func withStream() Option {
+    return func(o *options) {
+        o.stream = true
+    }
+}
+
+// containsInt 列表中是否包含 n
+func containsInt(list []int, n int) bool {
+    for _, item := range list {
+        if item == n {
+            return true
+        }
+    }
+    return false
+}
+
+// splitOptions 拆分请求选项与 imroc/req 参数，Headers/Query 转换为 imroc/req 参数
+func splitOptions(v []interface{}) (*options, []interface{}) {
+    opts := &options{}
+    args := make([]interface{}, 0, len(v))
+    for _, arg := range v {
+        if option, ok := arg.(Option); ok {
+            option(opts)
+            continue
+        }
+        arg, err := typedArg(arg)
+        if err != nil && opts.err == nil {
+            opts.err = err
+        }
+        args = append(args, arg)
+    }
+    return opts, args
+}
@@ -0,0 +1,168 @@
+package req
+
+import (
+	"context"
+	"net/http"
+	neturl "net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+)
+
+// PageSpec 分页规则，未设置 Cursor 与 PageParam 时跟随 Link: rel=next 响应头
+type PageSpec struct {
+    // Items 条目数组的 JSON 路径，如 data.items，为空时响应本身为数组
+    Items string
+    // Cursor 下一页游标的 JSON 路径，如 meta.next_cursor，为空值时结束
+    Cursor string
+    // CursorParam 游标查询参数名，默认 cursor
+    CursorParam string
+    // PageParam 页码查询参数名，页码递增直到返回空页
+    PageParam string
+    // StartPage 起始页码，默认 1
+    StartPage int
+    // MaxPages 最大页数，0 表示不限制
+    MaxPages int
+}
+
+// GetAllPages 自动翻页获取全部条目
+func GetAllPages[T any](ctx context.Context, url string, spec PageSpec, v ...interface{}) ([]T, error) {
+    var items []T
+    err := EachPage(ctx, url, spec, func(item T) error {
+        items = append(items, item)
+        return nil
+    }, v...)
+    return items, err
+}
+
+// EachPage 自动翻页，逐条回调，回调返回错误时停止
+func EachPage[T any](ctx context.Context, url string, spec PageSpec, fn func(T) error, v ...interface{}) error {
+    page := spec.StartPage
+    if page == 0 {
+        page = 1
+    }
+    if spec.CursorParam == "" {
+        spec.CursorParam = "cursor"
+    }
+
+    next := url
+    if spec.PageParam != "" {
+        next = setQuery(url, spec.PageParam, strconv.Itoa(page))
+    }
+
+    for count := 1; next != ""; count++ {
+        rep, err := doResponse(http.MethodGet, next, append([]interface{}{ctx}, v...)...)
+        if err != nil {
+            return err
+        }
+
+        var body interface{}
+        if err = jsoniter.Unmarshal(rep.Bytes(), &body); err != nil {
+            return errors.Wrapf(err, "page: %s", next)
+        }
+
+        value, ok := jsonPath(body, spec.Items)
+        if !ok {
+            return errors.Errorf("page items not found: %s", spec.Items)
+        }
+        list, ok := value.([]interface{})
+        if !ok && value != nil {
+            return errors.Errorf("page items is not array: %s", spec.Items)
+        }
+
+        for _, raw := range list {
+            var item T
+            data, _ := jsoniter.Marshal(raw)
+            if err = jsoniter.Unmarshal(data, &item); err != nil {
+                return errors.WithStack(err)
+            }
+            if err = fn(item); err != nil {
+                return err
+            }
+        }
+
+        if spec.MaxPages > 0 && count >= spec.MaxPages {
+            return nil
+        }
+
+        current := next
+        next = ""
+        switch {
+        case spec.Cursor != "":
+            if cursor, ok := jsonPath(body, spec.Cursor); ok && cursor != nil {
+                if value := jsonString(cursor); value != "" {
+                    next = setQuery(current, spec.CursorParam, value)
+                }
+            }
+        case spec.PageParam != "":
+            if len(list) > 0 {
+                page++
+                next = setQuery(current, spec.PageParam, strconv.Itoa(page))
+            }
+        default:
+            if link := parseLink(rep.Response().Header.Values("Link"))["next"]; link != "" {
+                next = resolveURL(current, link)
+            }
+        }
+    }
+    return nil
+}
+
+// setQuery 设置查询参数
+func setQuery(rawurl, key, value string) string {
+    u, err := neturl.Parse(rawurl)
+    if err != nil {
+        return rawurl
+    }
+    query := u.Query()
+    query.Set(key, value)
+    u.RawQuery = query.Encode()
+    return u.String()
+}
+
+// resolveURL 解析相对地址
+func resolveURL(base, ref string) string {
+    b, err := neturl.Parse(base)
+    if err != nil {
+        return ref
+    }
+    r, err := neturl.Parse(ref)
+    if err != nil {
+        return ref
+    }
+    return b.ResolveReference(r).String()
+}
+
+// jsonString JSON 值转字符串
+func jsonString(value interface{}) string {
+    if s, ok := value.(string); ok {
+        return s
+    }
+    data, _ := jsoniter.Marshal(value)
+    return string(data)
+}
+
+// linkPattern RFC 5988 Link 响应头
+var linkPattern = regexp.MustCompile(`<([^>]*)>\s*((?:;\s*[^;,]+)*)`)
+
+// parseLink 解析 Link 响应头，返回 rel 与地址的对应关系
+func parseLink(values []string) map[string]string {
+    links := make(map[string]string)
+    for _, value := range values {
+        for _, match := range linkPattern.FindAllStringSubmatch(value, -1) {
+            for _, param := range strings.Split(match[2], ";") {
+                key, val, ok := strings.Cut(strings.TrimSpace(param), "=")
+                if !ok || !strings.EqualFold(strings.TrimSpace(key), "rel") {
+                    continue
+                }
+                for _, rel := range strings.Fields(strings.Trim(strings.TrimSpace(val), `"`)) {
+                    links[strings.ToLower(rel)] = match[1]
+                }
+            }
+        }
+    }
+    return links
+}
@@ -0,0 +1,206 @@
+package req
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// Record 提取结果记录
+type Record map[string]interface{}
+
+// Transform 记录转换，返回 nil 时丢弃记录
+type Transform func(record Record) (Record, error)
+
+// Sink 记录输出
+type Sink interface {
+    Write(record Record) error
+    Close() error
+}
+
+// SinkFunc 回调输出
+type SinkFunc func(record Record) error
+
+// Write 输出记录
+func (f SinkFunc) Write(record Record) error {
+    return f(record)
+}
+
+// Close 关闭输出
+func (f SinkFunc) Close() error {
+    return nil
+}
+
+// chanSink 通道输出
+type chanSink chan<- Record
+
+// ChanSink 通道输出，流水线结束时关闭通道
+func ChanSink(ch chan<- Record) Sink {
+    return chanSink(ch)
+}
+
+func (s chanSink) Write(record Record) error {
+    s <- record
+    return nil
+}
+
+func (s chanSink) Close() error {
+    close(s)
+    return nil
+}
+
+// jsonlSink JSONL 文件输出
+type jsonlSink struct {
+    file *os.File
+}
+
+// JSONLSink JSONL 文件输出，每条记录一行
+func JSONLSink(fileName string) (Sink, error) {
+    if err := os.MkdirAll(filepath.Dir(fileName), os.ModePerm); err != nil {
+        return nil, errors.WithStack(err)
+    }
+    file, err := os.Create(fileName)
+    if err != nil {
+        return nil, errors.WithStack(err)
+    }
+    return &jsonlSink{file: file}, nil
+}
+
+func (s *jsonlSink) Write(record Record) error {
+    data, err := jsoniter.Marshal(record)
+    if err != nil {
+        return errors.WithStack(err)
+    }
+    _, err = s.file.Write(append(data, '\n'))
+    return errors.WithStack(err)
+}
+
+func (s *jsonlSink) Close() error {
+    return errors.WithStack(s.file.Close())
+}
+
+// Pipeline 抓取流水线，Fetch → Parse → Transform → Sink
+type Pipeline struct {
+    // Items 条目选择器，HTML 内容为 CSS 选择器，JSON 内容为点分路径，每个匹配生成一条记录，为空时整个页面为一条记录
+    Items string
+    // Format 内容格式 html/json，为空时根据内容识别
+    Format string
+    // Rules 字段提取规则
+    Rules []Rule
+    // Transforms 依次执行的记录转换
+    Transforms []Transform
+    // Sink 记录输出，为 nil 时丢弃
+    Sink Sink
+}
+
+// PipelineConfig 流水线配置文件
+type PipelineConfig struct {
+    Items  string `json:"items" yaml:"items"`
+    Format string `json:"format" yaml:"format"`
+    Rules  []Rule `json:"rules" yaml:"rules"`
+    // Output JSONL 输出文件
+    Output string `json:"output" yaml:"output"`
+}
+
+// LoadPipeline 读取流水线配置文件，扩展名为 .yaml/.yml 时按 YAML 解析，否则按 JSON 解析
+func LoadPipeline(path string) (*Pipeline, error) {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, errors.WithStack(err)
+    }
+
+    var c PipelineConfig
+    switch strings.ToLower(filepath.Ext(path)) {
+    case ".yaml", ".yml":
+        err = yaml.Unmarshal(data, &c)
+    default:
+        err = jsoniter.Unmarshal(data, &c)
+    }
+    if err != nil {
+        return nil, errors.Wrapf(err, "pipeline: %s", path)
+    }
+
+    p := &Pipeline{Items: c.Items, Format: c.Format, Rules: c.Rules}
+    if c.Output != "" {
+        if p.Sink, err = JSONLSink(c.Output); err != nil {
+            return nil, err
+        }
+    }
+    return p, nil
+}
+
+// Run 从迭代器读取链接批量抓取，按规则提取记录并转换后写入 Sink，结束后关闭 Sink
+// 单个链接失败不影响其它链接，返回第一个错误
+func (p *Pipeline) Run(ctx context.Context, next URLIterator, v ...interface{}) error {
+    var (
+        mu    sync.Mutex
+        first error
+    )
+    fail := func(url string, err error) {
+        mu.Lock()
+        if first == nil {
+            first = errors.Wrapf(err, "pipeline: %s", url)
+        }
+        mu.Unlock()
+    }
+
+    opts, _ := splitOptions(v)
+    err := streamBatch(ctx, next, opts, func(ctx context.Context, url string) error {
+        body, err := Get(url, append([]interface{}{ctx}, v...)...)
+        if err == nil {
+            var records []Record
+            if records, err = p.Parse(body); err == nil {
+                mu.Lock()
+                err = p.emit(records)
+                mu.Unlock()
+            }
+        }
+        if err != nil && ctx.Err() == nil {
+            fail(url, err)
+        }
+        return err
+    })
+
+    if p.Sink != nil {
+        if closeErr := p.Sink.Close(); err == nil {
+            err = closeErr
+        }
+    }
+    if err != nil {
+        return err
+    }
+    return first
+}
+
+// Parse 按规则从内容提取记录
+func (p *Pipeline) Parse(body string) ([]Record, error) {
+    return extractRecords(body, p.Format, p.Items, p.Rules)
+}
+
+// emit 转换记录并写入 Sink
+func (p *Pipeline) emit(records []Record) error {
+    for _, record := range records {
+        var err error
+        for _, transform := range p.Transforms {
+            if record, err = transform(record); err != nil || record == nil {
+                break
+            }
+        }
+        if err != nil {
+            return err
+        }
+        if record == nil || p.Sink == nil {
+            continue
+        }
+        if err = p.Sink.Write(record); err != nil {
+            return err
+        }
+    }
+    return nil
+}
@@ -0,0 +1,65 @@
+package req
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// pollMaxFactor 轮询退避最大倍数
+const pollMaxFactor = 10
+
+// Poll 轮询请求直到 until 返回 true，间隔按 1.5 倍退避并加入随机抖动，最长为 interval 的 10 倍
+// 请求失败时继续轮询，until 返回错误时立即停止
+func Poll(ctx context.Context, url string, interval time.Duration, until func(*Response) (bool, error), v ...interface{}) (*Response, error) {
+    var (
+        wait    = interval
+        lastErr error
+    )
+
+    for {
+        rep, attempts, err := doAttempts(http.MethodGet, url, append([]interface{}{ctx}, v...)...)
+        if err == nil {
+            res := newResponse(rep)
+            res.Attempts = attempts
+            done, err := until(res)
+            if err != nil {
+                res.Close()
+                return nil, err
+            }
+            if done {
+                return res, nil
+            }
+            res.Close()
+        }
+        lastErr = err
+
+        select {
+        case <-ctx.Done():
+            if lastErr != nil {
+                return nil, errors.Wrap(lastErr, ctx.Err().Error())
+            }
+            return nil, errors.WithStack(ctx.Err())
+        case <-conf().clock.After(jitter(wait)):
+        }
+
+        if wait = wait * 3 / 2; wait > interval*pollMaxFactor {
+            wait = interval * pollMaxFactor
+        }
+    }
+}
+
+// jitter 在 ±20% 范围内随机调整时长
+func jitter(d time.Duration) time.Duration {
+    if d <= 0 {
+        return d
+    }
+    delta := int64(d) / 5
+    if delta == 0 {
+        return d
+    }
+    return d - time.Duration(delta) + time.Duration(rand.Int63n(2*delta))
+}
@@ -0,0 +1,58 @@
+package req
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
+)
+
+// Preconnect 预先解析域名并建立到主机的连接，连接保留在连接池中供后续请求复用，减少批量请求首个请求的延迟
+// host 可为主机名、host:port 或链接，未指定协议时使用 https；服务端返回任意状态码均视为成功
+func Preconnect(hosts ...string) error {
+    return PreconnectContext(context.Background(), hosts...)
+}
+
+// PreconnectContext 同 Preconnect，由 ctx 控制超时与取消
+func PreconnectContext(ctx context.Context, hosts ...string) error {
+    var group errgroup.Group
+    group.SetLimit(conf().limit)
+    for _, host := range hosts {
+        url := host
+        if !strings.Contains(url, "://") {
+            url = "https://" + url
+        }
+        group.Go(func() error {
+            return errors.Wrapf(preconnect(ctx, url), "preconnect: %s", url)
+        })
+    }
+    return group.Wait()
+}
+
+// preconnect 发送 HEAD 请求建立连接，读取完响应后连接回到连接池
+func preconnect(ctx context.Context, url string) error {
+    if err := ValidateURL(url); err != nil {
+        return err
+    }
+    done, err := track()
+    if err != nil {
+        return err
+    }
+    defer done()
+
+    request, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+    if err != nil {
+        return errors.WithStack(err)
+    }
+    setCommonHeader(request)
+
+    resp, err := conf().client.Do(request)
+    if err != nil {
+        return errors.WithStack(err)
+    }
+    _, _ = io.Copy(io.Discard, resp.Body)
+    return errors.WithStack(resp.Body.Close())
+}
@@ -0,0 +1,397 @@
+package req
+
+import (
+	"container/heap"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+)
+
+// QueueItem 队列请求
+type QueueItem struct {
+    // ID 请求 ID，为空时入队自动生成
+    ID string `json:"id"`
+    // Method 请求方法，默认 GET
+    Method  string            `json:"method"`
+    URL     string            `json:"url"`
+    Headers map[string]string `json:"headers,omitempty"`
+    Body    string            `json:"body,omitempty"`
+    Labels  map[string]string `json:"labels,omitempty"`
+    // NotBefore 最早执行时间，零值时立即执行
+    NotBefore time.Time `json:"not_before"`
+    // Every 重复执行间隔，为 0 时只执行一次
+    Every time.Duration `json:"every,omitempty"`
+    // Cron 五段 cron 表达式（分 时 日 月 周），设置时忽略 Every
+    Cron string `json:"cron,omitempty"`
+}
+
+// Queue 延迟与定时请求队列，到期的请求通过共享调度执行，与批量请求共用并发限制
+type Queue struct {
+    mutex   sync.Mutex
+    entries queueHeap
+    index   map[string]*queueEntry
+    wake    chan struct{}
+    fn      func(item QueueItem, body string, err error)
+    v       []interface{}
+    // stateFile 队列状态文件，Close 时保存
+    stateFile string
+}
+
+// queueEntry 队列条目
+type queueEntry struct {
+    item QueueItem
+    cron *cronSchedule
+    // at 下一次执行时间
+    at  time.Time
+    pos int
+}
+
+// NewQueue 创建队列，每次执行完成回调 fn，v 为全部请求共用的参数
+func NewQueue(fn func(item QueueItem, body string, err error), v ...interface{}) *Queue {
+    return &Queue{
+        index: make(map[string]*queueEntry),
+        wake:  make(chan struct{}, 1),
+        fn:    fn,
+        v:     v,
+    }
+}
+
+// Enqueue 加入队列，返回请求 ID，相同 ID 的请求会被替换
+func (q *Queue) Enqueue(item QueueItem) (string, error) {
+    if item.Method == "" {
+        item.Method = http.MethodGet
+    }
+    if err := ValidateURL(item.URL); err != nil {
+        return "", err
+    }
+    entry := &queueEntry{item: item, at: item.NotBefore}
+    if item.Cron != "" {
+        schedule, err := parseCron(item.Cron)
+        if err != nil {
+            return "", err
+        }
+        entry.cron = schedule
+        if now := conf().clock.Now(); entry.at.Before(now) {
+            entry.at = schedule.next(now)
+        } else {
+            entry.at = schedule.next(entry.at.Add(-time.Minute))
+        }
+        if entry.at.IsZero() {
+            return "", errors.Errorf("cron: no matching time: %s", item.Cron)
+        }
+    } else if entry.at.IsZero() {
+        // 立即执行，重复请求从当前时间开始计算间隔
+        entry.at = conf().clock.Now()
+    }
+    if entry.item.ID == "" {
+        entry.item.ID = queueID()
+    }
+
+    q.mutex.Lock()
+    if old, ok := q.index[entry.item.ID]; ok {
+        heap.Remove(&q.entries, old.pos)
+    }
+    q.index[entry.item.ID] = entry
+    heap.Push(&q.entries, entry)
+    q.mutex.Unlock()
+    q.notify()
+    return entry.item.ID, nil
+}
+
+// Persist 从状态文件恢复队列并在 Close 时保存等待执行的请求，文件不存在时忽略
+func (q *Queue) Persist(fileName string) error {
+    data, err := os.ReadFile(fileName)
+    if err != nil && !os.IsNotExist(err) {
+        return errors.WithStack(err)
+    }
+    if len(data) > 0 {
+        var items []QueueItem
+        if err = jsoniter.Unmarshal(data, &items); err != nil {
+            return errors.Wrapf(err, "queue state: %s", fileName)
+        }
+        for _, item := range items {
+            if _, err = q.Enqueue(item); err != nil {
+                return err
+            }
+        }
+    }
+
+    q.mutex.Lock()
+    q.stateFile = fileName
+    q.mutex.Unlock()
+
+    shutdown.mutex.Lock()
+    shutdown.queues = append(shutdown.queues, q)
+    shutdown.mutex.Unlock()
+    return nil
+}
+
+// save 保存等待执行的请求到状态文件
+func (q *Queue) save() error {
+    q.mutex.Lock()
+    fileName := q.stateFile
+    q.mutex.Unlock()
+    if fileName == "" {
+        return nil
+    }
+
+    data, err := jsoniter.Marshal(q.Pending())
+    if err != nil {
+        return errors.WithStack(err)
+    }
+    return writeCache(fileName, data)
+}
+
+// Cancel 取消队列中的请求，已开始执行的请求不受影响，重复请求不再执行
+func (q *Queue) Cancel(id string) bool {
+    q.mutex.Lock()
+    defer q.mutex.Unlock()
+
+    entry, ok := q.index[id]
+    if ok {
+        heap.Remove(&q.entries, entry.pos)
+        delete(q.index, id)
+    }
+    return ok
+}
+
+// Pending 等待执行的请求，按执行时间排序，NotBefore 为下一次执行时间
+func (q *Queue) Pending() []QueueItem {
+    q.mutex.Lock()
+    items := make([]QueueItem, 0, len(q.entries))
+    for _, entry := range q.entries {
+        item := entry.item
+        item.NotBefore = entry.at
+        items = append(items, item)
+    }
+    q.mutex.Unlock()
+
+    sort.Slice(items, func(i, j int) bool { return items[i].NotBefore.Before(items[j].NotBefore) })
+    return items
+}
+
+// Run 持续执行到期的请求直到 ctx 结束或调用 Close，重复请求执行后按间隔或 cron 表达式重新入队
+func (q *Queue) Run(ctx context.Context) error {
+    for {
+        q.mutex.Lock()
+        var wait <-chan time.Time
+        if len(q.entries) > 0 {
+            wait = conf().clock.After(q.entries[0].at.Sub(conf().clock.Now()))
+        }
+        q.mutex.Unlock()
+
+        select {
+        case <-ctx.Done():
+            return errors.WithStack(ctx.Err())
+        case <-shutdown.done:
+            return errors.WithStack(ErrClosed)
+        case <-q.wake:
+            continue
+        case <-wait:
+        }
+
+        for _, item := range q.due(conf().clock.Now()) {
+            item := item
+            getScheduler().submit(newBatchItem(0, item.URL, 0, func() error {
+                body, err := q.do(ctx, item)
+                if q.fn != nil && ctx.Err() == nil {
+                    q.fn(item, body, err)
+                }
+                return err
+            }))
+        }
+    }
+}
+
+// due 取出到期的请求，重复请求计算下一次执行时间后放回
+func (q *Queue) due(now time.Time) []QueueItem {
+    q.mutex.Lock()
+    defer q.mutex.Unlock()
+
+    var items []QueueItem
+    for len(q.entries) > 0 && !q.entries[0].at.After(now) {
+        entry := q.entries[0]
+        items = append(items, entry.item)
+
+        switch {
+        case entry.cron != nil && !entry.cron.next(now).IsZero():
+            entry.at = entry.cron.next(now)
+        case entry.cron == nil && entry.item.Every > 0:
+            // 错过的执行不补发，直接跳到 now 之后的下一次
+            every := entry.item.Every
+            entry.at = entry.at.Add((now.Sub(entry.at)/every + 1) * every)
+        default:
+            heap.Pop(&q.entries)
+            delete(q.index, entry.item.ID)
+            continue
+        }
+        heap.Fix(&q.entries, 0)
+    }
+    return items
+}
+
+// do 执行队列请求
+func (q *Queue) do(ctx context.Context, item QueueItem) (string, error) {
+    v := append([]interface{}{ctx}, q.v...)
+    if len(item.Headers) > 0 {
+        v = append(v, Headers(item.Headers))
+    }
+    if item.Body != "" {
+        v = append(v, []byte(item.Body))
+    }
+    if len(item.Labels) > 0 {
+        v = append(v, WithLabels(item.Labels))
+    }
+    return doRequest(item.Method, item.URL, v...)
+}
+
+// notify 唤醒 Run 重新计算等待时间
+func (q *Queue) notify() {
+    select {
+    case q.wake <- struct{}{}:
+    default:
+    }
+}
+
+// queueID 随机请求 ID
+func queueID() string {
+    b := make([]byte, 8)
+    _, _ = rand.Read(b)
+    return hex.EncodeToString(b)
+}
+
+// queueHeap 按执行时间排序的队列
+type queueHeap []*queueEntry
+
+func (h queueHeap) Len() int           { return len(h) }
+func (h queueHeap) Less(i, j int) bool { return h[i].at.Before(h[j].at) }
+func (h queueHeap) Swap(i, j int) {
+    h[i], h[j] = h[j], h[i]
+    h[i].pos, h[j].pos = i, j
+}
+func (h *queueHeap) Push(x interface{}) {
+    entry := x.(*queueEntry)
+    entry.pos = len(*h)
+    *h = append(*h, entry)
+}
+func (h *queueHeap) Pop() interface{} {
+    old := *h
+    entry := old[len(old)-1]
+    *h = old[:len(old)-1]
+    return entry
+}
+
+// cronSchedule cron 表达式各字段允许的取值
+type cronSchedule struct {
+    minute, hour, dom, month, dow uint64
+    // anyDom/anyDow 日/周字段为 *，均有限制时按 cron 惯例满足其一即可
+    anyDom, anyDow bool
+}
+
+// cronFields cron 字段取值范围
+var cronFields = [5][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 6}}
+
+// parseCron 解析五段 cron 表达式，支持 *、*/n、a-b、a-b/n 与逗号分隔的列表
+func parseCron(expr string) (*cronSchedule, error) {
+    fields := strings.Fields(expr)
+    if len(fields) != 5 {
+        return nil, errors.Errorf("cron: expected 5 fields, got %d: %s", len(fields), expr)
+    }
+
+    var bits [5]uint64
+    for i, field := range fields {
+        for _, part := range strings.Split(field, ",") {
+            min, max := cronFields[i][0], cronFields[i][1]
+            step := 1
+            if j := strings.IndexByte(part, '/'); j >= 0 {
+                n, err := strconv.Atoi(part[j+1:])
+                if err != nil || n <= 0 {
+                    return nil, errors.Errorf("cron: invalid step %q: %s", part, expr)
+                }
+                step, part = n, part[:j]
+            }
+
+            lo, hi := min, max
+            if part != "*" {
+                bounds := strings.SplitN(part, "-", 2)
+                var err error
+                if lo, err = strconv.Atoi(bounds[0]); err != nil {
+                    return nil, errors.Errorf("cron: invalid value %q: %s", part, expr)
+                }
+                hi = lo
+                if len(bounds) == 2 {
+                    if hi, err = strconv.Atoi(bounds[1]); err != nil {
+                        return nil, errors.Errorf("cron: invalid value %q: %s", part, expr)
+                    }
+                } else if step > 1 {
+                    hi = max
+                }
+            }
+            // 周日可写作 7
+            if i == 4 && hi == 7 {
+                bits[i] |= 1
+                if lo == 7 {
+                    continue
+                }
+                hi = 6
+            }
+            if lo < min || hi > max || lo > hi {
+                return nil, errors.Errorf("cron: value %q out of range: %s", part, expr)
+            }
+            for n := lo; n <= hi; n += step {
+                bits[i] |= 1 << uint(n)
+            }
+        }
+    }
+
+    return &cronSchedule{
+        minute: bits[0], hour: bits[1], dom: bits[2], month: bits[3], dow: bits[4],
+        anyDom: fields[2] == "*", anyDow: fields[4] == "*",
+    }, nil
+}
+
+// next 晚于 t 的下一次执行时间，五年内无匹配时返回零值
+func (s *cronSchedule) next(t time.Time) time.Time {
+    t = t.Truncate(time.Minute).Add(time.Minute)
+    for limit := t.AddDate(5, 0, 0); t.Before(limit); {
+        switch {
+        case s.month&(1<<uint(t.Month())) == 0:
+            t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+        case !s.dayMatch(t):
+            t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+        case s.hour&(1<<uint(t.Hour())) == 0:
+            t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+        case s.minute&(1<<uint(t.Minute())) == 0:
+            t = t.Add(time.Minute)
+        default:
+            return t
+        }
+    }
+    return time.Time{}
+}
+
+// dayMatch 日期是否匹配，日与周均有限制时满足其一即可
+func (s *cronSchedule) dayMatch(t time.Time) bool {
+    dom := s.dom&(1<<uint(t.Day())) != 0
+    dow := s.dow&(1<<uint(t.Weekday())) != 0
+    switch {
+    case s.anyDom && s.anyDow:
+        return true
+    case s.anyDom:
+        return dow
+    case s.anyDow:
+        return dom
+    default:
+        return dom || dow
+    }
+}
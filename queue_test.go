@@ -0,0 +1,27 @@
+package req
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQueueEveryWithoutNotBefore(t *testing.T) {
+    clock := withFakeClock(t)
+    q := NewQueue(nil)
+    if _, err := q.Enqueue(QueueItem{URL: "http://api.example.com/tick", Every: time.Second}); err != nil {
+        t.Fatal(err)
+    }
+
+    start := clock.Now()
+    if items := q.due(start); len(items) != 1 {
+        t.Fatalf("due = %d, want immediate run", len(items))
+    }
+    // 错过多次执行后只执行一次，下一次在当前时间之后
+    now := start.Add(time.Hour + time.Millisecond*500)
+    if items := q.due(now); len(items) != 1 {
+        t.Fatalf("due after an hour = %d, want 1", len(items))
+    }
+    if next := q.entries[0].at; !next.Equal(start.Add(time.Hour + time.Second)) {
+        t.Fatalf("next = %v, want %v", next.Sub(start), time.Hour+time.Second)
+    }
+}
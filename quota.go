@@ -0,0 +1,174 @@
+package req
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Quota 响应头中的接口配额
+type Quota struct {
+    // Limit 窗口内允许的请求数量，未返回时为 0
+    Limit int
+    // Remaining 剩余请求数量
+    Remaining int
+    // Reset 配额重置时间
+    Reset time.Time
+    // Updated 最近一次更新时间
+    Updated time.Time
+}
+
+// hostQuota 主机配额与下一次允许请求的时间
+type hostQuota struct {
+    Quota
+    next time.Time
+}
+
+// quotas 按主机记录的配额
+var quotas = struct {
+    mutex sync.Mutex
+    hosts map[string]*hostQuota
+}{hosts: map[string]*hostQuota{}}
+
+// SetQuotaPacing 开启按配额响应头控制请求节奏，剩余配额在重置前均匀分配，只剩最后一次时等待重置，默认关闭
+// 支持 X-RateLimit-Limit/Remaining/Reset、RateLimit-Limit/Remaining/Reset 与 RateLimit 响应头
+func SetQuotaPacing(enable bool) {
+    updateConfig(func(c *config) { c.quotaPacing = enable })
+}
+
+// HostQuota 主机最近一次响应中的配额，host 为链接中的主机与端口，如 api.github.com
+func HostQuota(host string) (Quota, bool) {
+    quotas.mutex.Lock()
+    defer quotas.mutex.Unlock()
+    q, ok := quotas.hosts[host]
+    if !ok {
+        return Quota{}, false
+    }
+    return q.Quota, true
+}
+
+// parseQuota 解析配额响应头，未包含剩余数量时 ok 为 false
+func parseQuota(header http.Header, now time.Time) (q Quota, ok bool) {
+    var limit, remaining, reset string
+    for _, prefix := range []string{"X-RateLimit-", "RateLimit-"} {
+        if remaining = header.Get(prefix + "Remaining"); remaining != "" {
+            limit, reset = header.Get(prefix+"Limit"), header.Get(prefix+"Reset")
+            break
+        }
+    }
+    if remaining == "" {
+        // RateLimit: limit=100, remaining=50, reset=30 或 "default";r=50;t=30
+        for _, part := range strings.FieldsFunc(header.Get("RateLimit"), func(r rune) bool { return r == ',' || r == ';' }) {
+            key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+            switch key {
+            case "limit":
+                limit = value
+            case "remaining", "r":
+                remaining = value
+            case "reset", "t":
+                reset = value
+            }
+        }
+    }
+
+    n, err := strconv.Atoi(leadingNumber(remaining))
+    if err != nil {
+        return q, false
+    }
+    q.Remaining, q.Updated = n, now
+    q.Limit, _ = strconv.Atoi(leadingNumber(limit))
+    if seconds, err := strconv.ParseInt(leadingNumber(reset), 10, 64); err == nil {
+        // X-RateLimit-Reset 多为 Unix 时间戳，RateLimit-Reset 为剩余秒数
+        if seconds > 1e9 {
+            q.Reset = time.Unix(seconds, 0)
+        } else {
+            q.Reset = now.Add(time.Duration(seconds) * time.Second)
+        }
+    }
+    return q, true
+}
+
+// leadingNumber 去除首尾空白后开头的数字，如 "100, 100;w=60" 取 100
+func leadingNumber(s string) string {
+    s = strings.TrimSpace(s)
+    end := 0
+    for end < len(s) && s[end] >= '0' && s[end] <= '9' {
+        end++
+    }
+    return s[:end]
+}
+
+// recordQuota 记录响应中的配额
+func recordQuota(host string, header http.Header, now time.Time) {
+    q, ok := parseQuota(header, now)
+    if !ok {
+        return
+    }
+
+    quotas.mutex.Lock()
+    defer quotas.mutex.Unlock()
+    if old, ok := quotas.hosts[host]; ok {
+        old.Quota = q
+        return
+    }
+    quotas.hosts[host] = &hostQuota{Quota: q}
+}
+
+// paceQuota 按剩余配额等待到允许请求的时间
+func paceQuota(ctx context.Context, clock Clock, host string) error {
+    now := clock.Now()
+    quotas.mutex.Lock()
+    q, ok := quotas.hosts[host]
+    if !ok || q.Reset.IsZero() || !now.Before(q.Reset) {
+        quotas.mutex.Unlock()
+        return nil
+    }
+
+    at := q.next
+    if at.Before(now) {
+        at = now
+    }
+    if q.Remaining <= 1 {
+        at = q.Reset
+    } else {
+        q.next = at.Add(q.Reset.Sub(now) / time.Duration(q.Remaining))
+        // 等待中的请求预先占用配额，下一次响应更新为准确值
+        q.Remaining--
+    }
+    quotas.mutex.Unlock()
+
+    if wait := at.Sub(now); wait > 0 {
+        select {
+        case <-ctx.Done():
+            return errors.WithStack(ctx.Err())
+        case <-clock.After(wait):
+        }
+    }
+    return nil
+}
+
+// quotaTransport 记录配额响应头并按配额控制请求节奏的传输层
+type quotaTransport struct {
+    base http.RoundTripper
+}
+
+// RoundTrip 等待配额后发送请求
+func (t *quotaTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+    c := conf()
+    if c.quotaPacing {
+        if err := paceQuota(r.Context(), c.clock, r.URL.Host); err != nil {
+            return nil, err
+        }
+    }
+
+    rep, err := t.base.RoundTrip(r)
+    if err == nil {
+        recordQuota(r.URL.Host, rep.Header, c.clock.Now())
+    }
+    return rep, err
+}
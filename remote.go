@@ -0,0 +1,315 @@
+package req
+
+import (
+	"context"
+	"io"
+	"net"
+	neturl "net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/jlaffaye/ftp"
+	"github.com/pkg/errors"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+	xproxy "golang.org/x/net/proxy"
+)
+
+// RemoteAuth FTP/SFTP 认证信息
+type RemoteAuth struct {
+    // User 用户名，FTP 为空时使用 anonymous
+    User     string
+    Password string
+    // PrivateKey SFTP 私钥，PEM 格式
+    PrivateKey []byte
+    // Passphrase 私钥密码
+    Passphrase string
+    // KnownHosts SFTP known_hosts 文件，为空时使用 ~/.ssh/known_hosts
+    KnownHosts string
+    // InsecureIgnoreHostKey 不校验 SFTP 主机密钥，仅用于测试
+    InsecureIgnoreHostKey bool
+}
+
+// WithRemoteAuth 设置 Download 访问 ftp:// 与 sftp:// 链接的认证信息
+func WithRemoteAuth(auth RemoteAuth) Option {
+    return func(o *options) {
+        o.remoteAuth = &auth
+    }
+}
+
+// WithResume Download 目标文件已存在时从已有长度续传，HTTP 服务端不支持 Range 时重新下载
+func WithResume() Option {
+    return func(o *options) {
+        o.resume = true
+    }
+}
+
+// resumeOffset 续传时目标文件的已有长度，不续传或文件不存在时为 0
+func resumeOffset(fileName string, resume bool) int64 {
+    if !resume {
+        return 0
+    }
+    info, err := os.Stat(fileName)
+    if err != nil {
+        return 0
+    }
+    return info.Size()
+}
+
+// openDownload 打开下载目标文件，offset 大于 0 时追加写入，否则清空
+func openDownload(fileName string, offset int64) (*os.File, error) {
+    if offset > 0 {
+        file, err := os.OpenFile(fileName, os.O_WRONLY|os.O_APPEND, 0644)
+        return file, errors.WithStack(err)
+    }
+    file, err := os.Create(fileName)
+    return file, errors.WithStack(err)
+}
+
+// remoteAddr 链接的主机与端口
+func remoteAddr(u *neturl.URL, port string) string {
+    if u.Port() != "" {
+        port = u.Port()
+    }
+    return net.JoinHostPort(u.Hostname(), port)
+}
+
+// remoteDial FTP/SFTP 建立连接的函数，与 HTTP 请求一样经过请求目标限制、本地地址绑定与代理
+// HTTP 代理使用 CONNECT 隧道，每次连接受超时时间与 ctx 控制
+func remoteDial(ctx context.Context, opts *options) func(network, addr string) (net.Conn, error) {
+    c := conf()
+    dial := dialContext(c, requestLocalAddr(opts))
+    return func(network, addr string) (net.Conn, error) {
+        host, _, err := net.SplitHostPort(addr)
+        if err != nil {
+            return nil, errors.WithStack(err)
+        }
+        if guard := c.guard; guard != nil {
+            if err = guard.checkHost(host); err != nil {
+                return nil, errors.WithStack(err)
+            }
+        }
+
+        ctx := ctx
+        if c.timeout > 0 {
+            var cancel context.CancelFunc
+            ctx, cancel = context.WithTimeout(ctx, c.timeout)
+            defer cancel()
+        }
+        proxy, err := proxyFor("https://" + addr)
+        if err != nil {
+            return nil, err
+        }
+        if proxy != nil && (proxy.Scheme == "socks5" || proxy.Scheme == "socks5h") {
+            dialer, err := xproxy.FromURL(proxy, contextDialer(dial))
+            if err != nil {
+                return nil, errors.WithStack(err)
+            }
+            conn, err := dialer.(xproxy.ContextDialer).DialContext(ctx, network, addr)
+            return conn, errors.WithStack(err)
+        }
+        return dialTunnel(dial, nil)(ctx, network, addr)
+    }
+}
+
+// contextDialer 函数形式的代理拨号器
+type contextDialer func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// Dial 建立连接
+func (d contextDialer) Dial(network, addr string) (net.Conn, error) {
+    return d(context.Background(), network, addr)
+}
+
+// DialContext 建立连接
+func (d contextDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+    return d(ctx, network, addr)
+}
+
+// ftpDownload 通过 FTP 下载文件
+func ftpDownload(ctx context.Context, u *neturl.URL, fileName string, opts *options) error {
+    auth := RemoteAuth{User: "anonymous", Password: "anonymous"}
+    if opts.remoteAuth != nil && opts.remoteAuth.User != "" {
+        auth = *opts.remoteAuth
+    }
+
+    conn, err := ftp.Dial(remoteAddr(u, "21"), ftp.DialWithContext(ctx), ftp.DialWithDialFunc(remoteDial(ctx, opts)))
+    if err != nil {
+        return errors.WithStack(err)
+    }
+    defer conn.Quit()
+    if err = conn.Login(auth.User, auth.Password); err != nil {
+        return errors.WithStack(err)
+    }
+
+    offset := resumeOffset(fileName, opts.resume)
+    if size, err := conn.FileSize(u.Path); err == nil {
+        if err = checkDiskSpace(fileName, size-offset); err != nil {
+            return err
+        }
+    }
+    body, err := conn.RetrFrom(u.Path, uint64(offset))
+    if err != nil {
+        return errors.WithStack(err)
+    }
+    defer body.Close()
+
+    // 远端文件打开成功后再打开目标文件，失败时不破坏已有内容
+    file, err := openDownload(fileName, offset)
+    if err != nil {
+        return err
+    }
+    defer file.Close()
+
+    _, err = io.Copy(file, readerWithContext(ctx, body))
+    return errors.WithStack(err)
+}
+
+// sshDial 经 remoteDial 建立 SSH 连接，握手受超时时间与 ctx 控制
+func sshDial(ctx context.Context, addr string, config *ssh.ClientConfig, opts *options) (*ssh.Client, error) {
+    conn, err := remoteDial(ctx, opts)("tcp", addr)
+    if err != nil {
+        return nil, err
+    }
+    if config.Timeout > 0 {
+        conn.SetDeadline(time.Now().Add(config.Timeout))
+    }
+
+    // ctx 取消时中断握手
+    done, exited := make(chan struct{}), make(chan struct{})
+    go func() {
+        defer close(exited)
+        select {
+        case <-ctx.Done():
+            conn.SetDeadline(time.Unix(1, 0))
+        case <-done:
+        }
+    }()
+    sc, chans, reqs, err := ssh.NewClientConn(conn, addr, config)
+    close(done)
+    <-exited
+    if err == nil && ctx.Err() != nil {
+        sc.Close()
+        err = ctx.Err()
+    }
+    if err != nil {
+        conn.Close()
+        return nil, errors.WithStack(err)
+    }
+
+    conn.SetDeadline(time.Time{})
+    return ssh.NewClient(sc, chans, reqs), nil
+}
+
+// sftpDownload 通过 SFTP 下载文件
+func sftpDownload(ctx context.Context, u *neturl.URL, fileName string, opts *options) error {
+    if opts.remoteAuth == nil || opts.remoteAuth.User == "" {
+        return errors.New("sftp: WithRemoteAuth required")
+    }
+    config, err := sshConfig(*opts.remoteAuth)
+    if err != nil {
+        return err
+    }
+
+    client, err := sshDial(ctx, remoteAddr(u, "22"), config, opts)
+    if err != nil {
+        return err
+    }
+    defer client.Close()
+
+    sc, err := sftp.NewClient(client)
+    if err != nil {
+        return errors.WithStack(err)
+    }
+    defer sc.Close()
+
+    remote, err := sc.Open(u.Path)
+    if err != nil {
+        return errors.WithStack(err)
+    }
+    defer remote.Close()
+
+    offset := resumeOffset(fileName, opts.resume)
+    if info, err := remote.Stat(); err == nil {
+        if err = checkDiskSpace(fileName, info.Size()-offset); err != nil {
+            return err
+        }
+    }
+    if offset > 0 {
+        if _, err = remote.Seek(offset, io.SeekStart); err != nil {
+            return errors.WithStack(err)
+        }
+    }
+
+    // 远端文件打开成功后再打开目标文件，失败时不破坏已有内容
+    file, err := openDownload(fileName, offset)
+    if err != nil {
+        return err
+    }
+    defer file.Close()
+
+    _, err = io.Copy(file, readerWithContext(ctx, remote))
+    return errors.WithStack(err)
+}
+
+// sshConfig SSH 客户端配置
+func sshConfig(auth RemoteAuth) (*ssh.ClientConfig, error) {
+    config := &ssh.ClientConfig{User: auth.User, Timeout: conf().timeout}
+    if len(auth.PrivateKey) > 0 {
+        var (
+            signer ssh.Signer
+            err    error
+        )
+        if auth.Passphrase != "" {
+            signer, err = ssh.ParsePrivateKeyWithPassphrase(auth.PrivateKey, []byte(auth.Passphrase))
+        } else {
+            signer, err = ssh.ParsePrivateKey(auth.PrivateKey)
+        }
+        if err != nil {
+            return nil, errors.WithStack(err)
+        }
+        config.Auth = append(config.Auth, ssh.PublicKeys(signer))
+    }
+    if auth.Password != "" {
+        config.Auth = append(config.Auth, ssh.Password(auth.Password))
+    }
+
+    if auth.InsecureIgnoreHostKey {
+        config.HostKeyCallback = ssh.InsecureIgnoreHostKey()
+        return config, nil
+    }
+    path := auth.KnownHosts
+    if path == "" {
+        home, err := os.UserHomeDir()
+        if err != nil {
+            return nil, errors.WithStack(err)
+        }
+        path = filepath.Join(home, ".ssh", "known_hosts")
+    }
+    callback, err := knownhosts.New(path)
+    if err != nil {
+        return nil, errors.Wrapf(err, "sftp: load known hosts %s", path)
+    }
+    config.HostKeyCallback = callback
+    return config, nil
+}
+
+// contextReader 上下文取消后停止读取
+type contextReader struct {
+    ctx context.Context
+    r   io.Reader
+}
+
+// Read 读取内容
+func (r *contextReader) Read(p []byte) (int, error) {
+    if err := r.ctx.Err(); err != nil {
+        return 0, err
+    }
+    return r.r.Read(p)
+}
+
+// readerWithContext 包装读取器，上下文取消后停止读取
+func readerWithContext(ctx context.Context, r io.Reader) io.Reader {
+    return &contextReader{ctx: ctx, r: r}
+}
@@ -3,210 +3,475 @@ package req
 import (
 	"context"
 	"crypto/md5"
+	"crypto/rand"
 	"fmt"
 	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"sync"
 	"time"
 
+	"github.com/chromedp/cdproto/network"
 	"github.com/chromedp/chromedp"
 	"github.com/imroc/req"
 	jsoniter "github.com/json-iterator/go"
 	"github.com/pkg/errors"
-	"golang.org/x/sync/errgroup"
-)
-
-var (
-    // defaultLimit 并发数量
-    defaultLimit = 10
-    // defaultTimeout 超时时间
-    defaultTimeout = time.Second * 10
-    // defaultCachePath 文件缓存路径
-    defaultCachePath = ""
-    // defaultRetryCount 重试次数
-    defaultRetryCount = 3
-    // defaultRetrySleep 重试暂停时长
-    defaultRetrySleepTime = time.Millisecond * 200
 )
 
 func init() {
-    req.SetTimeout(defaultTimeout)
+    resetClient()
 }
 
 // SetLimit 设置并发数量
 func SetLimit(limit int) {
-    defaultLimit = limit
+    updateConfig(func(c *config) { c.limit = limit })
 }
 
-// SetTimeout 设置超时时间
+// SetTimeout 设置超时时间，重建客户端而不修改进行中请求使用的客户端，单次请求可使用 WithTimeout
 func SetTimeout(timeout time.Duration) {
-    defaultTimeout = timeout
-    req.SetTimeout(defaultTimeout)
+    updateConfig(func(c *config) { c.timeout = timeout })
+    resetClient()
 }
 
 // SetRetryCount 设置重试次数
 func SetRetryCount(retryCount int) {
-    defaultRetryCount = retryCount
+    updateConfig(func(c *config) { c.retryCount = retryCount })
 }
 
 // SetRetrySleepTime 设置重试暂停时长
 func SetRetrySleepTime(sleep time.Duration) {
-    defaultRetrySleepTime = sleep
+    updateConfig(func(c *config) { c.retrySleepTime = sleep })
 }
 
-// SetCachePath 设置缓存目录
+// SetCachePath 设置缓存目录，目录无法创建或写入时 panic，建议使用 SetCachePathE
 func SetCachePath(dir string) {
+    if err := SetCachePathE(dir); err != nil {
+        panic(err)
+    }
+}
+
+// SetCachePathE 设置缓存目录，目录不存在时创建，并检查是否可写
+// 出错时保持原有缓存配置不变
+func SetCachePathE(dir string) error {
     path, err := filepath.Abs(dir)
     if err != nil {
-        panic(errors.WithStack(err))
+        return errors.WithStack(err)
     }
 
     if !fileExist(path) {
         err = os.MkdirAll(path, os.ModePerm)
         if err != nil {
-            panic(errors.WithStack(err))
+            return errors.WithStack(err)
         }
     }
 
-    defaultCachePath = path
+    file, err := os.CreateTemp(path, ".write-check-*")
+    if err != nil {
+        return errors.Wrapf(err, "cache path not writable: %s", path)
+    }
+    file.Close()
+    _ = os.Remove(file.Name())
+
+    updateConfig(func(c *config) { c.cachePath = path })
+    return nil
 }
 
-// cacheName 缓存名称
+// cacheName 缓存名称，链接匹配 SetCacheBypass 规则时返回空
 func cacheName(method, url string, v ...interface{}) string {
-    if defaultCachePath != "" {
+    if path := conf().cachePath; path != "" && !cacheBypassed(url) {
         var args string
-        if len(v) > 0 {
+        if v = cacheArgs(v); len(v) > 0 {
             args, _ = jsoniter.MarshalToString(v)
         }
-        return fmt.Sprintf("%s/.%s.%s.cache", defaultCachePath, md5sum([]byte(url+args)), method)
+        return fmt.Sprintf("%s/.%s.%s.cache", path, md5sum([]byte(url+args)), method)
     }
     return ""
 }
 
-func doRequest(method, url string, retryCount int, v ...interface{}) (string, error) {
-    name := cacheName(method, url, v...)
-    if name != "" && fileExist(name) {
-        data, err := os.ReadFile(name)
-        return string(data), errors.WithStack(err)
+// cacheArgs 参与缓存名称计算的参数，忽略上下文与客户端
+func cacheArgs(v []interface{}) []interface{} {
+    args := make([]interface{}, 0, len(v))
+    for _, arg := range v {
+        switch arg.(type) {
+        case context.Context, *http.Client:
+            continue
+        }
+        args = append(args, arg)
     }
+    return args
+}
 
-    rep, err := req.Do(method, url, v...)
-    if err != nil {
-        return "", errors.WithStack(err)
-    } else if rep.Response().StatusCode != 200 {
-        if retryCount < defaultRetryCount {
-            retryCount++
-            time.Sleep(defaultRetrySleepTime)
-            return doRequest(method, url, retryCount, v...)
+func doRequest(method, url string, v ...interface{}) (string, error) {
+    body, err := doRequestBytes(method, url, v...)
+    return string(body), err
+}
+
+// doRequestBytes 发起请求并读取响应体，可缓存的请求读写文件缓存
+func doRequestBytes(method, url string, v ...interface{}) ([]byte, error) {
+    opts, args := splitOptions(v)
+    if opts.err != nil {
+        return nil, opts.err
+    }
+    if opts.body != nil {
+        args = append(args, opts.body)
+    }
+
+    // 非 GET 请求默认不缓存，避免缓存写操作
+    var name string
+    if method == http.MethodGet {
+        name = cacheName(method, url, args...)
+    } else if opts.cache {
+        name = bodyCacheName(method, url, opts, args)
+    }
+    if !opts.cacheRefresh && cacheHit(name, url) {
+        if data, err := readCache(name); err == nil {
+            return data, nil
         }
-        return "", errors.WithStack(fmt.Errorf("http status code: %d", rep.Response().StatusCode))
     }
 
-    body := rep.Bytes()
+    start := time.Now()
+    rep, err := doResponse(method, url, v...)
+    if err == nil {
+        // 会话失效被重定向到登录页时重新登录后重试
+        if login := loginRedirect(rep.Response(), url); login != nil && !opts.oneShotBody {
+            rep.Response().Body.Close()
+            if err = login.refresh(argContext(v), start); err == nil {
+                rep, err = doResponse(method, url, v...)
+            }
+        }
+    }
+    if err != nil {
+        return nil, err
+    } else if rep.Response().StatusCode != http.StatusOK {
+        rep.Response().Body.Close()
+        return nil, errors.WithStack(&StatusError{StatusCode: rep.Response().StatusCode})
+    }
+
+    body, err := readBody(rep.Response(), opts)
+    if err != nil {
+        return nil, err
+    }
+    if opts.schema != nil {
+        if err = validateSchema(opts.schema, url, body); err != nil {
+            return nil, err
+        }
+    }
     if name != "" {
-        err = os.WriteFile(name, body, os.ModePerm)
+        if err = storeCache(name, method, url, body); err != nil {
+            return nil, err
+        }
+    }
+
+    return body, nil
+}
+
+// StatusError 响应状态码错误
+type StatusError struct {
+    StatusCode int
+}
+
+// Error 错误信息
+func (e *StatusError) Error() string {
+    return fmt.Sprintf("http status code: %d", e.StatusCode)
+}
+
+// doResponse 发起请求，状态码非 200/206/304 时返回错误，可重试的状态码按配置重试，非幂等请求默认不重试
+func doResponse(method, url string, v ...interface{}) (*req.Resp, error) {
+    rep, _, err := doAttempts(method, url, v...)
+    return rep, err
+}
+
+// doAttempts 循环发起请求直到成功或重试次数用尽，返回每次尝试的记录
+func doAttempts(method, url string, v ...interface{}) (*req.Resp, []Attempt, error) {
+    done, err := track()
+    if err != nil {
+        return nil, nil, err
+    }
+    defer done()
+
+    c := conf()
+    budget, blockBudget := c.retryCount, 0
+    if opts, _ := splitOptions(v); opts.retries != nil {
+        budget = *opts.retries
+    }
+    if c.blockPolicy != nil {
+        blockBudget = c.blockPolicy.Retry
+    }
+    if !retryable(method, v) {
+        budget, blockBudget = 0, 0
+    }
+    v = withIdempotencyKey(method, v)
+    v = withRequestID(v)
+    opts, _ := splitOptions(v)
+    stats := contextStats(argContext(v))
+    // failover 连接错误切换地址的剩余次数，首次连接错误时按解析地址数量计算，未开启切换地址时连接错误不重试
+    failover, failoverEnabled := -1, c.addressFailover && retryable(method, v)
+
+    // backoff 第 n 次尝试失败后回调并等待重试
+    backoff := func(n int, attempt Attempt) Attempt {
+        attempt.Wait = c.retrySleepTime
+        if c.onRetry != nil {
+            info := RequestInfo{Method: method, URL: url, RequestID: RequestID(attempt.Err), Labels: opts.labels}
+            c.onRetry(n+1, info, attempt.Err, attempt.Wait)
+        }
+        c.clock.Sleep(attempt.Wait)
+        return attempt
+    }
+
+    var attempts []Attempt
+    for n := 0; ; n++ {
+        if stats != nil {
+            stats.attempt()
+        }
+        start := time.Now()
+        rep, err := doOnce(method, url, v...)
+        attempt := Attempt{Start: start, Duration: time.Since(start)}
         if err != nil {
-            return "", errors.WithStack(err)
+            attempt.Err = wrapRequestID(err, v)
+            if !failoverEnabled || !connectionError(err) {
+                return nil, append(attempts, attempt), attempt.Err
+            }
+
+            // 建立连接时已逐个尝试全部地址，已连接后出错的地址排到末尾，还有可用地址时立即改用，不占用重试次数
+            total, healthy := alternateAddrs(urlHost(url))
+            if failover < 0 {
+                failover = total - 1
+            }
+            if failover > 0 && healthy > 0 {
+                failover--
+                n--
+                attempts = append(attempts, attempt)
+                continue
+            }
+            // 没有可用地址后按重试次数等待重试
+            if n >= budget {
+                return nil, append(attempts, attempt), attempt.Err
+            }
+            attempts = append(attempts, backoff(n, attempt))
+            continue
+        }
+
+        code := rep.Response().StatusCode
+        attempt.StatusCode = code
+        if c.blockPolicy != nil && !opts.stream {
+            if reason, blocked := detectBlock(c.blockPolicy, rep.Response()); blocked {
+                rep.Response().Body.Close()
+                attempt.Err = wrapRequestID(blockError(code, reason), v)
+                attempts = append(attempts, attempt)
+
+                ua := rep.Request().Header.Get("User-Agent")
+                if c.blockPolicy.OnBlocked != nil {
+                    c.blockPolicy.OnBlocked(RequestInfo{Method: method, URL: url, RequestID: RequestID(attempt.Err), Labels: opts.labels}, reason, ua)
+                }
+                burnUserAgent(ua, c.blockPolicy.BurnDuration)
+                if blockBudget <= 0 {
+                    return nil, attempts, attempt.Err
+                }
+                // 更换身份的重试不占用状态码重试次数
+                blockBudget--
+                n--
+                continue
+            }
+        }
+        if code == http.StatusOK || code == http.StatusPartialContent || code == http.StatusNotModified || containsInt(opts.acceptStatus, code) {
+            return rep, append(attempts, attempt), nil
         }
+
+        rep.Response().Body.Close()
+        attempt.Err = wrapRequestID(errors.WithStack(&StatusError{StatusCode: code}), v)
+        if n >= budget || !c.retryStatus(code) {
+            return nil, append(attempts, attempt), attempt.Err
+        }
+
+        attempts = append(attempts, backoff(n, attempt))
+    }
+}
+
+// doOnce 发起单次请求，不检查状态码
+func doOnce(method, url string, v ...interface{}) (*req.Resp, error) {
+    if err := ValidateURL(url); err != nil {
+        return nil, err
+    }
+    opts, args := splitOptions(v)
+    if opts.err != nil {
+        return nil, opts.err
     }
 
-    return string(body), nil
+    if opts.body != nil {
+        args = append(args, opts.body)
+        args = withHeader(args, req.Header{"Content-Type": opts.contentType})
+    }
+    args = withRequestEdits(args, opts.edits)
+    args = withLabels(args, opts.labels)
+    if opts.digest != nil {
+        args = withContextValue(args, digestKey{}, opts.digest)
+    }
+    args = withHeader(args, opts.profile.requestHeader(method, args))
+    args = withCommonHeader(args)
+    client, err := requestClient(opts, args)
+    if err != nil {
+        return nil, err
+    }
+    if client != nil {
+        args = append(args, client)
+    }
+    if opts.hedgeDelay > 0 && canHedge(method, args) {
+        return doHedged(method, url, opts.hedgeDelay, args)
+    }
+
+    rep, err := req.Do(method, url, args...)
+    return rep, errors.WithStack(err)
 }
 
 // Get GET请求内容
 func Get(url string, v ...interface{}) (string, error) {
-    return doRequest(http.MethodGet, url, 0, v...)
+    return doRequest(http.MethodGet, url, v...)
 }
 
 // Post POST请求内容
 func Post(url string, v ...interface{}) (string, error) {
-    return doRequest(http.MethodPost, url, 0, v...)
+    return doRequest(http.MethodPost, url, v...)
 }
 
-// BatchGet 批量请求内容
+// BatchGet 批量请求内容，重复链接只请求一次，resMap 为成功的内容，errMap 为失败的错误信息，均以链接下标为键
 func BatchGet(urls []string, v ...interface{}) (resMap, errMap map[int]string, err error) {
     var (
-        group    errgroup.Group
-        resMutex sync.Mutex
-        errMutex sync.Mutex
+        wg      sync.WaitGroup
+        mutex   sync.Mutex
+        opts, _ = splitOptions(v)
+        unique  = uniqueURLs(urls)
+        items   = make([]*batchItem, 0, len(unique))
     )
 
     resMap = make(map[int]string)
     errMap = make(map[int]string)
 
-    group.SetLimit(defaultLimit)
-    for i, url := range urls {
-        i := i
-        url := url
-        group.Go(func() error {
+    for _, indexes := range unique {
+        indexes := indexes
+        url := urls[indexes[0]]
+        wg.Add(1)
+        items = append(items, newBatchItem(indexes[0], url, opts.priority, func() error {
+            defer wg.Done()
             body, err := Get(url, v...)
-            if err != nil {
-                errMutex.Lock()
-                resMap[i] = url
-                errMutex.Unlock()
-            } else {
-                resMutex.Lock()
-                resMap[i] = body
-                resMutex.Unlock()
+            mutex.Lock()
+            for _, i := range indexes {
+                if err != nil {
+                    errMap[i] = err.Error()
+                } else {
+                    resMap[i] = body
+                }
             }
-            return nil
-        })
+            mutex.Unlock()
+            return err
+        }))
     }
 
-    err = group.Wait()
-    return resMap, errMap, errors.WithStack(err)
+    getScheduler().submit(items...)
+    wg.Wait()
+
+    if opts.stats != nil {
+        *opts.stats = BatchStats{Total: len(urls), Unique: len(unique), Saved: len(urls) - len(unique)}
+    }
+    return resMap, errMap, nil
 }
 
-// ChromeGet 模拟Chrome访问
-func ChromeGet(ctx context.Context, url string) (string, error) {
-    name := cacheName(http.MethodGet, url)
-    if name != "" && fileExist(name) {
-        if data, err := os.ReadFile(name); err == nil && len(data) > 0 {
-            return string(data), nil
+// ChromeGet 模拟Chrome访问，v 支持 WithChromeWait、WithChromeSelector、WithChromeSleep 与 WithChromeProfile 等选项
+// 渲染选项参与缓存名称计算，缓存索引中记录最终链接与渲染耗时
+func ChromeGet(ctx context.Context, url string, v ...interface{}) (string, error) {
+    page, err := ChromeFetch(ctx, url, v...)
+    if err != nil {
+        return "", err
+    }
+    return page.Body, nil
+}
+
+// ChromeFetch 模拟Chrome访问，返回内容与主文档的状态码、响应头及最终链接，参数同 ChromeGet
+// 状态码非 2xx 的页面不写入缓存
+func ChromeFetch(ctx context.Context, url string, v ...interface{}) (*ChromePage, error) {
+    if err := ValidateURL(url); err != nil {
+        return nil, err
+    }
+    opts, _ := splitOptions(v)
+    if opts.err != nil {
+        return nil, opts.err
+    }
+    done, err := track()
+    if err != nil {
+        return nil, err
+    }
+    defer done()
+
+    name := chromeCacheName(url, opts)
+    if cacheHit(name, url) {
+        if data, err := readCache(name); err == nil && len(data) > 0 {
+            return cachedChromePage(name, url, data), nil
         }
     }
 
-    ctx, cancel := chromedp.NewContext(ctx)
+    ctx, cancel, err := chromeContext(ctx, url, opts)
+    if err != nil {
+        return nil, err
+    }
     defer cancel()
 
-    var body string
-    err := chromedp.Run(ctx,
-        chromedp.Navigate(url),
-        chromedp.OuterHTML(`body`, &body, chromedp.NodeVisible),
-    )
-    if err != nil {
-        return "", errors.WithStack(err)
+    page := &ChromePage{}
+    document := listenDocument(ctx, page)
+    start := time.Now()
+    if err = chromedp.Run(ctx, append([]chromedp.Action{network.Enable()}, chromeActions(url, opts, &page.Body, &page.URL)...)...); err != nil {
+        return nil, errors.WithStack(err)
     }
+    document()
 
-    if name != "" {
-        err = os.WriteFile(name, []byte(body), os.ModePerm)
-        if err != nil {
-            return "", errors.WithStack(err)
+    if name != "" && (page.StatusCode == 0 || page.StatusCode/100 == 2) {
+        meta := map[string]string{
+            "final_url":   page.URL,
+            "render_time": time.Since(start).String(),
+            "status":      strconv.Itoa(page.StatusCode),
+        }
+        if err = storeCacheMeta(name, http.MethodGet, url, []byte(page.Body), meta); err != nil {
+            return nil, err
         }
     }
 
-    return body, nil
+    return page, nil
 }
 
-// CurlGet 模拟CURL请求
-func CurlGet(url string, headers ...req.Header) (string, error) {
+// CurlGet 模拟CURL请求，v 可传入 req.Header 与 WithHeaderProfile，模板请求头按模板顺序发送
+func CurlGet(url string, v ...interface{}) (string, error) {
+    if err := ValidateURL(url); err != nil {
+        return "", err
+    }
+    opts, headers := splitOptions(v)
+    if opts.err != nil {
+        return "", opts.err
+    }
+    done, err := track()
+    if err != nil {
+        return "", err
+    }
+    defer done()
+
     name := cacheName(http.MethodGet, url)
-    if name != "" && fileExist(name) {
-        if data, err := os.ReadFile(name); err == nil && len(data) > 0 {
+    if cacheHit(name, url) {
+        if data, err := readCache(name); err == nil && len(data) > 0 {
             return string(data), nil
         }
     }
 
-    var header req.Header
-    if len(headers) > 0 {
-        header = headers[0]
-    }
+    headers = withHeader(headers, opts.profile.requestHeader(http.MethodGet, headers))
+    args := append([]string{url}, curlHeaders(opts.profile, withCommonHeader(headers))...)
 
-    args := []string{url}
-    for k, v := range header {
-        args = append(args, "-H", fmt.Sprintf("%s: %v", k, v))
+    proxy, err := proxyFor(url)
+    if err != nil {
+        return "", err
+    }
+    if proxy != nil {
+        args = append(args, "--proxy", proxy.String())
+    } else {
+        // curl 自身也会读取代理环境变量，未使用代理时显式关闭
+        args = append(args, "--noproxy", "*")
     }
 
     cmd := exec.Command("curl", args...)
@@ -216,9 +481,8 @@ func CurlGet(url string, headers ...req.Header) (string, error) {
     }
 
     if name != "" {
-        err := os.WriteFile(name, output, os.ModePerm)
-        if err != nil {
-            return "", errors.WithStack(err)
+        if err = storeCache(name, http.MethodGet, url, output); err != nil {
+            return "", err
         }
     }
 
@@ -228,7 +492,7 @@ func CurlGet(url string, headers ...req.Header) (string, error) {
 // RemoveCache 删除缓存文件
 func RemoveCache(url string) error {
     name := cacheName(http.MethodGet, url)
-    return fileRemove(name)
+    return removeCacheFile(name)
 }
 
 // fileExist 是否存在
@@ -256,3 +520,12 @@ func fileRemove(name string) error {
 func md5sum(v []byte) string {
     return fmt.Sprintf("%x", md5.Sum(v))
 }
+
+// newUUID 生成随机 UUID v4
+func newUUID() string {
+    var b [16]byte
+    _, _ = rand.Read(b[:])
+    b[6] = b[6]&0x0f | 0x40
+    b[8] = b[8]&0x3f | 0x80
+    return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
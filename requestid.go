@@ -0,0 +1,77 @@
+package req
+
+import (
+	"fmt"
+
+	"github.com/imroc/req"
+	"github.com/pkg/errors"
+)
+
+// SetRequestIDHeader 设置请求 ID 请求头，默认 X-Request-ID，为空时不自动生成
+func SetRequestIDHeader(name string) {
+    updateConfig(func(c *config) { c.requestIDHeader = name })
+}
+
+// WithRequestID 指定本次请求的请求 ID，重试时保持不变
+func WithRequestID(id string) Option {
+    return func(o *options) {
+        o.requestID = id
+    }
+}
+
+// withRequestID 为一次逻辑请求设置请求 ID，调用方已设置请求头时不覆盖
+func withRequestID(v []interface{}) []interface{} {
+    name := conf().requestIDHeader
+    if name == "" || hasHeader(v, name) {
+        return v
+    }
+
+    opts, _ := splitOptions(v)
+    id := opts.requestID
+    if id == "" {
+        id = newUUID()
+    }
+    return withHeader(v, req.Header{name: id})
+}
+
+// RequestIDError 携带请求 ID 的错误，便于与服务端日志对应
+type RequestIDError struct {
+    RequestID string
+    Err       error
+}
+
+// Error 错误信息
+func (e *RequestIDError) Error() string {
+    return fmt.Sprintf("%v (request id: %s)", e.Err, e.RequestID)
+}
+
+// Unwrap 原始错误
+func (e *RequestIDError) Unwrap() error {
+    return e.Err
+}
+
+// Cause 原始错误
+func (e *RequestIDError) Cause() error {
+    return e.Err
+}
+
+// RequestID 错误对应的请求 ID，没有时返回空
+func RequestID(err error) string {
+    var e *RequestIDError
+    if errors.As(err, &e) {
+        return e.RequestID
+    }
+    return ""
+}
+
+// wrapRequestID 为错误附加参数中的请求 ID
+func wrapRequestID(err error, v []interface{}) error {
+    name := conf().requestIDHeader
+    if err == nil || name == "" || RequestID(err) != "" {
+        return err
+    }
+    if id := headerValue(v, name); id != "" {
+        return &RequestIDError{RequestID: id, Err: err}
+    }
+    return err
+}
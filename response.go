@@ -0,0 +1,120 @@
+package req
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/imroc/req"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+)
+
+// Response 请求响应，内容未读取前 Body 可作为流使用
+type Response struct {
+    *http.Response
+    // Attempts 每次请求尝试的记录，最后一次为本响应
+    Attempts []Attempt
+
+    data  []byte
+    err   error
+    read  bool
+    stats *requestStats
+}
+
+// Timings 各阶段耗时，响应体未读完时 Total 为已用时长
+func (r *Response) Timings() Timings {
+    if r.stats == nil {
+        return Timings{}
+    }
+    timings, _, _, _ := r.stats.snapshot()
+    return timings
+}
+
+// BytesReceived 已读取的响应体大小
+func (r *Response) BytesReceived() int64 {
+    if r.stats == nil {
+        return 0
+    }
+    _, _, _, received := r.stats.snapshot()
+    return received
+}
+
+// newResponse 包装 imroc/req 响应
+func newResponse(rep *req.Resp) *Response {
+    return &Response{Response: rep.Response()}
+}
+
+// Bytes 读取全部响应内容，重复调用返回同一结果
+func (r *Response) Bytes() ([]byte, error) {
+    if !r.read {
+        r.read = true
+        r.data, r.err = io.ReadAll(r.Body)
+        r.err = errors.WithStack(r.err)
+        r.Body.Close()
+    }
+    return r.data, r.err
+}
+
+// String 读取全部响应内容为字符串
+func (r *Response) String() (string, error) {
+    data, err := r.Bytes()
+    return string(data), err
+}
+
+// JSON 读取并解析 JSON 响应内容
+func (r *Response) JSON(v interface{}) error {
+    data, err := r.Bytes()
+    if err != nil {
+        return err
+    }
+    return errors.WithStack(jsoniter.Unmarshal(data, v))
+}
+
+// Close 关闭响应
+func (r *Response) Close() error {
+    return r.Body.Close()
+}
+
+// GetStream GET请求，返回未读取的响应流，不经过缓存，调用方需关闭响应
+// 仅建立连接阶段按配置重试，读取过程由 ctx 控制
+func GetStream(ctx context.Context, url string, v ...interface{}) (*Response, error) {
+    args, stats := withStats(append([]interface{}{ctx, streamClient(), withStream()}, v...))
+    rep, attempts, err := doAttempts(http.MethodGet, url, args...)
+    if err != nil {
+        return nil, err
+    }
+
+    res := newResponse(rep)
+    res.Attempts, res.stats = attempts, stats
+    return res, nil
+}
+
+// jsonPath 按点分路径取值，如 data.items.0.id，数字段用于数组下标
+func jsonPath(value interface{}, path string) (interface{}, bool) {
+    if path == "" {
+        return value, true
+    }
+
+    for _, key := range strings.Split(path, ".") {
+        switch node := value.(type) {
+        case map[string]interface{}:
+            v, ok := node[key]
+            if !ok {
+                return nil, false
+            }
+            value = v
+        case []interface{}:
+            i, err := strconv.Atoi(key)
+            if err != nil || i < 0 || i >= len(node) {
+                return nil, false
+            }
+            value = node[i]
+        default:
+            return nil, false
+        }
+    }
+    return value, true
+}
@@ -0,0 +1,216 @@
+package req
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// BatchEntry 批量请求条目结果
+type BatchEntry struct {
+    // Index 输入下标
+    Index int
+    URL   string
+    Body  string
+    // StatusCode 响应状态码，请求失败且无响应时为 0
+    StatusCode int
+    Err        error
+    // Attempts 请求次数，命中缓存时为 0
+    Attempts int
+    // BytesSent 请求体大小，未知时为 -1
+    BytesSent int64
+    // BytesReceived 读取的响应体大小
+    BytesReceived int64
+    // Timings 最后一次请求的各阶段耗时
+    Timings Timings
+}
+
+// BatchResult 批量请求结果，条目按输入顺序排列
+type BatchResult struct {
+    Entries []BatchEntry
+    // Labels WithLabels 附加的批量请求标签
+    Labels map[string]string
+
+    // root 完整结果，子集重试后合并到完整结果
+    root *BatchResult
+    // positions 条目在完整结果中的位置
+    positions []int
+    v         []interface{}
+}
+
+// Batch 批量请求内容，重复链接只请求一次，返回每个输入链接的结果
+func Batch(ctx context.Context, urls []string, v ...interface{}) *BatchResult {
+    entries := make([]BatchEntry, len(urls))
+    for i, url := range urls {
+        entries[i] = BatchEntry{Index: i, URL: url}
+    }
+
+    opts, _ := splitOptions(v)
+    r := &BatchResult{Entries: entries, Labels: opts.labels, v: v}
+    r.root = r
+    r.positions = make([]int, len(urls))
+    for i := range r.positions {
+        r.positions[i] = i
+    }
+    runBatch(ctx, r.Entries, v)
+    return r
+}
+
+// runBatch 执行条目请求并写入结果，相同链接只请求一次
+func runBatch(ctx context.Context, entries []BatchEntry, v []interface{}) {
+    urls := make([]string, len(entries))
+    for i, entry := range entries {
+        urls[i] = entry.URL
+    }
+
+    var (
+        wg      sync.WaitGroup
+        opts, _ = splitOptions(v)
+        budget  = newBatchBudget(ctx, opts)
+        unique  = uniqueURLs(urls)
+        items   = make([]*batchItem, 0, len(unique))
+    )
+    defer budget.stop()
+    for _, indexes := range unique {
+        indexes := indexes
+        url := urls[indexes[0]]
+        wg.Add(1)
+        items = append(items, newBatchItem(indexes[0], url, opts.priority, func() error {
+            defer wg.Done()
+            var (
+                body     string
+                err      error
+                timings  Timings
+                attempts int
+                sent     int64
+                received int64
+            )
+            if budget.expired() {
+                err = budget.err()
+            } else {
+                args, stats := withStats(append([]interface{}{budget.ctx}, v...))
+                body, err = Get(url, args...)
+                budget.record(err)
+                err = budget.wrap(err)
+                timings, attempts, sent, received = stats.snapshot()
+            }
+            code := http.StatusOK
+            if err != nil {
+                code = statusCode(err)
+            }
+            // 不同下标写入互不重叠，无需加锁
+            for _, i := range indexes {
+                entries[i].Body, entries[i].StatusCode, entries[i].Err = body, code, err
+                entries[i].Attempts, entries[i].BytesSent, entries[i].BytesReceived, entries[i].Timings = attempts, sent, received, timings
+            }
+            return err
+        }))
+    }
+
+    getScheduler().submit(items...)
+    wg.Wait()
+}
+
+// Failed 失败条目子集，可通过 Retry 重新请求
+func (r *BatchResult) Failed() *BatchResult {
+    failed := &BatchResult{Labels: r.Labels, root: r.root, v: r.v}
+    for i, entry := range r.Entries {
+        if entry.Err != nil {
+            failed.Entries = append(failed.Entries, entry)
+            failed.positions = append(failed.positions, r.positions[i])
+        }
+    }
+    return failed
+}
+
+// Retry 使用相同参数重新请求本结果中的条目，合并到完整结果并返回完整结果
+func (r *BatchResult) Retry(ctx context.Context) *BatchResult {
+    if len(r.Entries) > 0 {
+        runBatch(ctx, r.Entries, r.v)
+        for i, entry := range r.Entries {
+            r.root.Entries[r.positions[i]] = entry
+        }
+    }
+    return r.root
+}
+
+// ByURL 按链接索引结果，重复链接只请求一次，对应首次出现的条目
+func (r *BatchResult) ByURL() map[string]BatchEntry {
+    m := make(map[string]BatchEntry, len(r.Entries))
+    for _, entry := range r.Entries {
+        if _, ok := m[entry.URL]; !ok {
+            m[entry.URL] = entry
+        }
+    }
+    return m
+}
+
+// Bodies 按输入顺序排列的内容，失败条目为空字符串
+func (r *BatchResult) Bodies() []string {
+    bodies := make([]string, len(r.Entries))
+    for i, entry := range r.Entries {
+        bodies[i] = entry.Body
+    }
+    return bodies
+}
+
+// StatusCodes 按输入顺序排列的响应状态码
+func (r *BatchResult) StatusCodes() []int {
+    codes := make([]int, len(r.Entries))
+    for i, entry := range r.Entries {
+        codes[i] = entry.StatusCode
+    }
+    return codes
+}
+
+// BatchSummary 批量请求结果统计
+type BatchSummary struct {
+    Total     int
+    Succeeded int
+    Failed    int
+    // Skipped 因超出时间预算或失败率阈值未执行或被取消的条目数量
+    Skipped int
+}
+
+// Summary 结果统计
+func (r *BatchResult) Summary() BatchSummary {
+    summary := BatchSummary{Total: len(r.Entries)}
+    for _, entry := range r.Entries {
+        switch {
+        case entry.Err == nil:
+            summary.Succeeded++
+        case skippedEntry(entry.Err):
+            summary.Skipped++
+        default:
+            summary.Failed++
+        }
+    }
+    return summary
+}
+
+// Incomplete 是否有条目因超出时间预算或失败率阈值未执行或被取消
+func (r *BatchResult) Incomplete() bool {
+    for _, entry := range r.Entries {
+        if skippedEntry(entry.Err) {
+            return true
+        }
+    }
+    return false
+}
+
+// skippedEntry 是否为中止产生的错误
+func skippedEntry(err error) bool {
+    return errors.Is(err, ErrBudgetExceeded) || errors.Is(err, ErrErrorRateExceeded)
+}
+
+// Err 第一个失败条目的错误，全部成功时为 nil
+func (r *BatchResult) Err() error {
+    for _, entry := range r.Entries {
+        if entry.Err != nil {
+            return entry.Err
+        }
+    }
+    return nil
+}
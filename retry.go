@@ -0,0 +1,102 @@
+package req
+
+import (
+	"net/http"
+	"time"
+)
+
+// WithUnsafeRetry 允许 POST/PATCH 等非幂等请求在失败时重试
+// 默认只重试幂等请求，非幂等请求指定了 Idempotency-Key 时同样会重试
+func WithUnsafeRetry() Option {
+    return func(o *options) {
+        o.unsafeRetry = true
+    }
+}
+
+// WithRetries 设置本次请求的状态码重试次数，覆盖 SetRetryCount，非幂等请求仍需 WithUnsafeRetry 才会重试
+func WithRetries(n int) Option {
+    return func(o *options) {
+        o.retries = &n
+    }
+}
+
+// idempotentMethod 是否为幂等请求方法
+func idempotentMethod(method string) bool {
+    switch method {
+    case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+        return true
+    }
+    return false
+}
+
+// retryable 请求是否可以重试，需在自动生成幂等键之前判断，请求体只能读取一次时不重试
+func retryable(method string, v []interface{}) bool {
+    opts, _ := splitOptions(v)
+    if opts.oneShotBody {
+        return false
+    }
+    if idempotentMethod(method) {
+        return true
+    }
+    return opts.unsafeRetry || opts.idempotencyKey != "" || hasHeader(v, idempotencyHeader)
+}
+
+// SetRetryStatus 设置需要重试的状态码，为空时恢复默认：408/425/429/5xx
+func SetRetryStatus(codes ...int) {
+    if len(codes) == 0 {
+        SetRetryStatusFunc(nil)
+        return
+    }
+
+    set := make(map[int]bool, len(codes))
+    for _, code := range codes {
+        set[code] = true
+    }
+    SetRetryStatusFunc(func(code int) bool { return set[code] })
+}
+
+// SetRetryStatusFunc 设置判断状态码是否需要重试的函数，为 nil 时恢复默认
+func SetRetryStatusFunc(fn func(code int) bool) {
+    if fn == nil {
+        fn = defaultRetryStatus
+    }
+    updateConfig(func(c *config) { c.retryStatus = fn })
+}
+
+// defaultRetryStatus 默认需要重试的状态码
+func defaultRetryStatus(code int) bool {
+    switch code {
+    case http.StatusRequestTimeout, http.StatusTooEarly, http.StatusTooManyRequests:
+        return true
+    }
+    return code >= 500 && code <= 599
+}
+
+// RequestInfo 请求信息
+type RequestInfo struct {
+    Method    string
+    URL       string
+    RequestID string
+    // Labels WithLabels 附加的请求标签
+    Labels map[string]string
+}
+
+// OnRetry 设置重试回调，每次重试等待前调用，attempt 从 1 开始，为 nil 时取消
+// 回调在请求所在协程中执行，应避免阻塞
+func OnRetry(fn func(attempt int, info RequestInfo, err error, wait time.Duration)) {
+    updateConfig(func(c *config) { c.onRetry = fn })
+}
+
+// Attempt 单次请求尝试记录
+type Attempt struct {
+    // StatusCode 响应状态码，请求失败时为 0
+    StatusCode int
+    // Err 本次尝试的错误
+    Err error
+    // Start 开始时间
+    Start time.Time
+    // Duration 请求耗时
+    Duration time.Duration
+    // Wait 下次重试前的等待时长
+    Wait time.Duration
+}
@@ -0,0 +1,171 @@
+package req
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"gopkg.in/yaml.v3"
+)
+
+// SchemaViolation 响应不符合 JSON Schema 的位置与原因
+type SchemaViolation struct {
+    // Path 响应中的 JSON Pointer，如 /items/0/id
+    Path string
+    // Keyword 校验失败的规则位置
+    Keyword string
+    Message string
+}
+
+// SchemaError 响应校验错误
+type SchemaError struct {
+    URL        string
+    Violations []SchemaViolation
+}
+
+func (e *SchemaError) Error() string {
+    var b strings.Builder
+    b.WriteString("schema: " + e.URL)
+    for _, v := range e.Violations {
+        b.WriteString("; " + v.Path + ": " + v.Message)
+    }
+    return b.String()
+}
+
+// WithSchema 按 JSON Schema 校验 JSON 响应，不符合时返回 SchemaError，校验失败的响应不写入缓存
+func WithSchema(schema string) Option {
+    compiled, err := compileSchema("schema.json", []byte(schema), "schema.json")
+    return func(o *options) {
+        o.schema = compiled
+        if err != nil && o.err == nil {
+            o.err = err
+        }
+    }
+}
+
+// WithOpenAPISchema 按 OpenAPI 3 文档中接口的响应定义校验 JSON 响应，spec 可为 JSON 或 YAML
+// path 为文档中的路径模板，如 /pets/{id}，status 为响应状态码，文档中不存在时使用 default
+func WithOpenAPISchema(spec []byte, method, path string, status int) Option {
+    compiled, err := compileOpenAPISchema(spec, method, path, status)
+    return func(o *options) {
+        o.schema = compiled
+        if err != nil && o.err == nil {
+            o.err = err
+        }
+    }
+}
+
+// compileSchema 编译文档中指定位置的 JSON Schema，YAML 文档转换为 JSON
+func compileSchema(resource string, doc []byte, location string) (*jsonschema.Schema, error) {
+    if trimmed := bytes.TrimSpace(doc); len(trimmed) > 0 && trimmed[0] != '{' {
+        var value interface{}
+        if err := yaml.Unmarshal(doc, &value); err != nil {
+            return nil, errors.Wrap(err, "schema")
+        }
+        data, err := jsoniter.Marshal(stringKeys(value))
+        if err != nil {
+            return nil, errors.Wrap(err, "schema")
+        }
+        doc = data
+    }
+
+    compiler := jsonschema.NewCompiler()
+    if err := compiler.AddResource(resource, bytes.NewReader(doc)); err != nil {
+        return nil, errors.Wrap(err, "schema")
+    }
+    schema, err := compiler.Compile(location)
+    return schema, errors.Wrap(err, "schema")
+}
+
+// compileOpenAPISchema 编译 OpenAPI 接口响应的 JSON Schema，引用的 components 在同一文档中解析
+func compileOpenAPISchema(spec []byte, method, path string, status int) (*jsonschema.Schema, error) {
+    var doc struct {
+        Paths map[string]map[string]struct {
+            Responses map[string]interface{} `json:"responses" yaml:"responses"`
+        } `json:"paths" yaml:"paths"`
+    }
+    var err error
+    if trimmed := bytes.TrimSpace(spec); len(trimmed) > 0 && trimmed[0] == '{' {
+        err = jsoniter.Unmarshal(spec, &doc)
+    } else {
+        err = yaml.Unmarshal(spec, &doc)
+    }
+    if err != nil {
+        return nil, errors.Wrap(err, "openapi")
+    }
+
+    method = strings.ToLower(method)
+    code := strconv.Itoa(status)
+    if _, ok := doc.Paths[path][method].Responses[code]; !ok {
+        code = "default"
+    }
+    if _, ok := doc.Paths[path][method].Responses[code]; !ok {
+        return nil, errors.Errorf("openapi: no response %d for %s %s", status, method, path)
+    }
+
+    location := "openapi.json#/paths/" + jsonPointerEscape(path) + "/" + method +
+        "/responses/" + code + "/content/application~1json/schema"
+    return compileSchema("openapi.json", spec, location)
+}
+
+// stringKeys 将 YAML 中的非字符串键转换为字符串，如响应状态码 200
+func stringKeys(value interface{}) interface{} {
+    switch v := value.(type) {
+    case map[interface{}]interface{}:
+        m := make(map[string]interface{}, len(v))
+        for key, item := range v {
+            m[fmt.Sprint(key)] = stringKeys(item)
+        }
+        return m
+    case map[string]interface{}:
+        for key, item := range v {
+            v[key] = stringKeys(item)
+        }
+    case []interface{}:
+        for i, item := range v {
+            v[i] = stringKeys(item)
+        }
+    }
+    return value
+}
+
+// jsonPointerEscape 转义 JSON Pointer 片段
+func jsonPointerEscape(s string) string {
+    return strings.ReplaceAll(strings.ReplaceAll(s, "~", "~0"), "/", "~1")
+}
+
+// validateSchema 校验 JSON 响应
+func validateSchema(schema *jsonschema.Schema, url string, body []byte) error {
+    var value interface{}
+    if err := jsoniter.Unmarshal(body, &value); err != nil {
+        return errors.WithStack(&SchemaError{URL: url, Violations: []SchemaViolation{{Path: "", Message: "invalid json: " + err.Error()}}})
+    }
+
+    err := schema.Validate(value)
+    if err == nil {
+        return nil
+    }
+    var verr *jsonschema.ValidationError
+    if !errors.As(err, &verr) {
+        return errors.WithStack(err)
+    }
+
+    e := &SchemaError{URL: url}
+    collectViolations(verr, &e.Violations)
+    return errors.WithStack(e)
+}
+
+// collectViolations 收集最底层的校验错误
+func collectViolations(err *jsonschema.ValidationError, list *[]SchemaViolation) {
+    if len(err.Causes) == 0 {
+        *list = append(*list, SchemaViolation{Path: err.InstanceLocation, Keyword: err.KeywordLocation, Message: err.Message})
+        return
+    }
+    for _, cause := range err.Causes {
+        collectViolations(cause, list)
+    }
+}
@@ -0,0 +1,68 @@
+package req
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// ErrClosed 已调用 Close，不再接受新请求
+var ErrClosed = errors.New("req: closed")
+
+// shutdown 关闭状态与执行中的请求
+var shutdown = struct {
+    mutex  sync.Mutex
+    closed bool
+    done   chan struct{}
+    wg     sync.WaitGroup
+    // queues 关闭时需要保存状态的队列
+    queues []*Queue
+}{done: make(chan struct{})}
+
+// track 记录开始执行的请求，返回结束函数，已关闭时返回 ErrClosed
+func track() (func(), error) {
+    shutdown.mutex.Lock()
+    defer shutdown.mutex.Unlock()
+
+    if shutdown.closed {
+        return nil, errors.WithStack(ErrClosed)
+    }
+    shutdown.wg.Add(1)
+    return shutdown.wg.Done, nil
+}
+
+// Close 停止接受新请求，等待执行中的请求、批量条目与下载完成，
+// 超出 ctx 期限时不再等待，随后保存队列状态并关闭缓存索引；关闭后无法恢复，用于服务退出前清理
+func Close(ctx context.Context) error {
+    shutdown.mutex.Lock()
+    if !shutdown.closed {
+        shutdown.closed = true
+        close(shutdown.done)
+    }
+    queues := shutdown.queues
+    shutdown.mutex.Unlock()
+
+    drained := make(chan struct{})
+    go func() {
+        shutdown.wg.Wait()
+        close(drained)
+    }()
+
+    var err error
+    select {
+    case <-drained:
+    case <-ctx.Done():
+        err = errors.WithStack(ctx.Err())
+    }
+
+    for _, q := range queues {
+        if saveErr := q.save(); saveErr != nil && err == nil {
+            err = saveErr
+        }
+    }
+    if closeErr := closeCacheIndex(); closeErr != nil && err == nil {
+        err = closeErr
+    }
+    return err
+}
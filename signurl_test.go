@@ -0,0 +1,64 @@
+package req
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestURLSignerTampered(t *testing.T) {
+    clock := withFakeClock(t)
+    signer := URLSigner{Key: []byte("secret")}
+    signed, err := signer.Sign("https://cdn.example.com/files/a.zip?user=1", clock.Now().Add(time.Hour))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if err = signer.Verify(signed); err != nil {
+        t.Fatalf("Verify(%s) = %v", signed, err)
+    }
+
+    tests := []struct {
+        name string
+        url  string
+    }{
+        {"path", strings.Replace(signed, "/files/a.zip", "/files/b.zip", 1)},
+        {"host", strings.Replace(signed, "cdn.example.com", "evil.example.com", 1)},
+        {"scheme", strings.Replace(signed, "https://", "http://", 1)},
+        {"query", strings.Replace(signed, "user=1", "user=2", 1)},
+        {"added query", signed + "&admin=1"},
+        {"expires", strings.Replace(signed, "expires=", "expires=9", 1)},
+        {"signature", strings.Replace(signed, "signature=", "signature=x", 1)},
+        {"missing signature", strings.Replace(signed, "signature=", "sig=", 1)},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if err := signer.Verify(tt.url); !errors.Is(err, ErrSignatureInvalid) {
+                t.Fatalf("Verify(%s) = %v, want ErrSignatureInvalid", tt.url, err)
+            }
+        })
+    }
+    if err = (URLSigner{Key: []byte("other")}).Verify(signed); !errors.Is(err, ErrSignatureInvalid) {
+        t.Fatalf("Verify with other key = %v, want ErrSignatureInvalid", err)
+    }
+}
+
+func TestURLSignerExpired(t *testing.T) {
+    clock := withFakeClock(t)
+    signer := URLSigner{Key: []byte("secret"), ExpiresParam: "e", SignatureParam: "sig"}
+    signed, err := signer.Sign("https://cdn.example.com/files/a.zip", clock.Now().Add(time.Minute))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    clock.Advance(time.Minute - time.Second)
+    if err = signer.Verify(signed); err != nil {
+        t.Fatalf("Verify before expiry = %v", err)
+    }
+    // 到达过期时间即失效
+    clock.Advance(time.Second)
+    if err = signer.Verify(signed); !errors.Is(err, ErrSignatureExpired) {
+        t.Fatalf("Verify after expiry = %v, want ErrSignatureExpired", err)
+    }
+}
@@ -0,0 +1,131 @@
+package req
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/xml"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
+)
+
+// SitemapURL 站点地图链接
+type SitemapURL struct {
+    Loc        string  `xml:"loc"`
+    LastMod    string  `xml:"lastmod"`
+    ChangeFreq string  `xml:"changefreq"`
+    Priority   float64 `xml:"priority"`
+}
+
+// LastModTime 最后修改时间，无法解析时返回零值
+func (u SitemapURL) LastModTime() time.Time {
+    for _, layout := range []string{time.RFC3339, "2006-01-02T15:04Z07:00", "2006-01-02"} {
+        if t, err := time.Parse(layout, u.LastMod); err == nil {
+            return t
+        }
+    }
+    return time.Time{}
+}
+
+// sitemapDoc 站点地图文档，兼容 urlset 与 sitemapindex
+type sitemapDoc struct {
+    XMLName  xml.Name
+    URLs     []SitemapURL `xml:"url"`
+    Sitemaps []SitemapURL `xml:"sitemap"`
+}
+
+// GetSitemap 获取并解析站点地图，支持索引文件与 gzip 压缩
+func GetSitemap(url string, v ...interface{}) ([]SitemapURL, error) {
+    var (
+        mutex   sync.Mutex
+        visited = make(map[string]bool)
+    )
+    return getSitemap(url, visited, &mutex, v...)
+}
+
+// SitemapLocs 提取链接地址，可直接用于 BatchGet
+func SitemapLocs(items []SitemapURL) []string {
+    urls := make([]string, 0, len(items))
+    for _, item := range items {
+        urls = append(urls, item.Loc)
+    }
+    return urls
+}
+
+func getSitemap(url string, visited map[string]bool, mutex *sync.Mutex, v ...interface{}) ([]SitemapURL, error) {
+    mutex.Lock()
+    if visited[url] {
+        mutex.Unlock()
+        return nil, nil
+    }
+    visited[url] = true
+    mutex.Unlock()
+
+    body, err := Get(url, v...)
+    if err != nil {
+        return nil, err
+    }
+
+    doc, err := parseSitemap([]byte(body))
+    if err != nil {
+        return nil, errors.Wrapf(err, "sitemap: %s", url)
+    }
+
+    if len(doc.Sitemaps) == 0 {
+        return doc.URLs, nil
+    }
+
+    var (
+        group  errgroup.Group
+        result = doc.URLs
+    )
+
+    group.SetLimit(conf().limit)
+    for _, item := range doc.Sitemaps {
+        loc := item.Loc
+        group.Go(func() error {
+            items, err := getSitemap(loc, visited, mutex, v...)
+            if err != nil {
+                return err
+            }
+            mutex.Lock()
+            result = append(result, items...)
+            mutex.Unlock()
+            return nil
+        })
+    }
+
+    err = group.Wait()
+    return result, err
+}
+
+// parseSitemap 解析站点地图内容
+func parseSitemap(data []byte) (*sitemapDoc, error) {
+    if len(data) > 2 && data[0] == 0x1f && data[1] == 0x8b {
+        reader, err := gzip.NewReader(bytes.NewReader(data))
+        if err != nil {
+            return nil, errors.WithStack(err)
+        }
+        defer reader.Close()
+
+        data, err = io.ReadAll(reader)
+        if err != nil {
+            return nil, errors.WithStack(err)
+        }
+    }
+
+    var doc sitemapDoc
+    err := xml.Unmarshal(data, &doc)
+    if err != nil {
+        return nil, errors.WithStack(err)
+    }
+
+    if doc.XMLName.Local != "urlset" && doc.XMLName.Local != "sitemapindex" {
+        return nil, errors.Errorf("unknown sitemap root: %s", doc.XMLName.Local)
+    }
+
+    return &doc, nil
+}
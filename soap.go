@@ -0,0 +1,135 @@
+package req
+
+import (
+	"bytes"
+	"encoding/xml"
+	"net/http"
+	"strings"
+
+	"github.com/imroc/req"
+	"github.com/pkg/errors"
+)
+
+// soapEnvelopeNS SOAP 1.1 信封命名空间
+const soapEnvelopeNS = "http://schemas.xmlsoap.org/soap/envelope/"
+
+// SOAPFault SOAP 错误响应
+type SOAPFault struct {
+    Code    string
+    Message string
+    Actor   string
+    // Detail detail 元素内的原始 XML
+    Detail string
+}
+
+func (f *SOAPFault) Error() string {
+    return "soap fault: " + f.Code + ": " + f.Message
+}
+
+// WithSOAPHeader 设置 SOAP 信封 Header 中的元素，如 WS-Security 认证信息
+func WithSOAPHeader(header interface{}) Option {
+    data, err := xml.Marshal(header)
+    return func(o *options) {
+        if err != nil {
+            o.err = errors.Wrap(err, "soap header")
+            return
+        }
+        o.soapHeader = data
+    }
+}
+
+// soapRequest 请求信封
+type soapRequest struct {
+    XMLName xml.Name  `xml:"soap:Envelope"`
+    NS      string    `xml:"xmlns:soap,attr"`
+    Header  *soapPart `xml:"soap:Header,omitempty"`
+    Body    soapPart  `xml:"soap:Body"`
+}
+
+// soapPart 原样写入的信封内容
+type soapPart struct {
+    Content []byte `xml:",innerxml"`
+}
+
+// soapResponse 响应信封，兼容 SOAP 1.1 与 1.2 错误格式
+type soapResponse struct {
+    XMLName xml.Name `xml:"Envelope"`
+    Body    struct {
+        Fault *struct {
+            Code   string `xml:"faultcode"`
+            String string `xml:"faultstring"`
+            Actor  string `xml:"faultactor"`
+            Detail struct {
+                Content string `xml:",innerxml"`
+            } `xml:"detail"`
+            // SOAP 1.2
+            Code12   string `xml:"Code>Value"`
+            Reason12 string `xml:"Reason>Text"`
+            Detail12 struct {
+                Content string `xml:",innerxml"`
+            } `xml:"Detail"`
+        } `xml:"Fault"`
+        Content []byte `xml:",innerxml"`
+    } `xml:"Body"`
+}
+
+// PostSOAP 发送 SOAP 1.1 请求，body 编码后放入信封 Body，响应 Body 中的第一个元素解析到 out
+// 服务端返回 Fault 时返回 *SOAPFault，out 为 nil 时不解析响应
+func PostSOAP(url, action string, body, out interface{}, v ...interface{}) error {
+    opts, _ := splitOptions(v)
+    if opts.err != nil {
+        return opts.err
+    }
+    content, err := xml.Marshal(body)
+    if err != nil {
+        return errors.WithStack(err)
+    }
+
+    envelope := soapRequest{NS: soapEnvelopeNS, Body: soapPart{Content: content}}
+    if opts.soapHeader != nil {
+        envelope.Header = &soapPart{Content: opts.soapHeader}
+    }
+    data, err := xml.Marshal(envelope)
+    if err != nil {
+        return errors.WithStack(err)
+    }
+
+    v = withHeader(v, req.Header{
+        "Accept":       "text/xml, application/soap+xml;q=0.9",
+        "Content-Type": "text/xml; charset=utf-8",
+        "SOAPAction":   `"` + action + `"`,
+    })
+    // SOAP 1.1 以 500 状态码返回 Fault，需读取响应体
+    args := append(v[:len(v):len(v)], append([]byte(xml.Header), data...), withAcceptStatus(http.StatusInternalServerError))
+    rep, _, err := doAttempts(http.MethodPost, url, args...)
+    if err != nil {
+        return err
+    }
+    code := rep.Response().StatusCode
+    res, err := readBody(rep.Response(), opts)
+    if err != nil {
+        return err
+    }
+
+    var env soapResponse
+    if err = decodeXML(string(res), &env); err != nil {
+        if code != http.StatusOK {
+            return errors.WithStack(&StatusError{StatusCode: code})
+        }
+        return err
+    }
+    if fault := env.Body.Fault; fault != nil {
+        f := &SOAPFault{Code: fault.Code, Message: fault.String, Actor: fault.Actor, Detail: strings.TrimSpace(fault.Detail.Content)}
+        if f.Code == "" && f.Message == "" {
+            f.Code, f.Message, f.Detail = fault.Code12, fault.Reason12, strings.TrimSpace(fault.Detail12.Content)
+        }
+        return errors.WithStack(f)
+    }
+    if code != http.StatusOK {
+        return errors.WithStack(&StatusError{StatusCode: code})
+    }
+    if out == nil || len(bytes.TrimSpace(env.Body.Content)) == 0 {
+        return nil
+    }
+    return decodeXML(string(env.Body.Content), out)
+}
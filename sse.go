@@ -0,0 +1,139 @@
+package req
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/imroc/req"
+	"github.com/pkg/errors"
+)
+
+// maxReconnectWait 重连最大等待时长
+const maxReconnectWait = time.Second * 30
+
+// Event 服务端推送事件
+type Event struct {
+    ID    string
+    Event string
+    Data  string
+    // Retry 服务端建议的重连间隔
+    Retry time.Duration
+}
+
+// GetSSE 订阅服务端推送事件，断线后携带 Last-Event-ID 退避重连，直到 ctx 结束
+// 首次连接失败时直接返回错误
+func GetSSE(ctx context.Context, url string, handler func(Event), v ...interface{}) error {
+    var (
+        lastID    string
+        retry     time.Duration
+        connected bool
+        wait      = conf().retrySleepTime
+    )
+
+    for {
+        args := []interface{}{ctx, streamClient(), withStream(), req.Header{"Accept": "text/event-stream", "Cache-Control": "no-cache"}}
+        if lastID != "" {
+            args = append(args, req.Header{"Last-Event-ID": lastID})
+        }
+
+        rep, err := doResponse(http.MethodGet, url, append(args, v...)...)
+        if err != nil && !connected {
+            return err
+        }
+
+        if err == nil {
+            connected = true
+            received := false
+            err = readEvents(rep.Response().Body, func(event Event) {
+                received = true
+                if event.ID != "" {
+                    lastID = event.ID
+                }
+                if event.Retry > 0 {
+                    retry = event.Retry
+                }
+                if event.Data != "" || event.Event != "" {
+                    handler(event)
+                }
+            })
+            rep.Response().Body.Close()
+            if received {
+                wait = conf().retrySleepTime
+            }
+        }
+
+        if ctx.Err() != nil {
+            return ctx.Err()
+        }
+
+        sleep := wait
+        if retry > 0 {
+            sleep = retry
+        }
+        select {
+        case <-ctx.Done():
+            return ctx.Err()
+        case <-conf().clock.After(sleep):
+        }
+
+        if wait *= 2; wait > maxReconnectWait {
+            wait = maxReconnectWait
+        }
+    }
+}
+
+// readEvents 按 text/event-stream 格式解析事件流
+// 仅包含 id/retry 字段的事件也会回调，用于更新重连状态
+func readEvents(r io.Reader, fn func(Event)) error {
+    scanner := bufio.NewScanner(r)
+    scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+    var (
+        event Event
+        data  []string
+        dirty bool
+    )
+    for scanner.Scan() {
+        line := scanner.Text()
+        if line == "" {
+            if dirty {
+                event.Data = strings.Join(data, "\n")
+                if event.Event == "" && event.Data != "" {
+                    event.Event = "message"
+                }
+                fn(event)
+            }
+            event, data, dirty = Event{}, nil, false
+            continue
+        }
+        if strings.HasPrefix(line, ":") {
+            continue
+        }
+
+        field, value, _ := strings.Cut(line, ":")
+        value = strings.TrimPrefix(value, " ")
+        switch field {
+        case "event":
+            event.Event = value
+        case "data":
+            data = append(data, value)
+        case "id":
+            if !strings.Contains(value, "\x00") {
+                event.ID = value
+            }
+        case "retry":
+            if ms, err := strconv.Atoi(value); err == nil {
+                event.Retry = time.Duration(ms) * time.Millisecond
+            }
+        default:
+            continue
+        }
+        dirty = true
+    }
+    return errors.WithStack(scanner.Err())
+}
@@ -0,0 +1,139 @@
+package req
+
+import (
+	neturl "net/url"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/imroc/req"
+	"github.com/pkg/errors"
+)
+
+// placeholderPattern 模板变量
+var placeholderPattern = regexp.MustCompile(`\{(\w+)\}`)
+
+// Template 请求模板，URL、请求头与请求体中可使用 {name} 变量
+type Template struct {
+    Method string
+    URL    string
+    Header map[string]string
+    Body   string
+}
+
+// NewTemplate 创建请求模板
+func NewTemplate(method, url string) *Template {
+    return &Template{Method: method, URL: url, Header: map[string]string{}}
+}
+
+// SetHeader 设置模板请求头
+func (t *Template) SetHeader(key, value string) *Template {
+    t.Header[key] = value
+    return t
+}
+
+// SetBody 设置模板请求体
+func (t *Template) SetBody(body string) *Template {
+    t.Body = body
+    return t
+}
+
+// Render 替换模板变量，URL 路径与查询参数中的变量会被转义，缺少变量时返回错误
+func (t *Template) Render(vars map[string]string) (url string, header req.Header, body string, err error) {
+    path, query, hasQuery := strings.Cut(t.URL, "?")
+    if path, err = substitute(path, vars, neturl.PathEscape); err != nil {
+        return
+    }
+    url = path
+    if hasQuery {
+        if query, err = substitute(query, vars, neturl.QueryEscape); err != nil {
+            return
+        }
+        url += "?" + query
+    }
+
+    header = make(req.Header, len(t.Header))
+    for key, value := range t.Header {
+        if header[key], err = substitute(value, vars, nil); err != nil {
+            return
+        }
+    }
+
+    body, err = substitute(t.Body, vars, nil)
+    return
+}
+
+// Execute 使用变量执行请求
+func (t *Template) Execute(vars map[string]string, v ...interface{}) (string, error) {
+    url, header, body, err := t.Render(vars)
+    if err != nil {
+        return "", err
+    }
+
+    args := []interface{}{header}
+    if body != "" {
+        args = append(args, []byte(body))
+    }
+    return doRequest(t.Method, url, append(args, v...)...)
+}
+
+// ExecuteAll 使用多组变量批量执行请求，返回值与 BatchGet 一致，渲染或请求失败的错误信息写入 errMap
+func (t *Template) ExecuteAll(vars []map[string]string, v ...interface{}) (resMap, errMap map[int]string, err error) {
+    var (
+        wg      sync.WaitGroup
+        mutex   sync.Mutex
+        opts, _ = splitOptions(v)
+        items   = make([]*batchItem, 0, len(vars))
+    )
+
+    resMap = make(map[int]string)
+    errMap = make(map[int]string)
+
+    for i, item := range vars {
+        url, _, _, err := t.Render(item)
+        if err != nil {
+            errMap[i] = err.Error()
+            continue
+        }
+
+        i, item := i, item
+        wg.Add(1)
+        items = append(items, newBatchItem(i, url, opts.priority, func() error {
+            defer wg.Done()
+            body, err := t.Execute(item, v...)
+            mutex.Lock()
+            if err != nil {
+                errMap[i] = err.Error()
+            } else {
+                resMap[i] = body
+            }
+            mutex.Unlock()
+            return err
+        }))
+    }
+
+    getScheduler().submit(items...)
+    wg.Wait()
+    return resMap, errMap, nil
+}
+
+// substitute 替换 {name} 变量
+func substitute(text string, vars map[string]string, escape func(string) string) (string, error) {
+    var missing []string
+    result := placeholderPattern.ReplaceAllStringFunc(text, func(match string) string {
+        name := match[1 : len(match)-1]
+        value, ok := vars[name]
+        if !ok {
+            missing = append(missing, name)
+            return match
+        }
+        if escape != nil {
+            return escape(value)
+        }
+        return value
+    })
+    if len(missing) > 0 {
+        return "", errors.Errorf("template variables missing: %s", strings.Join(missing, ", "))
+    }
+    return result, nil
+}
@@ -0,0 +1,121 @@
+package req
+
+import (
+	"net/http"
+	"time"
+)
+
+var (
+    // throttleInterval 首次限流时同一主机的请求间隔
+    throttleInterval = time.Millisecond * 200
+    // throttleMinInterval 恢复时请求间隔低于该值后取消
+    throttleMinInterval = time.Millisecond * 10
+)
+
+// SetAutoThrottle 开启批量请求自动限流，主机返回 429/503 时降低该主机的并发数量与请求频率，
+// 冷却 cooldown 后每次成功逐步恢复，为 0 时关闭
+func SetAutoThrottle(cooldown time.Duration) {
+    updateConfig(func(c *config) { c.autoThrottle = cooldown })
+}
+
+// hostThrottle 主机限流状态
+type hostThrottle struct {
+    // limit 限流后的主机并发数量
+    limit int
+    // interval 同一主机两次调度的最小间隔
+    interval time.Duration
+    // until 冷却结束时间，之前不恢复
+    until time.Time
+    // reduced 最近一次降低的时间，间隔内的重复信号只延长冷却
+    reduced time.Time
+    // next 下一次允许调度的时间
+    next time.Time
+}
+
+// throttleSignal 是否为需要限流的响应
+func throttleSignal(err error) bool {
+    code := statusCode(err)
+    return code == http.StatusTooManyRequests || code == http.StatusServiceUnavailable
+}
+
+// throttled 主机是否处于限流中，因间隔未到而限流时返回可调度时间
+func (s *scheduler) throttled(host string, now time.Time) (bool, time.Time) {
+    t := s.throttles[host]
+    if t == nil {
+        return false, time.Time{}
+    }
+    if s.hosts[host] >= t.limit {
+        return true, time.Time{}
+    }
+    if now.Before(t.next) {
+        return true, t.next
+    }
+    return false, time.Time{}
+}
+
+// throttle 根据执行结果调整主机限流，需持有锁
+func (s *scheduler) throttle(c *config, host string, err error) {
+    now := c.clock.Now()
+    t := s.throttles[host]
+    if throttleSignal(err) {
+        if t == nil {
+            t = &hostThrottle{limit: s.hosts[host] + 1}
+            s.throttles[host] = t
+        }
+        t.until = now.Add(c.autoThrottle)
+        if !t.reduced.IsZero() && now.Sub(t.reduced) < t.interval {
+            return
+        }
+
+        t.reduced = now
+        if t.limit /= 2; t.limit < 1 {
+            t.limit = 1
+        }
+        if t.interval *= 2; t.interval == 0 {
+            t.interval = throttleInterval
+        }
+        if t.interval > c.autoThrottle {
+            t.interval = c.autoThrottle
+        }
+        // 限流信号后的下一次请求同样需要等待间隔
+        t.next = now.Add(t.interval)
+        return
+    }
+
+    if t == nil || err != nil || now.Before(t.until) {
+        return
+    }
+
+    // 冷却结束后每次成功增加并发、缩短间隔，恢复到配置的并发数量时取消限流
+    t.limit++
+    if t.interval /= 2; t.interval < throttleMinInterval {
+        t.interval = 0
+    }
+    limit := hostLimit(c, host)
+    if limit <= 0 {
+        limit = c.limit
+    }
+    if t.interval == 0 && t.limit >= limit {
+        delete(s.throttles, host)
+    }
+}
+
+// wakeAt 按时钟在指定时间唤醒调度，需持有锁
+func (s *scheduler) wakeAt(clock Clock, at time.Time) {
+    if s.wake != nil {
+        close(s.wake)
+    }
+    stop := make(chan struct{})
+    s.wake = stop
+    after := clock.After(at.Sub(clock.Now()))
+    go func() {
+        select {
+        case <-stop:
+            return
+        case <-after:
+        }
+        s.mutex.Lock()
+        s.cond.Broadcast()
+        s.mutex.Unlock()
+    }()
+}
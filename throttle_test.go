@@ -0,0 +1,56 @@
+package req
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestAutoThrottleBackoff(t *testing.T) {
+    for _, code := range []int{http.StatusTooManyRequests, http.StatusServiceUnavailable} {
+        t.Run(http.StatusText(code), func(t *testing.T) {
+            clock := withFakeClock(t)
+            withRetry(t, 0, 0)
+            withHostLimit(t, 1)
+            SetAutoThrottle(time.Minute)
+            t.Cleanup(func() { SetAutoThrottle(0) })
+
+            server := NewMockServer()
+            defer server.Close()
+            route := server.Route(http.MethodGet, "/item/{id}").Status(code, 200).Body("ok")
+            defer server.Install()()
+
+            host := fmt.Sprintf("throttle%d.example.com", code)
+            t.Cleanup(func() {
+                s := getScheduler()
+                s.mutex.Lock()
+                delete(s.throttles, host)
+                s.mutex.Unlock()
+            })
+            result := make(chan *BatchResult, 1)
+            go func() {
+                result <- Batch(context.Background(), []string{"http://" + host + "/item/1", "http://" + host + "/item/2", "http://" + host + "/item/3"})
+            }()
+
+            // 限流后每次调度前等待间隔，推进时钟后继续
+            for i := 1; i <= 2; i++ {
+                clock.BlockUntil(1)
+                if calls := route.Calls(); calls != i {
+                    t.Fatalf("calls before interval %d = %d", i, calls)
+                }
+                clock.Advance(throttleInterval)
+            }
+
+            select {
+            case r := <-result:
+                if summary := r.Summary(); summary.Succeeded != 2 || summary.Failed != 1 {
+                    t.Fatalf("summary = %+v, want 2 succeeded and 1 failed", summary)
+                }
+            case <-time.After(time.Second * 5):
+                t.Fatal("throttled host was not resumed after advancing the clock")
+            }
+        })
+    }
+}
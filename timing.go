@@ -0,0 +1,228 @@
+package req
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Timings 请求各阶段耗时，复用连接时 DNS、Connect 与 TLS 为 0
+type Timings struct {
+    DNS     time.Duration
+    Connect time.Duration
+    TLS     time.Duration
+    // TTFB 请求发送完成到收到响应首字节，主要为服务端处理时间
+    TTFB time.Duration
+    // Download 收到响应首字节到读完响应体
+    Download time.Duration
+    // Total 开始请求到读完响应体
+    Total time.Duration
+}
+
+// String 各阶段耗时
+func (t Timings) String() string {
+    return fmt.Sprintf("dns=%s connect=%s tls=%s ttfb=%s download=%s total=%s",
+        t.DNS, t.Connect, t.TLS, t.TTFB, t.Download, t.Total)
+}
+
+// slowLog 慢请求回调
+type slowLog struct {
+    threshold time.Duration
+    fn        func(info RequestInfo, timings Timings)
+}
+
+// OnSlowRequest 设置慢请求回调，开始请求到读完响应体超过 threshold 时调用，附带各阶段耗时
+// fn 为 nil 时使用标准库 log 输出，threshold 为 0 时取消；回调在读取响应体的协程中执行，应避免阻塞
+func OnSlowRequest(threshold time.Duration, fn func(info RequestInfo, timings Timings)) {
+    if fn == nil {
+        fn = func(info RequestInfo, timings Timings) {
+            log.Printf("req: slow request %s %s %s", info.Method, info.URL, timings)
+        }
+    }
+
+    var slow *slowLog
+    if threshold > 0 {
+        slow = &slowLog{threshold: threshold, fn: fn}
+    }
+    updateConfig(func(c *config) { c.slowLog = slow })
+}
+
+// statsKey 上下文中的请求统计
+type statsKey struct{}
+
+// requestStats 请求统计，重试时耗时与大小为最后一次尝试
+type requestStats struct {
+    mutex    sync.Mutex
+    timings  Timings
+    start    time.Time
+    first    time.Time
+    done     bool
+    // info 与 slow 慢请求回调的请求信息与配置
+    info RequestInfo
+    slow *slowLog
+    attempts int
+    sent     int64
+    received int64
+}
+
+// withStats 将请求统计写入参数中的上下文
+func withStats(args []interface{}) ([]interface{}, *requestStats) {
+    s := &requestStats{}
+    return withContextValue(args, statsKey{}, s), s
+}
+
+// contextStats 上下文中的请求统计
+func contextStats(ctx context.Context) *requestStats {
+    s, _ := ctx.Value(statsKey{}).(*requestStats)
+    return s
+}
+
+// attempt 记录一次请求尝试
+func (s *requestStats) attempt() {
+    s.mutex.Lock()
+    s.attempts++
+    s.mutex.Unlock()
+}
+
+// snapshot 当前统计，响应体未读完时 Total 为已用时长
+func (s *requestStats) snapshot() (timings Timings, attempts int, sent, received int64) {
+    s.mutex.Lock()
+    defer s.mutex.Unlock()
+    timings = s.timings
+    if !s.done && !s.start.IsZero() {
+        timings.Total = time.Since(s.start)
+    }
+    return timings, s.attempts, s.sent, s.received
+}
+
+// trace 开始一次请求并返回记录各阶段耗时的跟踪
+func (s *requestStats) trace(r *http.Request) *httptrace.ClientTrace {
+    s.mutex.Lock()
+    s.start, s.first, s.done, s.timings, s.received = time.Now(), time.Time{}, false, Timings{}, 0
+    s.sent = r.ContentLength
+    s.slow = conf().slowLog
+    if s.slow != nil {
+        s.info = RequestInfo{Method: r.Method, URL: r.URL.String(), Labels: Labels(r.Context())}
+        if name := conf().requestIDHeader; name != "" {
+            s.info.RequestID = r.Header.Get(name)
+        }
+    }
+    s.mutex.Unlock()
+
+    var dnsStart, connectStart, tlsStart, wrote time.Time
+    record := func(fn func(now time.Time)) {
+        s.mutex.Lock()
+        fn(time.Now())
+        s.mutex.Unlock()
+    }
+    return &httptrace.ClientTrace{
+        DNSStart: func(httptrace.DNSStartInfo) {
+            record(func(now time.Time) { dnsStart = now })
+        },
+        DNSDone: func(httptrace.DNSDoneInfo) {
+            record(func(now time.Time) { s.timings.DNS = now.Sub(dnsStart) })
+        },
+        ConnectStart: func(string, string) {
+            record(func(now time.Time) {
+                if connectStart.IsZero() {
+                    connectStart = now
+                }
+            })
+        },
+        ConnectDone: func(string, string, error) {
+            record(func(now time.Time) { s.timings.Connect = now.Sub(connectStart) })
+        },
+        TLSHandshakeStart: func() {
+            record(func(now time.Time) { tlsStart = now })
+        },
+        TLSHandshakeDone: func(tls.ConnectionState, error) {
+            record(func(now time.Time) { s.timings.TLS = now.Sub(tlsStart) })
+        },
+        WroteRequest: func(httptrace.WroteRequestInfo) {
+            record(func(now time.Time) { wrote = now })
+        },
+        GotFirstResponseByte: func() {
+            record(func(now time.Time) {
+                s.first = now
+                if !wrote.IsZero() {
+                    s.timings.TTFB = now.Sub(wrote)
+                }
+            })
+        },
+    }
+}
+
+// finish 响应体读取结束，超过慢请求阈值时调用回调
+func (s *requestStats) finish() {
+    s.mutex.Lock()
+    if s.done {
+        s.mutex.Unlock()
+        return
+    }
+    now := time.Now()
+    s.done = true
+    s.timings.Total = now.Sub(s.start)
+    if !s.first.IsZero() {
+        s.timings.Download = now.Sub(s.first)
+    }
+    slow, info, timings := s.slow, s.info, s.timings
+    s.mutex.Unlock()
+
+    if slow != nil && timings.Total >= slow.threshold {
+        slow.fn(info, timings)
+    }
+}
+
+// statsBody 统计读取大小的响应体
+type statsBody struct {
+    io.ReadCloser
+    stats *requestStats
+}
+
+func (b *statsBody) Read(p []byte) (int, error) {
+    n, err := b.ReadCloser.Read(p)
+    b.stats.mutex.Lock()
+    b.stats.received += int64(n)
+    b.stats.mutex.Unlock()
+    if err == io.EOF {
+        b.stats.finish()
+    }
+    return n, err
+}
+
+func (b *statsBody) Close() error {
+    b.stats.finish()
+    return b.ReadCloser.Close()
+}
+
+// statsTransport 按上下文中的请求统计记录耗时与大小的传输层
+type statsTransport struct {
+    base http.RoundTripper
+}
+
+// RoundTrip 跟踪请求各阶段
+func (t *statsTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+    s := contextStats(r.Context())
+    if s == nil {
+        if conf().slowLog == nil {
+            return t.base.RoundTrip(r)
+        }
+        // 开启慢请求回调时每个请求单独统计
+        s = &requestStats{}
+    }
+
+    r = r.WithContext(httptrace.WithClientTrace(r.Context(), s.trace(r)))
+    rep, err := t.base.RoundTrip(r)
+    if err != nil {
+        s.finish()
+        return rep, err
+    }
+    rep.Body = &statsBody{ReadCloser: rep.Body, stats: s}
+    return rep, nil
+}
@@ -0,0 +1,358 @@
+package req
+
+import (
+	"context"
+	"net"
+	"net/http"
+	neturl "net/url"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	utls "github.com/refraction-networking/utls"
+)
+
+// TLSFingerprint TLS 握手指纹
+type TLSFingerprint string
+
+const (
+    // TLSFingerprintGo Go 标准库握手
+    TLSFingerprintGo TLSFingerprint = ""
+    // TLSFingerprintChrome 模拟 Chrome 握手
+    TLSFingerprintChrome TLSFingerprint = "chrome"
+    // TLSFingerprintFirefox 模拟 Firefox 握手
+    TLSFingerprintFirefox TLSFingerprint = "firefox"
+    // TLSFingerprintSafari 模拟 Safari 握手
+    TLSFingerprintSafari TLSFingerprint = "safari"
+    // TLSFingerprintEdge 模拟 Edge 握手
+    TLSFingerprintEdge TLSFingerprint = "edge"
+    // TLSFingerprintIOS 模拟 iOS 握手
+    TLSFingerprintIOS TLSFingerprint = "ios"
+)
+
+// helloID uTLS 握手标识
+func (f TLSFingerprint) helloID() (utls.ClientHelloID, error) {
+    switch f {
+    case TLSFingerprintChrome:
+        return utls.HelloChrome_Auto, nil
+    case TLSFingerprintFirefox:
+        return utls.HelloFirefox_Auto, nil
+    case TLSFingerprintSafari:
+        return utls.HelloSafari_Auto, nil
+    case TLSFingerprintEdge:
+        return utls.HelloEdge_Auto, nil
+    case TLSFingerprintIOS:
+        return utls.HelloIOS_Auto, nil
+    }
+    return utls.ClientHelloID{}, errors.Errorf("unknown tls fingerprint: %s", f)
+}
+
+// SetTLSFingerprint 设置 TLS 握手指纹，仅作用于不经过代理的 HTTPS 请求
+func SetTLSFingerprint(fingerprint TLSFingerprint) error {
+    if fingerprint != TLSFingerprintGo {
+        if _, err := fingerprint.helloID(); err != nil {
+            return err
+        }
+    }
+
+    updateConfig(func(c *config) { c.tlsFingerprint = fingerprint })
+    resetClient()
+    return nil
+}
+
+// WithTLSFingerprint 本次请求使用的 TLS 握手指纹，覆盖 SetTLSFingerprint，相同指纹的请求共用连接池
+func WithTLSFingerprint(fingerprint TLSFingerprint) Option {
+    var err error
+    if fingerprint != TLSFingerprintGo {
+        _, err = fingerprint.helloID()
+    }
+    return func(o *options) {
+        if err != nil {
+            o.err = err
+            return
+        }
+        o.tlsFingerprint = &fingerprint
+    }
+}
+
+// SetProxy 设置代理地址，支持 http/https/socks5，为空时按 SetProxyFromEnvironment 决定是否使用环境变量
+func SetProxy(proxy string) error {
+    var u *neturl.URL
+    if proxy != "" {
+        var err error
+        if u, err = parseProxy(proxy); err != nil {
+            return err
+        }
+    }
+
+    updateConfig(func(c *config) { c.proxy = u })
+    resetClient()
+    return nil
+}
+
+// SetProxyFromEnvironment 设置未指定代理时是否使用 HTTP_PROXY/HTTPS_PROXY/NO_PROXY 环境变量，默认开启
+// 同时作用于请求、下载、CurlGet 与 ChromeGet
+func SetProxyFromEnvironment(enable bool) {
+    updateConfig(func(c *config) { c.proxyFromEnv = enable })
+    resetClient()
+}
+
+// proxyFor 链接使用的代理，不使用代理时返回 nil
+func proxyFor(rawurl string) (*neturl.URL, error) {
+    c := conf()
+    if c.proxy != nil {
+        return c.proxy, nil
+    }
+    if !c.proxyFromEnv {
+        return nil, nil
+    }
+
+    r, err := http.NewRequest(http.MethodGet, rawurl, nil)
+    if err != nil {
+        return nil, errors.WithStack(err)
+    }
+    u, err := http.ProxyFromEnvironment(r)
+    return u, errors.WithStack(err)
+}
+
+// parseProxy 解析代理地址
+func parseProxy(proxy string) (*neturl.URL, error) {
+    u, err := neturl.Parse(proxy)
+    if err != nil {
+        return nil, errors.WithStack(err)
+    }
+    switch u.Scheme {
+    case "http", "https", "socks5", "socks5h":
+    default:
+        return nil, errors.Errorf("unsupported proxy scheme: %s", proxy)
+    }
+    return u, nil
+}
+
+// SetExpectContinueTimeout 设置发送 Expect: 100-continue 后等待服务端响应的时长，超时后直接发送请求体，默认 1 秒
+func SetExpectContinueTimeout(timeout time.Duration) {
+    updateConfig(func(c *config) { c.expectContinueTimeout = timeout })
+    resetClient()
+}
+
+// resetClient 按当前配置重建请求客户端，保留原有 Cookie，进行中的请求继续使用原有客户端
+func resetClient() {
+    var old *clientSet
+    updateConfig(func(c *config) {
+        old = c.clients
+        c.client = &http.Client{
+            Transport: wrapTransport(c, defaultKey(c), newTransport(c, defaultKey(c))),
+            Jar:       c.jar,
+            Timeout:   c.timeout,
+        }
+        c.clients = &clientSet{config: c, clients: map[clientKey]*http.Client{}}
+    })
+    if old != nil {
+        old.closeIdle()
+    }
+}
+
+// clientKey 区分连接池的请求设置
+type clientKey struct {
+    // local 绑定的本地地址
+    local string
+    // fingerprint TLS 握手指纹
+    fingerprint TLSFingerprint
+    // serverAuth 服务端认证
+    serverAuth Authenticator
+    // proxyAuth 代理认证
+    proxyAuth Authenticator
+}
+
+// defaultKey 默认客户端的请求设置
+func defaultKey(c *config) clientKey {
+    return clientKey{fingerprint: c.tlsFingerprint, serverAuth: c.serverAuth, proxyAuth: c.proxyAuth}
+}
+
+// clientSet 按同一配置创建的非默认客户端，连接池相互隔离，重建客户端时整体替换
+type clientSet struct {
+    config  *config
+    mutex   sync.Mutex
+    clients map[clientKey]*http.Client
+}
+
+// transport 按请求设置获取传输层，不存在时创建
+func (s *clientSet) transport(key clientKey) http.RoundTripper {
+    s.mutex.Lock()
+    defer s.mutex.Unlock()
+    client, ok := s.clients[key]
+    if !ok {
+        client = &http.Client{Transport: wrapTransport(s.config, key, newTransport(s.config, key))}
+        s.clients[key] = client
+    }
+    return client.Transport
+}
+
+// closeIdle 关闭全部客户端的空闲连接
+func (s *clientSet) closeIdle() {
+    s.config.client.CloseIdleConnections()
+    s.mutex.Lock()
+    defer s.mutex.Unlock()
+    for _, client := range s.clients {
+        client.CloseIdleConnections()
+    }
+}
+
+// newTransport 按配置创建传输层，key 指定绑定的本地地址等请求设置
+func newTransport(c *config, key clientKey) *http.Transport {
+    transport := http.DefaultTransport.(*http.Transport).Clone()
+    switch {
+    case c.proxy != nil:
+        transport.Proxy = http.ProxyURL(c.proxy)
+    case !c.proxyFromEnv:
+        transport.Proxy = nil
+    }
+    if c.expectContinueTimeout > 0 {
+        transport.ExpectContinueTimeout = c.expectContinueTimeout
+    }
+    transport.DialContext = dialContext(c, net.ParseIP(key.local))
+    dial := transport.DialContext
+    if auth := key.proxyAuth; auth != nil {
+        // HTTPS 请求改为自行建立隧道，在同一连接上完成代理认证
+        transport.Proxy = tunnelProxy(transport.Proxy)
+        dial = dialTunnel(dial, auth)
+        transport.DialTLSContext = dialTLS(dial)
+    }
+    if fingerprint := key.fingerprint; fingerprint != TLSFingerprintGo {
+        transport.DialTLSContext = dialUTLS(fingerprint, dial)
+    }
+    if addr := c.mockAddr; addr != "" {
+        // 模拟服务不使用 TLS，HTTPS 请求直接使用明文连接
+        mock := func(ctx context.Context, network, _ string) (net.Conn, error) {
+            return (&net.Dialer{}).DialContext(ctx, network, addr)
+        }
+        transport.Proxy = nil
+        transport.DialContext = mock
+        transport.DialTLSContext = mock
+    }
+    return transport
+}
+
+// dialContext 按配置创建建立连接的函数，local 不为 nil 时绑定本地地址
+func dialContext(c *config, local net.IP) func(ctx context.Context, network, addr string) (net.Conn, error) {
+    dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second, FallbackDelay: c.fallbackDelay}
+    if local != nil {
+        dialer.LocalAddr = &net.TCPAddr{IP: local}
+    }
+    dial := dialer.DialContext
+    if c.ipPreference != IPDefault || len(c.hostIPs) > 0 || c.addressFailover || c.guard != nil {
+        dial = preferenceDial(dialer)
+    }
+    if guard := c.guard; guard != nil {
+        dial = guard.dialContext(dial, proxyDialAddrs(c))
+    }
+    return dial
+}
+
+// proxyDialAddrs 配置的代理地址，包括环境变量中的代理
+func proxyDialAddrs(c *config) []string {
+    var proxies []*neturl.URL
+    if c.proxy != nil {
+        proxies = append(proxies, c.proxy)
+    } else if c.proxyFromEnv {
+        for _, scheme := range []string{"http", "https"} {
+            r := &http.Request{URL: &neturl.URL{Scheme: scheme, Host: "proxy.invalid"}}
+            if u, err := http.ProxyFromEnvironment(r); err == nil && u != nil {
+                proxies = append(proxies, u)
+            }
+        }
+    }
+
+    addrs := make([]string, 0, len(proxies))
+    for _, u := range proxies {
+        port := u.Port()
+        if port == "" {
+            switch u.Scheme {
+            case "https":
+                port = "443"
+            case "socks5", "socks5h":
+                port = "1080"
+            default:
+                port = "80"
+            }
+        }
+        addrs = append(addrs, net.JoinHostPort(u.Hostname(), port))
+    }
+    return addrs
+}
+
+// wrapTransport 按配置与请求设置包装传输层
+func wrapTransport(c *config, key clientKey, transport http.RoundTripper) http.RoundTripper {
+    transport = &bandwidthTransport{base: transport}
+    transport = &quotaTransport{base: transport}
+    transport = &statsTransport{base: transport}
+    transport = &failoverTransport{base: transport}
+    if guard := c.guard; guard != nil {
+        transport = &guardTransport{base: transport, guard: guard}
+    }
+    if recorder := c.harRecorder; recorder != nil {
+        transport = &harTransport{base: transport, recorder: recorder}
+    }
+    if config := c.dumper; config != nil {
+        transport = &dumpTransport{base: transport, config: config}
+    }
+    if auth := key.proxyAuth; auth != nil {
+        transport = &negotiateTransport{base: transport, auth: auth, proxy: true}
+    }
+    if auth := key.serverAuth; auth != nil {
+        transport = &negotiateTransport{base: transport, auth: auth}
+    }
+    transport = &digestTransport{base: transport}
+    return &editTransport{base: transport}
+}
+
+// dialUTLS 在 dial 建立的连接上使用 uTLS 模拟浏览器握手
+func dialUTLS(fingerprint TLSFingerprint, dial func(ctx context.Context, network, addr string) (net.Conn, error)) func(ctx context.Context, network, addr string) (net.Conn, error) {
+    return func(ctx context.Context, network, addr string) (net.Conn, error) {
+        id, err := fingerprint.helloID()
+        if err != nil {
+            return nil, err
+        }
+
+        spec, err := utls.UTLSIdToSpec(id)
+        if err != nil {
+            return nil, errors.WithStack(err)
+        }
+
+        // 标准 Transport 无法在自定义 TLS 连接上使用 HTTP/2，只协商 HTTP/1.1
+        for _, ext := range spec.Extensions {
+            if alpn, ok := ext.(*utls.ALPNExtension); ok {
+                alpn.AlpnProtocols = []string{"http/1.1"}
+            }
+        }
+
+        host, _, err := net.SplitHostPort(addr)
+        if err != nil {
+            return nil, errors.WithStack(err)
+        }
+
+        conn, err := dial(ctx, network, addr)
+        if err != nil {
+            return nil, errors.WithStack(err)
+        }
+
+        uconn := utls.UClient(conn, &utls.Config{ServerName: host}, utls.HelloCustom)
+        if err = uconn.ApplyPreset(&spec); err != nil {
+            conn.Close()
+            return nil, errors.WithStack(err)
+        }
+        if err = uconn.HandshakeContext(ctx); err != nil {
+            conn.Close()
+            return nil, errors.WithStack(err)
+        }
+
+        return uconn, nil
+    }
+}
+
+// streamClient 流式请求客户端，不设置整体超时，由 ctx 控制
+func streamClient() *http.Client {
+    client := *conf().client
+    client.Timeout = 0
+    return &client
+}
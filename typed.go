@@ -0,0 +1,111 @@
+package req
+
+import (
+	"fmt"
+	neturl "net/url"
+	"reflect"
+	"strconv"
+
+	"github.com/imroc/req"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+)
+
+// Headers 请求头，可与其他参数一起传入 Get/Post 等方法
+type Headers map[string]string
+
+// Query 查询参数，值支持字符串、布尔、数值与 fmt.Stringer，可与其他参数一起传入 Get/Post 等方法
+type Query map[string]interface{}
+
+// JSONBody 使用 JSON 编码请求体，并设置 Content-Type
+func JSONBody(v interface{}) Option {
+    data, err := jsoniter.Marshal(v)
+    return func(o *options) {
+        if err != nil {
+            o.err = errors.Wrap(err, "json body")
+            return
+        }
+        o.body, o.contentType = data, "application/json; charset=utf-8"
+    }
+}
+
+// FormBody 使用表单编码请求体，并设置 Content-Type
+// v 支持 url.Values、map[string]string、map[string][]string 与值为标量的 map[string]interface{}
+func FormBody(v interface{}) Option {
+    form := neturl.Values{}
+    var err error
+    switch values := v.(type) {
+    case neturl.Values:
+        form = values
+    case map[string][]string:
+        form = values
+    case map[string]string:
+        for key, value := range values {
+            form.Set(key, value)
+        }
+    case map[string]interface{}:
+        for key, value := range values {
+            s, ok := scalarString(value)
+            if !ok {
+                err = errors.Errorf("form body %s: unsupported type %T", key, value)
+                break
+            }
+            form.Set(key, s)
+        }
+    default:
+        err = errors.Errorf("form body: unsupported type %T", v)
+    }
+
+    data := []byte(form.Encode())
+    return func(o *options) {
+        if err != nil {
+            o.err = err
+            return
+        }
+        o.body, o.contentType = data, "application/x-www-form-urlencoded"
+    }
+}
+
+// typedArg 将 Headers/Query 转换为 imroc/req 参数
+func typedArg(arg interface{}) (interface{}, error) {
+    switch a := arg.(type) {
+    case Headers:
+        return req.Header(a), nil
+    case Query:
+        query := make(req.QueryParam, len(a))
+        for key, value := range a {
+            s, ok := scalarString(value)
+            if !ok {
+                return nil, errors.Errorf("query %s: unsupported type %T", key, value)
+            }
+            query[key] = s
+        }
+        return query, nil
+    }
+    return arg, nil
+}
+
+// scalarString 标量值转换为字符串
+func scalarString(value interface{}) (string, bool) {
+    switch v := value.(type) {
+    case string:
+        return v, true
+    case fmt.Stringer:
+        return v.String(), true
+    }
+
+    rv := reflect.ValueOf(value)
+    switch rv.Kind() {
+    case reflect.Bool:
+        return strconv.FormatBool(rv.Bool()), true
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+        return strconv.FormatInt(rv.Int(), 10), true
+    case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+        return strconv.FormatUint(rv.Uint(), 10), true
+    case reflect.Float32, reflect.Float64:
+        return strconv.FormatFloat(rv.Float(), 'f', -1, rv.Type().Bits()), true
+    case reflect.String:
+        return rv.String(), true
+    }
+    return "", false
+}
@@ -0,0 +1,77 @@
+package req
+
+import (
+	"fmt"
+	neturl "net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// URLError 链接校验错误
+type URLError struct {
+    URL    string
+    Reason string
+}
+
+// Error 错误信息
+func (e *URLError) Error() string {
+    return fmt.Sprintf("invalid url %s: %s", truncate(e.URL, 200), e.Reason)
+}
+
+// SetAllowedSchemes 设置 http/https 之外允许的协议，如 ws、wss，设置后 Download 只允许列表中的 ftp/sftp
+func SetAllowedSchemes(schemes ...string) {
+    list := make([]string, 0, len(schemes))
+    for _, scheme := range schemes {
+        list = append(list, strings.ToLower(scheme))
+    }
+    updateConfig(func(c *config) { c.allowedSchemes = list })
+}
+
+// SetMaxURLLength 设置链接最大长度，默认 8192，为 0 时不限制
+func SetMaxURLLength(length int) {
+    updateConfig(func(c *config) { c.maxURLLength = length })
+}
+
+// ValidateURL 校验链接，拒绝不允许的协议、带用户信息的链接、缺少主机与超长链接
+// 发起请求前会自动校验，认证信息请通过 Authorization 请求头传递
+func ValidateURL(rawurl string) error {
+    return validateURL(rawurl)
+}
+
+// validateURL 校验链接，schemes 为未设置 SetAllowedSchemes 时本次额外允许的协议
+func validateURL(rawurl string, schemes ...string) error {
+    c := conf()
+    if c.maxURLLength > 0 && len(rawurl) > c.maxURLLength {
+        return errors.WithStack(&URLError{URL: rawurl, Reason: fmt.Sprintf("length %d exceeds %d", len(rawurl), c.maxURLLength)})
+    }
+
+    u, err := neturl.Parse(rawurl)
+    if err != nil {
+        return errors.WithStack(&URLError{URL: rawurl, Reason: err.Error()})
+    }
+
+    scheme := strings.ToLower(u.Scheme)
+    // 已设置允许的协议时，额外允许的协议也需在其中
+    extra := c.allowedSchemes == nil && containsString(schemes, scheme)
+    if scheme != "http" && scheme != "https" && !containsString(c.allowedSchemes, scheme) && !extra {
+        return errors.WithStack(&URLError{URL: rawurl, Reason: fmt.Sprintf("scheme not allowed: %q", u.Scheme)})
+    }
+    if u.User != nil {
+        return errors.WithStack(&URLError{URL: rawurl, Reason: "userinfo not allowed"})
+    }
+    if u.Host == "" || u.Hostname() == "" {
+        return errors.WithStack(&URLError{URL: rawurl, Reason: "missing host"})
+    }
+    return nil
+}
+
+// containsString 切片是否包含字符串
+func containsString(list []string, s string) bool {
+    for _, item := range list {
+        if item == s {
+            return true
+        }
+    }
+    return false
+}
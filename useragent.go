@@ -0,0 +1,120 @@
+package req
+
+import (
+	"math/rand"
+
+	"github.com/imroc/req"
+)
+
+// UserAgent 浏览器标识，包含与 User-Agent 匹配的客户端提示请求头
+type UserAgent struct {
+    Value           string
+    AcceptLanguage  string
+    SecCHUA         string
+    SecCHUAMobile   string
+    SecCHUAPlatform string
+}
+
+// Header 生成请求头
+func (u UserAgent) Header() req.Header {
+    header := req.Header{}
+    if u.Value != "" {
+        header["User-Agent"] = u.Value
+    }
+    if u.AcceptLanguage != "" {
+        header["Accept-Language"] = u.AcceptLanguage
+    }
+    if u.SecCHUA != "" {
+        header["Sec-Ch-Ua"] = u.SecCHUA
+        header["Sec-Ch-Ua-Mobile"] = u.SecCHUAMobile
+        header["Sec-Ch-Ua-Platform"] = u.SecCHUAPlatform
+    }
+    return header
+}
+
+var (
+    // UserAgentChromeWindows Windows Chrome
+    UserAgentChromeWindows = UserAgent{
+        Value:           "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36",
+        AcceptLanguage:  "en-US,en;q=0.9",
+        SecCHUA:         `"Chromium";v="124", "Google Chrome";v="124", "Not-A.Brand";v="99"`,
+        SecCHUAMobile:   "?0",
+        SecCHUAPlatform: `"Windows"`,
+    }
+    // UserAgentChromeMac macOS Chrome
+    UserAgentChromeMac = UserAgent{
+        Value:           "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36",
+        AcceptLanguage:  "en-US,en;q=0.9",
+        SecCHUA:         `"Chromium";v="124", "Google Chrome";v="124", "Not-A.Brand";v="99"`,
+        SecCHUAMobile:   "?0",
+        SecCHUAPlatform: `"macOS"`,
+    }
+    // UserAgentEdge Windows Edge
+    UserAgentEdge = UserAgent{
+        Value:           "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36 Edg/124.0.0.0",
+        AcceptLanguage:  "en-US,en;q=0.9",
+        SecCHUA:         `"Chromium";v="124", "Microsoft Edge";v="124", "Not-A.Brand";v="99"`,
+        SecCHUAMobile:   "?0",
+        SecCHUAPlatform: `"Windows"`,
+    }
+    // UserAgentChromeAndroid Android Chrome
+    UserAgentChromeAndroid = UserAgent{
+        Value:           "Mozilla/5.0 (Linux; Android 10; K) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Mobile Safari/537.36",
+        AcceptLanguage:  "en-US,en;q=0.9",
+        SecCHUA:         `"Chromium";v="124", "Google Chrome";v="124", "Not-A.Brand";v="99"`,
+        SecCHUAMobile:   "?1",
+        SecCHUAPlatform: `"Android"`,
+    }
+    // UserAgentFirefox Windows Firefox
+    UserAgentFirefox = UserAgent{
+        Value:          "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:125.0) Gecko/20100101 Firefox/125.0",
+        AcceptLanguage: "en-US,en;q=0.5",
+    }
+    // UserAgentSafari macOS Safari
+    UserAgentSafari = UserAgent{
+        Value:          "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Safari/605.1.15",
+        AcceptLanguage: "en-US,en;q=0.9",
+    }
+    // UserAgentSafariIOS iPhone Safari
+    UserAgentSafariIOS = UserAgent{
+        Value:          "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1",
+        AcceptLanguage: "en-US,en;q=0.9",
+    }
+
+    // UserAgentBrowsers 常用桌面与移动浏览器
+    UserAgentBrowsers = []UserAgent{
+        UserAgentChromeWindows,
+        UserAgentChromeMac,
+        UserAgentEdge,
+        UserAgentChromeAndroid,
+        UserAgentFirefox,
+        UserAgentSafari,
+        UserAgentSafariIOS,
+    }
+)
+
+// SetUserAgent 设置 User-Agent
+func SetUserAgent(ua string) {
+    updateConfig(func(c *config) { c.userAgent = UserAgent{Value: ua} })
+}
+
+// SetBrowserUserAgent 设置浏览器标识，同时发送匹配的客户端提示请求头
+func SetBrowserUserAgent(ua UserAgent) {
+    updateConfig(func(c *config) { c.userAgent = ua })
+}
+
+// SetUserAgentPool 设置轮换 User-Agent 池，每次请求随机选择，为空时关闭轮换
+func SetUserAgentPool(agents ...UserAgent) {
+    pool := append([]UserAgent{}, agents...)
+    updateConfig(func(c *config) { c.userAgentPool = pool })
+}
+
+// userAgentHeader 本次请求使用的 User-Agent 请求头
+func userAgentHeader() req.Header {
+    c := conf()
+    if pool := c.userAgentPool; len(pool) > 0 {
+        pool = activeAgents(pool)
+        return pool[rand.Intn(len(pool))].Header()
+    }
+    return c.userAgent.Header()
+}
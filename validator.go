@@ -0,0 +1,196 @@
+package req
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/imroc/req"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+)
+
+// Validator 条件请求校验值
+type Validator struct {
+    ETag         string `json:"etag,omitempty"`
+    LastModified string `json:"last_modified,omitempty"`
+}
+
+// ValidatorStore 按链接保存条件请求校验值
+type ValidatorStore interface {
+    Load(url string) (Validator, bool)
+    Store(url string, v Validator) error
+}
+
+// SetValidatorStore 设置 GetIfChanged 使用的校验值存储，默认保存在内存中
+func SetValidatorStore(store ValidatorStore) {
+    if store == nil {
+        store = NewMemoryValidatorStore()
+    }
+    updateConfig(func(c *config) { c.validatorStore = store })
+}
+
+// GetIfChanged GET请求，自动发送上次响应的 If-None-Match/If-Modified-Since
+// 内容未变化时返回空内容与 changed=false，不保存响应内容，与文件缓存相互独立
+func GetIfChanged(url string, v ...interface{}) (body string, changed bool, err error) {
+    store := conf().validatorStore
+    old, ok := store.Load(url)
+
+    header := req.Header{}
+    if ok {
+        if old.ETag != "" {
+            header["If-None-Match"] = old.ETag
+        }
+        if old.LastModified != "" {
+            header["If-Modified-Since"] = old.LastModified
+        }
+    }
+
+    rep, err := doResponse(http.MethodGet, url, withHeader(v, header)...)
+    if err != nil {
+        return "", false, err
+    }
+    res := rep.Response()
+    if res.StatusCode == http.StatusNotModified {
+        res.Body.Close()
+        return "", false, nil
+    }
+
+    body = string(rep.Bytes())
+    validator := Validator{ETag: res.Header.Get("ETag"), LastModified: res.Header.Get("Last-Modified")}
+    if validator != (Validator{}) {
+        if err = store.Store(url, validator); err != nil {
+            return "", false, err
+        }
+    }
+
+    // 服务端不支持条件请求但 ETag 未变化时同样视为未变化
+    changed = !ok || validator.ETag == "" || validator.ETag != old.ETag
+    return body, changed, nil
+}
+
+// 条件请求结果状态
+const (
+    // ChangeUnchanged 内容未变化
+    ChangeUnchanged = iota
+    // ChangeChanged 内容已变化，返回新内容
+    ChangeChanged
+    // ChangeFailed 请求失败
+    ChangeFailed
+)
+
+// ChangeEntry 批量条件请求条目结果
+type ChangeEntry struct {
+    // Index 输入下标
+    Index int
+    URL   string
+    // State 结果状态，ChangeUnchanged/ChangeChanged/ChangeFailed
+    State int
+    Body  string
+    Err   error
+}
+
+// BatchGetIfChanged 批量条件请求，每个链接发送已保存的校验值，适用于轮询大量订阅源
+// 返回按输入顺序排列的结果，重复链接只请求一次
+func BatchGetIfChanged(ctx context.Context, urls []string, v ...interface{}) []ChangeEntry {
+    var (
+        wg      sync.WaitGroup
+        opts, _ = splitOptions(v)
+        unique  = uniqueURLs(urls)
+        entries = make([]ChangeEntry, len(urls))
+        items   = make([]*batchItem, 0, len(unique))
+    )
+    for _, indexes := range unique {
+        indexes := indexes
+        url := urls[indexes[0]]
+        wg.Add(1)
+        items = append(items, newBatchItem(indexes[0], url, opts.priority, func() error {
+            defer wg.Done()
+            body, changed, err := GetIfChanged(url, append([]interface{}{ctx}, v...)...)
+            state := ChangeUnchanged
+            switch {
+            case err != nil:
+                state = ChangeFailed
+            case changed:
+                state = ChangeChanged
+            }
+            for _, i := range indexes {
+                entries[i] = ChangeEntry{Index: i, URL: url, State: state, Body: body, Err: err}
+            }
+            return err
+        }))
+    }
+
+    getScheduler().submit(items...)
+    wg.Wait()
+    return entries
+}
+
+// MemoryValidatorStore 内存校验值存储
+type MemoryValidatorStore struct {
+    values sync.Map
+}
+
+// NewMemoryValidatorStore 创建内存校验值存储
+func NewMemoryValidatorStore() *MemoryValidatorStore {
+    return &MemoryValidatorStore{}
+}
+
+// Load 读取校验值
+func (s *MemoryValidatorStore) Load(url string) (Validator, bool) {
+    v, ok := s.values.Load(url)
+    if !ok {
+        return Validator{}, false
+    }
+    return v.(Validator), true
+}
+
+// Store 保存校验值
+func (s *MemoryValidatorStore) Store(url string, v Validator) error {
+    s.values.Store(url, v)
+    return nil
+}
+
+// FileValidatorStore 文件校验值存储，全部校验值以 JSON 保存在一个文件中
+type FileValidatorStore struct {
+    path   string
+    mutex  sync.Mutex
+    values map[string]Validator
+}
+
+// NewFileValidatorStore 创建文件校验值存储，文件存在时读取已有校验值
+func NewFileValidatorStore(path string) (*FileValidatorStore, error) {
+    s := &FileValidatorStore{path: path, values: map[string]Validator{}}
+    data, err := os.ReadFile(path)
+    if os.IsNotExist(err) {
+        return s, nil
+    } else if err != nil {
+        return nil, errors.WithStack(err)
+    }
+    if err = jsoniter.Unmarshal(data, &s.values); err != nil {
+        return nil, errors.Wrapf(err, "validator store: %s", path)
+    }
+    return s, nil
+}
+
+// Load 读取校验值
+func (s *FileValidatorStore) Load(url string) (Validator, bool) {
+    s.mutex.Lock()
+    defer s.mutex.Unlock()
+    v, ok := s.values[url]
+    return v, ok
+}
+
+// Store 保存校验值并写入文件
+func (s *FileValidatorStore) Store(url string, v Validator) error {
+    s.mutex.Lock()
+    defer s.mutex.Unlock()
+    s.values[url] = v
+
+    data, err := jsoniter.Marshal(s.values)
+    if err != nil {
+        return errors.WithStack(err)
+    }
+    return errors.WithStack(os.WriteFile(s.path, data, os.ModePerm))
+}
@@ -0,0 +1,62 @@
+package req
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// WithCacheRefresh 忽略已有缓存重新请求，成功后更新缓存
+func WithCacheRefresh() Option {
+    return func(o *options) {
+        o.cacheRefresh = true
+    }
+}
+
+// CacheWarm 在后台按 interval 周期刷新链接的缓存，首次立即刷新，返回停止函数
+// 刷新经过批量调度，受 SetLimit 与 SetHostLimit 限制；请求失败时保留原有缓存
+// 需先设置缓存目录，v 中的参数需与读取时一致，否则缓存名称不同
+func CacheWarm(urls []string, interval time.Duration, v ...interface{}) (stop func()) {
+    ctx, cancel := context.WithCancel(context.Background())
+    args := append([]interface{}{WithCacheRefresh()}, v...)
+    opts, _ := splitOptions(v)
+
+    go func() {
+        for {
+            _ = streamBatch(ctx, URLsFromSlice(urls), opts, func(ctx context.Context, url string) error {
+                _, err := Get(url, append([]interface{}{ctx}, args...)...)
+                return err
+            })
+
+            select {
+            case <-conf().clock.After(interval):
+            case <-ctx.Done():
+                return
+            }
+        }
+    }()
+    return cancel
+}
+
+// writeCache 写入临时文件后替换缓存，避免并发读取到不完整的内容
+func writeCache(name string, data []byte) error {
+    file, err := os.CreateTemp(filepath.Dir(name), ".tmp-*")
+    if err != nil {
+        return errors.WithStack(err)
+    }
+    _, err = file.Write(data)
+    if cerr := file.Close(); err == nil {
+        err = cerr
+    }
+    if err == nil {
+        err = os.Rename(file.Name(), name)
+    }
+    if err != nil {
+        _ = os.Remove(file.Name())
+        return errors.WithStack(err)
+    }
+    return nil
+}
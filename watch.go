@@ -0,0 +1,77 @@
+package req
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+    // htmlNoisePattern 比较时忽略的脚本、样式与注释
+    htmlNoisePattern = regexp.MustCompile(`(?is)<script\b.*?</script>|<style\b.*?</style>|<!--.*?-->`)
+    // timestampPattern 比较时忽略的日期、时间与 Unix 时间戳
+    timestampPattern = regexp.MustCompile(`\d{4}-\d{2}-\d{2}([T ]\d{2}:\d{2}(:\d{2})?(\.\d+)?(Z|[+-]\d{2}:?\d{2})?)?|\b\d{1,2}:\d{2}(:\d{2})?\b|\b1\d{9}(\d{3})?\b`)
+    // spacePattern 连续空白
+    spacePattern = regexp.MustCompile(`\s+`)
+)
+
+// WithWatchNormalize 设置 Watch 比较内容前的规范化函数，默认比较原始内容
+func WithWatchNormalize(normalize func([]byte) []byte) Option {
+    return func(o *options) {
+        o.watchNormalize = normalize
+    }
+}
+
+// NormalizeHTML 去除脚本、样式、注释、日期时间与时间戳并合并空白，用于忽略页面中每次变化的无关内容
+func NormalizeHTML(data []byte) []byte {
+    data = htmlNoisePattern.ReplaceAll(data, nil)
+    data = timestampPattern.ReplaceAll(data, nil)
+    return bytes.TrimSpace(spacePattern.ReplaceAll(data, []byte(" ")))
+}
+
+// Watch 按 interval 周期请求链接，内容变化时回调 fn，直到 ctx 结束
+// 设置缓存目录时以缓存内容为初始版本并在每次请求后更新缓存，否则以首次请求的内容为初始版本
+// 比较规范化后内容的 SHA-256，fn 收到原始内容；请求失败时继续监控
+func Watch(ctx context.Context, url string, interval time.Duration, fn func(old, new []byte), v ...interface{}) error {
+    opts, args := splitOptions(v)
+    if opts.err != nil {
+        return opts.err
+    }
+    normalize := opts.watchNormalize
+    if normalize == nil {
+        normalize = func(data []byte) []byte { return data }
+    }
+
+    var (
+        old     []byte
+        oldHash [sha256.Size]byte
+        known   bool
+    )
+    if name := cacheName(http.MethodGet, url, args...); name != "" {
+        if data, err := readCache(name); err == nil {
+            old, oldHash, known = data, sha256.Sum256(normalize(data)), true
+        }
+    }
+
+    v = append([]interface{}{ctx, WithCacheRefresh()}, v...)
+    for {
+        if data, err := GetBytes(url, v...); err == nil {
+            hash := sha256.Sum256(normalize(data))
+            if known && hash != oldHash {
+                fn(old, data)
+            }
+            old, oldHash, known = data, hash, true
+        }
+
+        select {
+        case <-ctx.Done():
+            return errors.WithStack(ctx.Err())
+        case <-conf().clock.After(interval):
+        }
+    }
+}
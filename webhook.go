@@ -0,0 +1,249 @@
+package req
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/imroc/req"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+)
+
+// 推送状态
+const (
+    WebhookPending   = "pending"
+    WebhookDelivered = "delivered"
+    WebhookFailed    = "failed"
+)
+
+var (
+    // webhookBaseWait 首次重试等待时长，之后按 2 倍递增
+    webhookBaseWait = time.Second
+    // webhookMaxWait 重试最长等待时长
+    webhookMaxWait = time.Hour
+    // webhookTick 队列扫描间隔
+    webhookTick = time.Second
+)
+
+// WebhookDelivery 推送记录
+type WebhookDelivery struct {
+    ID        string              `json:"id"`
+    URL       string              `json:"url"`
+    Payload   jsoniter.RawMessage `json:"payload"`
+    Status    string              `json:"status"`
+    Attempts  int                 `json:"attempts"`
+    LastError string              `json:"last_error,omitempty"`
+    CreatedAt time.Time           `json:"created_at"`
+    NextAt    time.Time           `json:"next_at"`
+}
+
+// Webhook 推送器，待推送记录保存在磁盘目录中，进程重启后继续重试
+type Webhook struct {
+    // SignatureHeader 签名请求头，默认 X-Signature-256，值为 sha256=<hex>
+    SignatureHeader string
+    // MaxAttempts 最大尝试次数，默认 10
+    MaxAttempts int
+    // OnDelivery 推送成功、失败或等待重试时回调
+    OnDelivery func(WebhookDelivery)
+
+    dir     string
+    secret  []byte
+    mutex   sync.Mutex
+    running map[string]bool
+}
+
+// NewWebhook 创建推送器，dir 为队列目录，secret 为空时不签名
+func NewWebhook(dir, secret string) (*Webhook, error) {
+    path, err := filepath.Abs(dir)
+    if err != nil {
+        return nil, errors.WithStack(err)
+    }
+    if err = os.MkdirAll(path, os.ModePerm); err != nil {
+        return nil, errors.WithStack(err)
+    }
+
+    return &Webhook{
+        SignatureHeader: "X-Signature-256",
+        MaxAttempts:     10,
+        dir:             path,
+        secret:          []byte(secret),
+        running:         make(map[string]bool),
+    }, nil
+}
+
+// Enqueue 加入推送队列，返回推送编号
+func (w *Webhook) Enqueue(url string, payload interface{}) (string, error) {
+    data, err := jsoniter.Marshal(payload)
+    if err != nil {
+        return "", errors.WithStack(err)
+    }
+
+    now := conf().clock.Now()
+    delivery := WebhookDelivery{
+        ID:        newUUID(),
+        URL:       url,
+        Payload:   data,
+        Status:    WebhookPending,
+        CreatedAt: now,
+        NextAt:    now,
+    }
+    return delivery.ID, w.save(delivery)
+}
+
+// Get 查询推送记录
+func (w *Webhook) Get(id string) (WebhookDelivery, error) {
+    var delivery WebhookDelivery
+    for _, ext := range []string{".json", ".done", ".failed"} {
+        data, err := os.ReadFile(filepath.Join(w.dir, id+ext))
+        if err == nil {
+            return delivery, errors.WithStack(jsoniter.Unmarshal(data, &delivery))
+        }
+    }
+    return delivery, errors.Errorf("webhook delivery not found: %s", id)
+}
+
+// Run 处理推送队列直到 ctx 结束
+func (w *Webhook) Run(ctx context.Context) {
+    ticker := time.NewTicker(webhookTick)
+    defer ticker.Stop()
+
+    for {
+        w.dispatch(ctx)
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+        }
+    }
+}
+
+// dispatch 推送到期记录
+func (w *Webhook) dispatch(ctx context.Context) {
+    files, err := filepath.Glob(filepath.Join(w.dir, "*.json"))
+    if err != nil {
+        return
+    }
+
+    limit := make(chan struct{}, conf().limit)
+    for _, file := range files {
+        data, err := os.ReadFile(file)
+        if err != nil {
+            continue
+        }
+
+        var delivery WebhookDelivery
+        if err = jsoniter.Unmarshal(data, &delivery); err != nil || delivery.NextAt.After(conf().clock.Now()) {
+            continue
+        }
+
+        w.mutex.Lock()
+        if w.running[delivery.ID] {
+            w.mutex.Unlock()
+            continue
+        }
+        w.running[delivery.ID] = true
+        w.mutex.Unlock()
+
+        limit <- struct{}{}
+        go func() {
+            defer func() {
+                <-limit
+                w.mutex.Lock()
+                delete(w.running, delivery.ID)
+                w.mutex.Unlock()
+            }()
+            w.deliver(ctx, delivery)
+        }()
+    }
+}
+
+// deliver 推送单条记录并更新状态
+func (w *Webhook) deliver(ctx context.Context, delivery WebhookDelivery) {
+    header := req.Header{
+        "Content-Type": "application/json",
+        "X-Webhook-ID": delivery.ID,
+    }
+    if len(w.secret) > 0 {
+        header[w.SignatureHeader] = "sha256=" + w.Sign(delivery.Payload)
+    }
+
+    delivery.Attempts++
+    rep, err := doOnce(http.MethodPost, delivery.URL, ctx, header, []byte(delivery.Payload))
+    if err == nil {
+        code := rep.Response().StatusCode
+        rep.Response().Body.Close()
+        if code >= 200 && code < 300 {
+            delivery.Status, delivery.LastError = WebhookDelivered, ""
+            w.finish(delivery, ".done")
+            return
+        }
+        err = errors.Errorf("http status code: %d", code)
+    }
+    if ctx.Err() != nil {
+        return
+    }
+
+    delivery.LastError = err.Error()
+    if delivery.Attempts >= w.MaxAttempts {
+        delivery.Status = WebhookFailed
+        w.finish(delivery, ".failed")
+        return
+    }
+
+    wait := webhookBaseWait << (delivery.Attempts - 1)
+    if wait > webhookMaxWait || wait <= 0 {
+        wait = webhookMaxWait
+    }
+    delivery.NextAt = conf().clock.Now().Add(jitter(wait))
+    if w.save(delivery) == nil && w.OnDelivery != nil {
+        w.OnDelivery(delivery)
+    }
+}
+
+// Sign 计算载荷 HMAC-SHA256 签名
+func (w *Webhook) Sign(payload []byte) string {
+    mac := hmac.New(sha256.New, w.secret)
+    mac.Write(payload)
+    return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhook 校验签名请求头，供接收方使用
+func VerifyWebhook(secret string, payload []byte, signature string) bool {
+    w := &Webhook{secret: []byte(secret)}
+    expected := w.Sign(payload)
+    return hmac.Equal([]byte(expected), []byte(strings.TrimPrefix(signature, "sha256=")))
+}
+
+// save 写入队列文件
+func (w *Webhook) save(delivery WebhookDelivery) error {
+    data, err := jsoniter.Marshal(delivery)
+    if err != nil {
+        return errors.WithStack(err)
+    }
+
+    name := filepath.Join(w.dir, delivery.ID+".json")
+    if err = os.WriteFile(name+".tmp", data, os.ModePerm); err != nil {
+        return errors.WithStack(err)
+    }
+    return errors.WithStack(os.Rename(name+".tmp", name))
+}
+
+// finish 推送结束，记录移出队列
+func (w *Webhook) finish(delivery WebhookDelivery, ext string) {
+    data, _ := jsoniter.Marshal(delivery)
+    name := filepath.Join(w.dir, delivery.ID)
+    if os.WriteFile(name+ext, data, os.ModePerm) == nil {
+        _ = os.Remove(name + ".json")
+    }
+    if w.OnDelivery != nil {
+        w.OnDelivery(delivery)
+    }
+}
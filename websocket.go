@@ -0,0 +1,237 @@
+package req
+
+import (
+	"context"
+	"net/http"
+	neturl "net/url"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/imroc/req"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+)
+
+const (
+    // WSText 文本消息
+    WSText = websocket.TextMessage
+    // WSBinary 二进制消息
+    WSBinary = websocket.BinaryMessage
+)
+
+var (
+    // wsPingInterval 心跳间隔
+    wsPingInterval = time.Second * 25
+    // wsPongWait 等待心跳响应时长，超时视为断线
+    wsPongWait = time.Second * 60
+)
+
+// WSConn WebSocket 连接，断线后自动重连
+type WSConn struct {
+    // OnReconnect 重连成功回调
+    OnReconnect func()
+
+    ctx    context.Context
+    cancel context.CancelFunc
+    url    string
+    header http.Header
+    dialer *websocket.Dialer
+
+    mutex      sync.Mutex
+    writeMutex sync.Mutex
+    conn       *websocket.Conn
+}
+
+// Dial 建立 WebSocket 连接，复用请求客户端的代理、TLS 与请求头配置
+func Dial(ctx context.Context, url string, headers ...req.Header) (*WSConn, error) {
+    header := http.Header{}
+    for _, h := range withCommonHeader(headersArgs(headers)) {
+        for k, v := range h.(req.Header) {
+            header.Set(k, v)
+        }
+    }
+
+    ctx, cancel := context.WithCancel(ctx)
+    c := &WSConn{
+        ctx:    ctx,
+        cancel: cancel,
+        url:    url,
+        header: header,
+        dialer: wsDialer(),
+    }
+
+    conn, err := c.dial()
+    if err != nil {
+        cancel()
+        return nil, err
+    }
+    c.conn = conn
+
+    go c.keepalive()
+    return c, nil
+}
+
+// headersArgs 请求头转换为请求参数
+func headersArgs(headers []req.Header) []interface{} {
+    args := make([]interface{}, 0, len(headers))
+    for _, h := range headers {
+        args = append(args, h)
+    }
+    return args
+}
+
+// wsDialer 按请求客户端的传输层配置创建拨号器
+func wsDialer() *websocket.Dialer {
+    // 请求客户端的传输层已被包装，按当前配置重新创建
+    c := conf()
+    transport := newTransport(c, defaultKey(c))
+    return &websocket.Dialer{
+        Proxy:             transport.Proxy,
+        HandshakeTimeout:  c.timeout,
+        Jar:               c.jar,
+        TLSClientConfig:   transport.TLSClientConfig,
+        NetDialContext:    transport.DialContext,
+        NetDialTLSContext: transport.DialTLSContext,
+    }
+}
+
+func (c *WSConn) dial() (*websocket.Conn, error) {
+    // 经代理连接时拨号器只检查代理地址，目标主机在此检查
+    if guard := conf().guard; guard != nil {
+        u, err := neturl.Parse(c.url)
+        if err != nil {
+            return nil, errors.WithStack(err)
+        }
+        if err = guard.checkHost(u.Hostname()); err != nil {
+            return nil, errors.WithStack(err)
+        }
+    }
+    conn, _, err := c.dialer.DialContext(c.ctx, c.url, c.header)
+    if err != nil {
+        return nil, errors.WithStack(err)
+    }
+
+    _ = conn.SetReadDeadline(time.Now().Add(wsPongWait))
+    conn.SetPongHandler(func(string) error {
+        return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+    })
+    return conn, nil
+}
+
+// current 当前连接
+func (c *WSConn) current() *websocket.Conn {
+    c.mutex.Lock()
+    defer c.mutex.Unlock()
+    return c.conn
+}
+
+// reconnect 退避重连，old 已被其他调用方替换时直接返回
+func (c *WSConn) reconnect(old *websocket.Conn) error {
+    c.mutex.Lock()
+    defer c.mutex.Unlock()
+
+    if c.conn != old {
+        return nil
+    }
+    _ = old.Close()
+
+    wait := conf().retrySleepTime
+    for {
+        select {
+        case <-c.ctx.Done():
+            return errors.WithStack(c.ctx.Err())
+        case <-conf().clock.After(wait):
+        }
+
+        conn, err := c.dial()
+        if err == nil {
+            c.conn = conn
+            if c.OnReconnect != nil {
+                go c.OnReconnect()
+            }
+            return nil
+        }
+
+        if wait *= 2; wait > maxReconnectWait {
+            wait = maxReconnectWait
+        }
+    }
+}
+
+// keepalive 定时发送心跳
+func (c *WSConn) keepalive() {
+    ticker := time.NewTicker(wsPingInterval)
+    defer ticker.Stop()
+
+    for {
+        select {
+        case <-c.ctx.Done():
+            return
+        case <-ticker.C:
+            _ = c.current().WriteControl(websocket.PingMessage, nil, time.Now().Add(conf().timeout))
+        }
+    }
+}
+
+// ReadMessage 读取消息，断线时自动重连后继续读取
+func (c *WSConn) ReadMessage() (int, []byte, error) {
+    for {
+        conn := c.current()
+        messageType, data, err := conn.ReadMessage()
+        if err == nil {
+            return messageType, data, nil
+        }
+        if c.ctx.Err() != nil || websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+            return 0, nil, errors.WithStack(err)
+        }
+        if err = c.reconnect(conn); err != nil {
+            return 0, nil, err
+        }
+    }
+}
+
+// WriteMessage 发送消息，断线时重连后重发一次
+func (c *WSConn) WriteMessage(messageType int, data []byte) error {
+    c.writeMutex.Lock()
+    defer c.writeMutex.Unlock()
+
+    conn := c.current()
+    err := conn.WriteMessage(messageType, data)
+    if err == nil || c.ctx.Err() != nil {
+        return errors.WithStack(err)
+    }
+
+    if err = c.reconnect(conn); err != nil {
+        return err
+    }
+    return errors.WithStack(c.current().WriteMessage(messageType, data))
+}
+
+// ReadJSON 读取 JSON 消息
+func (c *WSConn) ReadJSON(v interface{}) error {
+    _, data, err := c.ReadMessage()
+    if err != nil {
+        return err
+    }
+    return errors.WithStack(jsoniter.Unmarshal(data, v))
+}
+
+// WriteJSON 发送 JSON 消息
+func (c *WSConn) WriteJSON(v interface{}) error {
+    data, err := jsoniter.Marshal(v)
+    if err != nil {
+        return errors.WithStack(err)
+    }
+    return c.WriteMessage(WSText, data)
+}
+
+// Close 关闭连接，不再重连
+func (c *WSConn) Close() error {
+    c.cancel()
+
+    conn := c.current()
+    _ = conn.WriteControl(websocket.CloseMessage,
+        websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+    return errors.WithStack(conn.Close())
+}
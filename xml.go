@@ -0,0 +1,46 @@
+package req
+
+import (
+	"encoding/xml"
+	"net/http"
+	"strings"
+
+	"github.com/imroc/req"
+	"github.com/pkg/errors"
+	"golang.org/x/net/html/charset"
+)
+
+// GetXML GET请求并解析 XML 响应
+func GetXML(url string, out interface{}, v ...interface{}) error {
+    v = withHeader(v, req.Header{"Accept": "application/xml, text/xml;q=0.9"})
+    body, err := doRequest(http.MethodGet, url, v...)
+    if err != nil {
+        return err
+    }
+    return decodeXML(body, out)
+}
+
+// PostXML POST请求 XML 内容并解析 XML 响应，out 为 nil 时不解析响应
+func PostXML(url string, body, out interface{}, v ...interface{}) error {
+    data, err := xml.Marshal(body)
+    if err != nil {
+        return errors.WithStack(err)
+    }
+
+    v = withHeader(v, req.Header{
+        "Accept":       "application/xml, text/xml;q=0.9",
+        "Content-Type": "application/xml; charset=utf-8",
+    })
+    res, err := doRequest(http.MethodPost, url, append(v[:len(v):len(v)], append([]byte(xml.Header), data...))...)
+    if err != nil || out == nil {
+        return err
+    }
+    return decodeXML(res, out)
+}
+
+// decodeXML 解析 XML 内容，支持声明中非 UTF-8 编码
+func decodeXML(body string, out interface{}) error {
+    decoder := xml.NewDecoder(strings.NewReader(body))
+    decoder.CharsetReader = charset.NewReaderLabel
+    return errors.WithStack(decoder.Decode(out))
+}